@@ -0,0 +1,95 @@
+package neco
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"reflect"
+	"strconv"
+)
+
+// ParseArtifactSet parses the source code of artifacts.go and returns
+// the value of CurrentArtifacts.  This is used to know the artifacts
+// of neco releases other than the running one.
+func ParseArtifactSet(src []byte) (*ArtifactSet, error) {
+	f, err := parser.ParseFile(token.NewFileSet(), "artifacts.go", src, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, decl := range f.Decls {
+		gd, ok := decl.(*ast.GenDecl)
+		if !ok || gd.Tok != token.VAR {
+			continue
+		}
+		for _, spec := range gd.Specs {
+			vs := spec.(*ast.ValueSpec)
+			for i, name := range vs.Names {
+				if name.Name != "CurrentArtifacts" || i >= len(vs.Values) {
+					continue
+				}
+				a := new(ArtifactSet)
+				err := evalLiteral(vs.Values[i], reflect.ValueOf(a).Elem())
+				if err != nil {
+					return nil, fmt.Errorf("failed to parse CurrentArtifacts: %w", err)
+				}
+				return a, nil
+			}
+		}
+	}
+	return nil, errors.New("CurrentArtifacts is not found")
+}
+
+// evalLiteral evaluates a literal expression generated by generate-artifacts into v.
+func evalLiteral(expr ast.Expr, v reflect.Value) error {
+	switch e := expr.(type) {
+	case *ast.CompositeLit:
+		switch v.Kind() {
+		case reflect.Struct:
+			for _, elt := range e.Elts {
+				kv, ok := elt.(*ast.KeyValueExpr)
+				if !ok {
+					return fmt.Errorf("unkeyed field in %s", v.Type())
+				}
+				key, ok := kv.Key.(*ast.Ident)
+				if !ok {
+					return fmt.Errorf("invalid field name in %s", v.Type())
+				}
+				field := v.FieldByName(key.Name)
+				if !field.IsValid() {
+					return fmt.Errorf("unknown field %s in %s", key.Name, v.Type())
+				}
+				if err := evalLiteral(kv.Value, field); err != nil {
+					return err
+				}
+			}
+			return nil
+		case reflect.Slice:
+			s := reflect.MakeSlice(v.Type(), len(e.Elts), len(e.Elts))
+			for i, elt := range e.Elts {
+				if err := evalLiteral(elt, s.Index(i)); err != nil {
+					return err
+				}
+			}
+			v.Set(s)
+			return nil
+		}
+	case *ast.BasicLit:
+		if e.Kind == token.STRING && v.Kind() == reflect.String {
+			s, err := strconv.Unquote(e.Value)
+			if err != nil {
+				return err
+			}
+			v.SetString(s)
+			return nil
+		}
+	case *ast.Ident:
+		if (e.Name == "true" || e.Name == "false") && v.Kind() == reflect.Bool {
+			v.SetBool(e.Name == "true")
+			return nil
+		}
+	}
+	return fmt.Errorf("unexpected expression for %s", v.Type())
+}
@@ -0,0 +1,32 @@
+package neco
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestParseArtifactSet(t *testing.T) {
+	src, err := os.ReadFile("artifacts.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := ParseArtifactSet(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(*a, CurrentArtifacts) {
+		t.Errorf("unexpected artifacts: %#v", a)
+	}
+
+	_, err = ParseArtifactSet([]byte("package neco\n\nvar CurrentArtifacts = ArtifactSet{Unknown: 1}\n"))
+	if err == nil {
+		t.Error("unknown field should be an error")
+	}
+
+	_, err = ParseArtifactSet([]byte("package neco\n"))
+	if err == nil {
+		t.Error("missing CurrentArtifacts should be an error")
+	}
+}
@@ -0,0 +1,113 @@
+package ext
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/cybozu-go/neco"
+	"github.com/cybozu-go/neco/storage"
+)
+
+// AlertNameUpdateFailed is the name of the alert sent by AlertmanagerClient.
+const AlertNameUpdateFailed = "NecoUpdateFailed"
+
+// alertDuration is the lifetime of the alert for a failure.
+// Alertmanager resolves an alert without endsAt after resolve_timeout
+// unless the alert is pushed again.  neco-updater pushes the alert only
+// once, so the alert is kept firing by a long endsAt until an update
+// completes successfully.
+const alertDuration = 365 * 24 * time.Hour
+
+// Alert represents an alert of Alertmanager API v2.
+type Alert struct {
+	Labels      map[string]string `json:"labels"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+	StartsAt    *time.Time        `json:"startsAt,omitempty"`
+	EndsAt      *time.Time        `json:"endsAt,omitempty"`
+}
+
+// AlertmanagerClient pushes alerts to Alertmanager.
+//
+// A failure of update fires an alert, and the alert is resolved when
+// an update completes successfully.  Informational notifications are
+// not sent.
+type AlertmanagerClient struct {
+	// URL is the base URL of Alertmanager such as http://alertmanager:9093
+	URL     string
+	HTTP    *http.Client
+	Cluster string
+}
+
+func newAlertmanagerNotifier(ctx context.Context, st storage.Storage, hc *http.Client, cluster string) (Notifier, error) {
+	amURL, err := st.GetAlertmanagerNotification(ctx)
+	if err == storage.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Alertmanager is an intranet service.
+	return &AlertmanagerClient{URL: amURL, HTTP: LocalHTTPClient(), Cluster: cluster}, nil
+}
+
+func (c AlertmanagerClient) push(alert Alert) error {
+	u, err := url.JoinPath(c.URL, "/api/v2/alerts")
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal([]Alert{alert})
+	if err != nil {
+		return err
+	}
+	return postJSON(c.HTTP, u, body)
+}
+
+// labels do not include the version so that the alert is resolved
+// by any successful update, including a rollback.
+func (c AlertmanagerClient) labels() map[string]string {
+	return map[string]string{
+		"alertname": AlertNameUpdateFailed,
+		"cluster":   c.Cluster,
+		"severity":  "critical",
+	}
+}
+
+// NotifyInfo implements Notifier.  This does nothing.
+func (c AlertmanagerClient) NotifyInfo(req neco.UpdateRequest, message string) error {
+	return nil
+}
+
+// NotifySucceeded implements Notifier.  This resolves the alert.
+func (c AlertmanagerClient) NotifySucceeded(req neco.UpdateRequest) error {
+	now := time.Now().UTC()
+	return c.push(Alert{
+		Labels: c.labels(),
+		Annotations: map[string]string{
+			"summary": fmt.Sprintf("neco %s has been installed on boot servers", req.Version),
+		},
+		StartsAt: &now,
+		EndsAt:   &now,
+	})
+}
+
+// NotifyFailure implements Notifier.  This fires the alert.
+func (c AlertmanagerClient) NotifyFailure(req neco.UpdateRequest, message string) error {
+	now := time.Now().UTC()
+	end := now.Add(alertDuration)
+	return c.push(Alert{
+		Labels: c.labels(),
+		Annotations: map[string]string{
+			"summary":     fmt.Sprintf("failed to update boot servers to neco %s", req.Version),
+			"description": message,
+			"version":     req.Version,
+			"servers":     fmt.Sprintf("%v", req.Servers),
+		},
+		StartsAt: &now,
+		EndsAt:   &end,
+	})
+}
@@ -0,0 +1,69 @@
+package ext
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestAlertmanagerClient(t *testing.T) {
+	t.Parallel()
+
+	r, ts := newTestReceiver(t, http.StatusOK)
+	c := AlertmanagerClient{URL: ts.URL, Cluster: "stage0"}
+	err := c.NotifyInfo(testUpdateRequest, "start")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = c.NotifyFailure(testUpdateRequest, "etcd is dead")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = c.NotifySucceeded(testUpdateRequest)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	paths, bodies := r.received()
+	if len(bodies) != 2 {
+		t.Fatal("info should not be sent", len(bodies))
+	}
+	for _, p := range paths {
+		if p != "/api/v2/alerts" {
+			t.Error("unexpected path", p)
+		}
+	}
+
+	var firing, resolved []Alert
+	err = json.Unmarshal(bodies[0], &firing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = json.Unmarshal(bodies[1], &resolved)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(firing) != 1 || len(resolved) != 1 {
+		t.Fatal("unexpected alerts", firing, resolved)
+	}
+
+	f := firing[0]
+	if f.Labels["alertname"] != AlertNameUpdateFailed || f.Labels["cluster"] != "stage0" {
+		t.Error("unexpected labels", f.Labels)
+	}
+	if f.Annotations["description"] != "etcd is dead" {
+		t.Error("unexpected annotations", f.Annotations)
+	}
+	if f.StartsAt == nil || f.EndsAt == nil || f.EndsAt.Before(f.StartsAt.Add(24*time.Hour)) {
+		t.Error("alert should be kept firing", f)
+	}
+
+	res := resolved[0]
+	if res.Labels["alertname"] != f.Labels["alertname"] || res.Labels["cluster"] != f.Labels["cluster"] || res.Labels["severity"] != f.Labels["severity"] {
+		t.Error("labels should be the same to resolve the alert", res.Labels)
+	}
+	if res.EndsAt == nil || res.EndsAt.After(time.Now()) {
+		t.Error("alert should be resolved", res)
+	}
+}
@@ -2,6 +2,8 @@ package ext
 
 import (
 	"context"
+	"errors"
+	"net/http"
 
 	"github.com/cybozu-go/neco"
 	"github.com/cybozu-go/neco/storage"
@@ -14,6 +16,41 @@ type Notifier interface {
 	NotifyFailure(req neco.UpdateRequest, message string) error
 }
 
+// Notification kinds.
+const (
+	NotificationInfo      = "info"
+	NotificationSucceeded = "succeeded"
+	NotificationFailure   = "failure"
+)
+
+// Notification represents a notification from neco-updater.
+// This is passed to the template of WebhookClient.
+type Notification struct {
+	Kind    string             `json:"kind"`
+	Cluster string             `json:"cluster"`
+	Title   string             `json:"title"`
+	Message string             `json:"message,omitempty"`
+	Request neco.UpdateRequest `json:"request"`
+}
+
+func newNotification(kind, cluster string, req neco.UpdateRequest, message string) Notification {
+	n := Notification{
+		Kind:    kind,
+		Cluster: cluster,
+		Message: message,
+		Request: req,
+	}
+	switch kind {
+	case NotificationInfo:
+		n.Title = "Update begins"
+	case NotificationSucceeded:
+		n.Title = "Update completed successfully"
+	case NotificationFailure:
+		n.Title = "Update failed"
+	}
+	return n
+}
+
 type nopNotifier struct {
 }
 
@@ -27,17 +64,47 @@ func (n nopNotifier) NotifyFailure(req neco.UpdateRequest, message string) error
 	return nil
 }
 
-// NewNotifier creates a new Notifier.
-func NewNotifier(ctx context.Context, st storage.Storage) (Notifier, error) {
-	slackURL, err := st.GetSlackNotification(ctx)
-	if err == storage.ErrNotFound {
-		return nopNotifier{}, nil
-	}
+// multiNotifier sends notifications to all notifiers.
+// A failure of a notifier does not prevent the others.
+type multiNotifier []Notifier
 
-	if err != nil {
-		return nil, err
+func (m multiNotifier) NotifyInfo(req neco.UpdateRequest, message string) error {
+	var errs []error
+	for _, n := range m {
+		errs = append(errs, n.NotifyInfo(req, message))
+	}
+	return errors.Join(errs...)
+}
+func (m multiNotifier) NotifySucceeded(req neco.UpdateRequest) error {
+	var errs []error
+	for _, n := range m {
+		errs = append(errs, n.NotifySucceeded(req))
+	}
+	return errors.Join(errs...)
+}
+func (m multiNotifier) NotifyFailure(req neco.UpdateRequest, message string) error {
+	var errs []error
+	for _, n := range m {
+		errs = append(errs, n.NotifyFailure(req, message))
 	}
+	return errors.Join(errs...)
+}
+
+// notifierBackend creates a Notifier from the configurations in storage.
+// It returns nil if the backend is not configured.
+type notifierBackend func(ctx context.Context, st storage.Storage, hc *http.Client, cluster string) (Notifier, error)
+
+var notifierBackends = []notifierBackend{
+	newSlackNotifier,
+	newWebhookNotifier,
+	newTeamsNotifier,
+	newAlertmanagerNotifier,
+}
 
+// NewNotifier creates a new Notifier.
+//
+// The returned Notifier sends notifications to all the configured backends.
+func NewNotifier(ctx context.Context, st storage.Storage) (Notifier, error) {
 	hc, err := ProxyHTTPClient(ctx, st)
 	if err != nil {
 		return nil, err
@@ -48,5 +115,33 @@ func NewNotifier(ctx context.Context, st storage.Storage) (Notifier, error) {
 		return nil, err
 	}
 
-	return &SlackClient{URL: slackURL, HTTP: hc, Cluster: me}, nil
+	var notifiers multiNotifier
+	for _, backend := range notifierBackends {
+		n, err := backend(ctx, st, hc, me)
+		if err != nil {
+			return nil, err
+		}
+		if n != nil {
+			notifiers = append(notifiers, n)
+		}
+	}
+
+	switch len(notifiers) {
+	case 0:
+		return nopNotifier{}, nil
+	case 1:
+		return notifiers[0], nil
+	}
+	return notifiers, nil
+}
+
+func newSlackNotifier(ctx context.Context, st storage.Storage, hc *http.Client, cluster string) (Notifier, error) {
+	slackURL, err := st.GetSlackNotification(ctx)
+	if err == storage.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &SlackClient{URL: slackURL, HTTP: hc, Cluster: cluster}, nil
 }
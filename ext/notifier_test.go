@@ -0,0 +1,104 @@
+package ext
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cybozu-go/neco"
+)
+
+var testUpdateRequest = neco.UpdateRequest{
+	Version:   "2024.12.01-12345",
+	Servers:   []int{0, 1, 2},
+	StartedAt: time.Date(2024, 12, 1, 9, 0, 0, 0, time.UTC),
+}
+
+// testReceiver records requests to a local HTTP server.
+type testReceiver struct {
+	mu     sync.Mutex
+	paths  []string
+	bodies [][]byte
+	status int
+}
+
+func newTestReceiver(t *testing.T, status int) (*testReceiver, *httptest.Server) {
+	r := &testReceiver{status: status}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost {
+			t.Error("unexpected method", req.Method)
+		}
+		if ct := req.Header.Get("Content-Type"); ct != "application/json" {
+			t.Error("unexpected content type", ct)
+		}
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		r.mu.Lock()
+		r.paths = append(r.paths, req.URL.Path)
+		r.bodies = append(r.bodies, body)
+		r.mu.Unlock()
+		w.WriteHeader(r.status)
+	}))
+	t.Cleanup(ts.Close)
+	return r, ts
+}
+
+func (r *testReceiver) received() ([]string, [][]byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.paths, r.bodies
+}
+
+type errorNotifier struct {
+	nopNotifier
+}
+
+func (n errorNotifier) NotifyFailure(req neco.UpdateRequest, message string) error {
+	return errors.New("error")
+}
+
+func TestMultiNotifier(t *testing.T) {
+	t.Parallel()
+
+	r1, ts1 := newTestReceiver(t, http.StatusOK)
+	r2, ts2 := newTestReceiver(t, http.StatusOK)
+
+	m := multiNotifier{
+		errorNotifier{},
+		WebhookClient{URL: ts1.URL, Cluster: "stage0"},
+		TeamsClient{URL: ts2.URL, Cluster: "stage0"},
+	}
+	err := m.NotifyInfo(testUpdateRequest, "start")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = m.NotifyFailure(testUpdateRequest, "aborted")
+	if err == nil {
+		t.Error("error of a notifier should be returned")
+	}
+
+	// other notifiers should be notified even if a notifier fails.
+	for i, r := range []*testReceiver{r1, r2} {
+		_, bodies := r.received()
+		if len(bodies) != 2 {
+			t.Errorf("receiver %d: unexpected number of notifications: %d", i, len(bodies))
+		}
+	}
+}
+
+func TestPostJSON(t *testing.T) {
+	t.Parallel()
+
+	_, ts := newTestReceiver(t, http.StatusInternalServerError)
+	err := postJSON(nil, ts.URL, []byte("{}"))
+	if err == nil {
+		t.Error("non-2xx status should be an error")
+	}
+}
@@ -0,0 +1,107 @@
+package ext
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/cybozu-go/neco"
+	"github.com/cybozu-go/neco/storage"
+)
+
+// Theme colors of Microsoft Teams cards.
+const (
+	TeamsColorInfo   = "439FE0"
+	TeamsColorGood   = "2EB886"
+	TeamsColorDanger = "A30200"
+)
+
+// TeamsCard represents a message card of Microsoft Teams incoming webhook.
+type TeamsCard struct {
+	Type       string         `json:"@type"`
+	Context    string         `json:"@context"`
+	ThemeColor string         `json:"themeColor,omitempty"`
+	Summary    string         `json:"summary"`
+	Title      string         `json:"title,omitempty"`
+	Text       string         `json:"text,omitempty"`
+	Sections   []TeamsSection `json:"sections,omitempty"`
+}
+
+// TeamsSection represents a section in TeamsCard.
+type TeamsSection struct {
+	ActivityTitle string      `json:"activityTitle,omitempty"`
+	Facts         []TeamsFact `json:"facts,omitempty"`
+}
+
+// TeamsFact represents a name-value pair in TeamsSection.
+type TeamsFact struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// TeamsClient posts notifications as cards to Microsoft Teams.
+type TeamsClient struct {
+	URL     string
+	HTTP    *http.Client
+	Cluster string
+}
+
+func newTeamsNotifier(ctx context.Context, st storage.Storage, hc *http.Client, cluster string) (Notifier, error) {
+	teamsURL, err := st.GetTeamsNotification(ctx)
+	if err == storage.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &TeamsClient{URL: teamsURL, HTTP: hc, Cluster: cluster}, nil
+}
+
+func (c TeamsClient) post(color, text, messageName string, n Notification) error {
+	facts := []TeamsFact{
+		{Name: "Cluster", Value: c.Cluster},
+		{Name: "Version", Value: n.Request.Version},
+		{Name: "Servers", Value: fmt.Sprintf("%v", n.Request.Servers)},
+		{Name: "Started at", Value: n.Request.StartedAt.Format(time.RFC3339)},
+	}
+	if n.Message != "" {
+		facts = append(facts, TeamsFact{Name: messageName, Value: n.Message})
+	}
+
+	card := TeamsCard{
+		Type:       "MessageCard",
+		Context:    "https://schema.org/extensions",
+		ThemeColor: color,
+		Summary:    fmt.Sprintf("%s: %s", c.Cluster, n.Title),
+		Title:      n.Title,
+		Text:       text,
+		Sections: []TeamsSection{
+			{ActivityTitle: "Boot server updater", Facts: facts},
+		},
+	}
+	body, err := json.Marshal(card)
+	if err != nil {
+		return err
+	}
+	return postJSON(c.HTTP, c.URL, body)
+}
+
+// NotifyInfo implements Notifier.
+func (c TeamsClient) NotifyInfo(req neco.UpdateRequest, message string) error {
+	n := newNotification(NotificationInfo, c.Cluster, req, message)
+	return c.post(TeamsColorInfo, "neco-worker has started the updating process.", "Detail", n)
+}
+
+// NotifySucceeded implements Notifier.
+func (c TeamsClient) NotifySucceeded(req neco.UpdateRequest) error {
+	n := newNotification(NotificationSucceeded, c.Cluster, req, "")
+	return c.post(TeamsColorGood, "boot servers were updated successfully.", "Detail", n)
+}
+
+// NotifyFailure implements Notifier.
+func (c TeamsClient) NotifyFailure(req neco.UpdateRequest, message string) error {
+	n := newNotification(NotificationFailure, c.Cluster, req, message)
+	return c.post(TeamsColorDanger, "there were some errors.  Please fix it manually.", "Reason", n)
+}
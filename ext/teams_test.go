@@ -0,0 +1,52 @@
+package ext
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestTeamsClient(t *testing.T) {
+	t.Parallel()
+
+	r, ts := newTestReceiver(t, http.StatusOK)
+	c := TeamsClient{URL: ts.URL, Cluster: "stage0"}
+	err := c.NotifyFailure(testUpdateRequest, "etcd is dead")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = c.NotifySucceeded(testUpdateRequest)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, bodies := r.received()
+	if len(bodies) != 2 {
+		t.Fatal("unexpected number of notifications", len(bodies))
+	}
+
+	var card TeamsCard
+	err = json.Unmarshal(bodies[0], &card)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if card.Type != "MessageCard" || card.ThemeColor != TeamsColorDanger || card.Title != "Update failed" {
+		t.Error("unexpected card", card)
+	}
+	if len(card.Sections) != 1 {
+		t.Fatal("unexpected sections", card.Sections)
+	}
+	facts := card.Sections[0].Facts
+	if len(facts) != 5 || facts[4].Name != "Reason" || facts[4].Value != "etcd is dead" {
+		t.Error("unexpected facts", facts)
+	}
+
+	card = TeamsCard{}
+	err = json.Unmarshal(bodies[1], &card)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if card.ThemeColor != TeamsColorGood || len(card.Sections[0].Facts) != 4 {
+		t.Error("unexpected card", card)
+	}
+}
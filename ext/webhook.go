@@ -0,0 +1,127 @@
+package ext
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"text/template"
+	"time"
+
+	"github.com/cybozu-go/neco"
+	"github.com/cybozu-go/neco/storage"
+)
+
+// ParseWebhookTemplate parses the payload template for WebhookClient.
+//
+// The template is executed with Notification, and must generate a JSON.
+// Function "json" is available to encode a value into JSON.
+func ParseWebhookTemplate(text string) (*template.Template, error) {
+	return template.New("webhook").Funcs(template.FuncMap{
+		"json": func(v interface{}) (string, error) {
+			data, err := json.Marshal(v)
+			return string(data), err
+		},
+	}).Parse(text)
+}
+
+// WebhookClient posts notifications as JSON to a generic webhook.
+type WebhookClient struct {
+	URL     string
+	HTTP    *http.Client
+	Cluster string
+
+	// Template generates the payload from Notification.
+	// If nil, Notification is encoded into JSON as is.
+	Template *template.Template
+}
+
+func newWebhookNotifier(ctx context.Context, st storage.Storage, hc *http.Client, cluster string) (Notifier, error) {
+	webhookURL, err := st.GetWebhookNotification(ctx)
+	if err == storage.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	c := &WebhookClient{URL: webhookURL, HTTP: hc, Cluster: cluster}
+	text, err := st.GetWebhookTemplate(ctx)
+	switch err {
+	case nil:
+		c.Template, err = ParseWebhookTemplate(text)
+		if err != nil {
+			return nil, err
+		}
+	case storage.ErrNotFound:
+	default:
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c WebhookClient) notify(n Notification) error {
+	if c.Template == nil {
+		body, err := json.Marshal(n)
+		if err != nil {
+			return err
+		}
+		return postJSON(c.HTTP, c.URL, body)
+	}
+
+	buf := new(bytes.Buffer)
+	err := c.Template.Execute(buf, n)
+	if err != nil {
+		return err
+	}
+	if !json.Valid(buf.Bytes()) {
+		return errors.New("webhook template generated invalid JSON")
+	}
+	return postJSON(c.HTTP, c.URL, buf.Bytes())
+}
+
+// NotifyInfo implements Notifier.
+func (c WebhookClient) NotifyInfo(req neco.UpdateRequest, message string) error {
+	return c.notify(newNotification(NotificationInfo, c.Cluster, req, message))
+}
+
+// NotifySucceeded implements Notifier.
+func (c WebhookClient) NotifySucceeded(req neco.UpdateRequest) error {
+	return c.notify(newNotification(NotificationSucceeded, c.Cluster, req, ""))
+}
+
+// NotifyFailure implements Notifier.
+func (c WebhookClient) NotifyFailure(req neco.UpdateRequest, message string) error {
+	return c.notify(newNotification(NotificationFailure, c.Cluster, req, message))
+}
+
+// postJSON posts body to url.  Unlike SlackClient.PostWebHook,
+// this returns an error if the response status is not 2xx.
+func postJSON(hc *http.Client, url string, body []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("failed to post notification to %s: %s", url, resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package ext
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestWebhookClient(t *testing.T) {
+	t.Parallel()
+
+	r, ts := newTestReceiver(t, http.StatusOK)
+	c := WebhookClient{URL: ts.URL, Cluster: "stage0"}
+	err := c.NotifyFailure(testUpdateRequest, "etcd is dead")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, bodies := r.received()
+	if len(bodies) != 1 {
+		t.Fatal("unexpected number of notifications", len(bodies))
+	}
+	var n Notification
+	err = json.Unmarshal(bodies[0], &n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n.Kind != NotificationFailure || n.Cluster != "stage0" || n.Title != "Update failed" ||
+		n.Message != "etcd is dead" || n.Request.Version != testUpdateRequest.Version {
+		t.Error("unexpected notification", n)
+	}
+}
+
+func TestWebhookClientTemplate(t *testing.T) {
+	t.Parallel()
+
+	tmpl, err := ParseWebhookTemplate(`{"text": {{ printf "[%s] %s: %s" .Cluster .Title .Message | json }}, "version": {{ json .Request.Version }}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, ts := newTestReceiver(t, http.StatusOK)
+	c := WebhookClient{URL: ts.URL, Cluster: "stage0", Template: tmpl}
+	err = c.NotifyInfo(testUpdateRequest, `start "updating"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, bodies := r.received()
+	if len(bodies) != 1 {
+		t.Fatal("unexpected number of notifications", len(bodies))
+	}
+	var payload map[string]string
+	err = json.Unmarshal(bodies[0], &payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if payload["text"] != `[stage0] Update begins: start "updating"` {
+		t.Error("unexpected text", payload["text"])
+	}
+	if payload["version"] != testUpdateRequest.Version {
+		t.Error("unexpected version", payload["version"])
+	}
+
+	tmpl, err = ParseWebhookTemplate(`{"text": {{ .Message }}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Template = tmpl
+	err = c.NotifyInfo(testUpdateRequest, "not quoted")
+	if err == nil {
+		t.Error("invalid JSON should not be posted")
+	}
+	_, bodies = r.received()
+	if len(bodies) != 1 {
+		t.Error("invalid JSON is posted")
+	}
+
+	_, err = ParseWebhookTemplate(`{"text": {{ .Message }`)
+	if err == nil {
+		t.Error("invalid template should be rejected")
+	}
+}
@@ -28,3 +28,12 @@ func NewDefaultGitHubClient() *github.Client {
 	}
 	return NewGitHubClient(hc)
 }
+
+// ReleaseTag returns the tag of the neco release for env on GitHub.
+// Releases for dev environments are tagged with "test-" prefix.
+func ReleaseTag(env, version string) string {
+	if env == DevEnv {
+		return "test-" + version
+	}
+	return "release-" + version
+}
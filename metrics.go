@@ -0,0 +1,28 @@
+package neco
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// RunMetricsServer serves handler at /metrics on addr until ctx is done.
+func RunMetricsServer(ctx context.Context, addr string, handler http.Handler) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", handler)
+	server := &http.Server{Addr: addr, Handler: mux}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- server.ListenAndServe()
+	}()
+	select {
+	case err := <-errCh:
+		return fmt.Errorf("failed to start metrics server: %w", err)
+	case <-ctx.Done():
+		ctx2, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		return server.Shutdown(ctx2)
+	}
+}
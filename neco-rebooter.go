@@ -1,11 +1,80 @@
 package neco
 
+import (
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+	"time"
+)
+
 type RebootListEntry struct {
 	Index      int64  `json:"index"`
 	Node       string `json:"node"`
 	Group      string `json:"group"`
 	RebootTime string `json:"reboot_time"`
 	Status     string `json:"status"`
+
+	// Priority is the priority of the reboot.  Entries and groups with
+	// higher priority are processed first.  The default is 0.
+	Priority int `json:"priority,omitempty"`
+
+	// Deadline is the time by which the node must be rebooted.
+	Deadline *time.Time `json:"deadline,omitempty"`
+
+	// PostponedUntil is the time until which the entry is not processed.
+	PostponedUntil *time.Time `json:"postponed_until,omitempty"`
+}
+
+// IsOverdue returns true if the entry has passed its deadline at now.
+func (e *RebootListEntry) IsOverdue(now time.Time) bool {
+	return e.Deadline != nil && now.After(*e.Deadline)
+}
+
+// IsPostponed returns true if the entry is postponed at now.
+func (e *RebootListEntry) IsPostponed(now time.Time) bool {
+	return e.PostponedUntil != nil && now.Before(*e.PostponedUntil)
+}
+
+// RebootListEntrySelector selects reboot list entries.
+// Empty fields match any entries, and all the non-empty fields must match.
+type RebootListEntrySelector struct {
+	Group string
+	// NodePattern is a pattern of node names in the syntax of path.Match.
+	NodePattern string
+	RebootTime  string
+	// Nodes is the list of node names.  If nil, any nodes match.
+	Nodes []string
+}
+
+// Validate validates the selector.
+func (s *RebootListEntrySelector) Validate() error {
+	if s.Group == "" && s.NodePattern == "" && s.RebootTime == "" && s.Nodes == nil {
+		return errors.New("no selector is specified")
+	}
+	if _, err := path.Match(s.NodePattern, ""); err != nil {
+		return fmt.Errorf("invalid node pattern %q: %w", s.NodePattern, err)
+	}
+	return nil
+}
+
+// Match returns true if the entry is selected by the selector.
+func (s *RebootListEntrySelector) Match(e *RebootListEntry) bool {
+	if s.Group != "" && e.Group != s.Group {
+		return false
+	}
+	if s.RebootTime != "" && e.RebootTime != s.RebootTime {
+		return false
+	}
+	if s.NodePattern != "" {
+		if ok, _ := path.Match(s.NodePattern, e.Node); !ok {
+			return false
+		}
+	}
+	if s.Nodes != nil && !slices.Contains(s.Nodes, e.Node) {
+		return false
+	}
+	return true
 }
 
 var (
@@ -13,3 +82,41 @@ var (
 	RebootListEntryStatusQueued    = "Queued"
 	RebootListEntryStatusCancelled = "Cancelled"
 )
+
+// Types of RebootListEvent.
+const (
+	RebootListEventAdded     = "Added"
+	RebootListEventQueued    = "Queued"
+	RebootListEventDequeued  = "Dequeued"
+	RebootListEventPostponed = "Postponed"
+	RebootListEventCancelled = "Cancelled"
+	RebootListEventCompleted = "Completed"
+	RebootListEventTimedOut  = "TimedOut"
+)
+
+// RebootListEvent is an audit record of a transition of a reboot list entry.
+type RebootListEvent struct {
+	Time       time.Time `json:"time"`
+	Type       string    `json:"type"`
+	Index      int64     `json:"index"`
+	Node       string    `json:"node"`
+	Group      string    `json:"group"`
+	RebootTime string    `json:"reboot_time"`
+	// Actor is the user who operated the entry, or the leader of neco-rebooter.
+	Actor  string `json:"actor"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// NewRebootListEvent creates a RebootListEvent for the entry at now.
+func NewRebootListEvent(entry *RebootListEntry, typ, actor, reason string) RebootListEvent {
+	return RebootListEvent{
+		Time:       time.Now().UTC(),
+		Type:       typ,
+		Index:      entry.Index,
+		Node:       entry.Node,
+		Group:      entry.Group,
+		RebootTime: entry.RebootTime,
+		Actor:      actor,
+		Reason:     reason,
+	}
+}
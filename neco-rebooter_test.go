@@ -0,0 +1,61 @@
+package neco
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRebootListEntrySelector(t *testing.T) {
+	entry := &RebootListEntry{Node: "10.69.0.4", Group: "rack0", RebootTime: "cs"}
+
+	testCases := []struct {
+		name     string
+		selector RebootListEntrySelector
+		expected bool
+	}{
+		{"group", RebootListEntrySelector{Group: "rack0"}, true},
+		{"other-group", RebootListEntrySelector{Group: "rack1"}, false},
+		{"pattern", RebootListEntrySelector{NodePattern: "10.69.0.*"}, true},
+		{"other-pattern", RebootListEntrySelector{NodePattern: "10.69.1.*"}, false},
+		{"reboot-time", RebootListEntrySelector{RebootTime: "cs"}, true},
+		{"nodes", RebootListEntrySelector{Nodes: []string{"10.69.0.5", "10.69.0.4"}}, true},
+		{"empty-nodes", RebootListEntrySelector{Nodes: []string{}}, false},
+		{"all", RebootListEntrySelector{Group: "rack0", NodePattern: "10.69.*", RebootTime: "cs", Nodes: []string{"10.69.0.4"}}, true},
+		{"partial", RebootListEntrySelector{Group: "rack0", RebootTime: "ss"}, false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := tc.selector.Validate(); err != nil {
+				t.Fatal(err)
+			}
+			if actual := tc.selector.Match(entry); actual != tc.expected {
+				t.Errorf("expected %v, actual %v", tc.expected, actual)
+			}
+		})
+	}
+
+	empty := RebootListEntrySelector{}
+	if empty.Validate() == nil {
+		t.Error("empty selector should be invalid")
+	}
+	bad := RebootListEntrySelector{NodePattern: "[10.69"}
+	if bad.Validate() == nil {
+		t.Error("bad pattern should be invalid")
+	}
+}
+
+func TestRebootListEntryIsPostponed(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	entry := &RebootListEntry{}
+	if entry.IsPostponed(now) {
+		t.Error("entry without PostponedUntil should not be postponed")
+	}
+	until := now.Add(time.Hour)
+	entry.PostponedUntil = &until
+	if !entry.IsPostponed(now) {
+		t.Error("entry should be postponed")
+	}
+	if entry.IsPostponed(until) {
+		t.Error("entry should not be postponed after PostponedUntil")
+	}
+}
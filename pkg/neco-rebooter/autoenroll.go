@@ -0,0 +1,265 @@
+package necorebooter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/cybozu-go/neco"
+	"github.com/cybozu-go/neco/ext"
+	"github.com/cybozu-go/neco/progs/sabakan"
+	sabakanv3 "github.com/cybozu-go/sabakan/v3"
+	sabac "github.com/cybozu-go/sabakan/v3/client"
+	serf "github.com/hashicorp/serf/client"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	DefaultAutoEnrollInterval = time.Hour
+
+	// serfTagOSVersion is the serf tag of the version of the booted OS image.
+	serfTagOSVersion = "os-version"
+	// serfTagVersion is the serf tag of the version of the booted ignition.
+	serfTagVersion = "version"
+)
+
+// AutoEnroll is the configuration of the automatic enrollment.
+// neco-rebooter adds the nodes running an outdated OS image or ignition to the reboot list.
+type AutoEnroll struct {
+	Interval metav1.Duration `json:"interval"`
+	// OSImage enables the comparison of the booted OS image with the one uploaded to sabakan.
+	OSImage bool `json:"osImage"`
+	// Ignition enables the comparison of the booted ignition with the latest one of the role.
+	Ignition bool `json:"ignition"`
+	// Selector limits the target nodes by the labels of Kubernetes nodes.
+	Selector    LabelSelector `json:"selector"`
+	Kubeconfig  string        `json:"kubeconfig"`
+	SerfAddress string        `json:"serfAddress"`
+}
+
+func (a *AutoEnroll) setDefaults() {
+	if a.Interval.Duration == 0 {
+		a.Interval.Duration = DefaultAutoEnrollInterval
+	}
+	if a.SerfAddress == "" {
+		a.SerfAddress = DefaultSerfAddress
+	}
+}
+
+func (a *AutoEnroll) validate() error {
+	if a.Interval.Duration < 0 {
+		return errors.New("autoEnroll.interval must not be negative")
+	}
+	if !a.OSImage && !a.Ignition {
+		return errors.New("autoEnroll requires osImage or ignition")
+	}
+	if len(a.Selector.MatchLabels) == 0 && len(a.Selector.MatchExpressions) == 0 {
+		return errors.New("autoEnroll.selector is required")
+	}
+	_, err := a.Selector.Selector()
+	if err != nil {
+		return fmt.Errorf("invalid autoEnroll.selector: %w", err)
+	}
+	return nil
+}
+
+// bootedVersions is the versions of the booted and the latest artifacts.
+// Machines are identified by their IPv4 addresses.
+type bootedVersions struct {
+	// tags is the serf tags of the machines.
+	tags map[string]map[string]string
+	// machines is the sabakan machines.
+	machines map[string]*sabakanv3.Machine
+	// osImage is the ID of the latest OS image uploaded to sabakan.
+	osImage string
+	// ignitions is the ID of the latest ignition template of each role.
+	ignitions map[string]string
+}
+
+// outdatedNode is a node to be enrolled with the reason.
+type outdatedNode struct {
+	node   *corev1.Node
+	reason string
+}
+
+// findOutdatedNodes returns the nodes selected by the config that run an outdated OS image or ignition.
+// Nodes in the reboot list, nodes whose versions are unknown, and machines that are not healthy are skipped.
+func findOutdatedNodes(config *AutoEnroll, nodes []corev1.Node, rebootListEntries []*neco.RebootListEntry, versions *bootedVersions) ([]outdatedNode, error) {
+	sel, err := config.Selector.Selector()
+	if err != nil {
+		return nil, err
+	}
+	listed := make(map[string]bool)
+	for _, entry := range rebootListEntries {
+		listed[entry.Node] = true
+	}
+
+	outdated := []outdatedNode{}
+	for i := range nodes {
+		node := &nodes[i]
+		if !sel.Matches(labels.Set(node.Labels)) || listed[node.Name] {
+			continue
+		}
+		machine := versions.machines[node.Name]
+		tags, ok := versions.tags[node.Name]
+		if machine == nil || !ok || machine.Status.State != sabakanv3.StateHealthy {
+			continue
+		}
+
+		reasons := []string{}
+		if config.OSImage && versions.osImage != "" && tags[serfTagOSVersion] != "" && tags[serfTagOSVersion] != versions.osImage {
+			reasons = append(reasons, fmt.Sprintf("OS image %s is outdated (latest: %s)", tags[serfTagOSVersion], versions.osImage))
+		}
+		latest := versions.ignitions[machine.Spec.Role]
+		if config.Ignition && latest != "" && tags[serfTagVersion] != "" && tags[serfTagVersion] != latest {
+			reasons = append(reasons, fmt.Sprintf("ignition %s is outdated (latest: %s)", tags[serfTagVersion], latest))
+		}
+		if len(reasons) != 0 {
+			outdated = append(outdated, outdatedNode{node: node, reason: strings.Join(reasons, ", ")})
+		}
+	}
+	return outdated, nil
+}
+
+type autoEnroller struct {
+	config     *AutoEnroll
+	sabakan    *sabac.Client
+	kubeClient kubernetes.Interface
+}
+
+func newAutoEnroller(config *AutoEnroll) (*autoEnroller, error) {
+	c, err := sabac.NewClient(neco.SabakanLocalEndpoint, ext.LocalHTTPClient())
+	if err != nil {
+		return nil, err
+	}
+	return &autoEnroller{config: config, sabakan: c}, nil
+}
+
+func (a *autoEnroller) listNodes(ctx context.Context) ([]corev1.Node, error) {
+	if a.kubeClient == nil {
+		client, err := newKubernetesClient(a.config.Kubeconfig)
+		if err != nil {
+			return nil, err
+		}
+		a.kubeClient = client
+	}
+	nodes, err := a.kubeClient.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		// The credential may have been expired. Create a new client next time.
+		a.kubeClient = nil
+		return nil, err
+	}
+	return nodes.Items, nil
+}
+
+func (a *autoEnroller) getVersions(ctx context.Context) (*bootedVersions, error) {
+	versions := &bootedVersions{
+		tags:      make(map[string]map[string]string),
+		machines:  make(map[string]*sabakanv3.Machine),
+		ignitions: make(map[string]string),
+	}
+
+	sc, err := serf.NewRPCClient(a.config.SerfAddress)
+	if err != nil {
+		return nil, err
+	}
+	defer sc.Close()
+	members, err := sc.Members()
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range members {
+		if m.Status == "alive" {
+			versions.tags[m.Addr.String()] = m.Tags
+		}
+	}
+
+	machines, err := a.sabakan.MachinesGet(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	for i := range machines {
+		m := &machines[i]
+		if len(m.Spec.IPv4) == 0 {
+			continue
+		}
+		versions.machines[m.Spec.IPv4[0]] = m
+		if _, ok := versions.ignitions[m.Spec.Role]; ok || !a.config.Ignition {
+			continue
+		}
+		ids, err := a.sabakan.IgnitionsListIDs(ctx, m.Spec.Role)
+		if err != nil {
+			return nil, err
+		}
+		versions.ignitions[m.Spec.Role] = ""
+		if len(ids) != 0 {
+			// IDs are sorted in ascending order of the version.
+			versions.ignitions[m.Spec.Role] = ids[len(ids)-1]
+		}
+	}
+
+	if a.config.OSImage {
+		versions.osImage, err = sabakan.UploadedOSImage(ctx, ext.LocalHTTPClient())
+		if err != nil {
+			return nil, err
+		}
+	}
+	return versions, nil
+}
+
+// autoEnroll adds the nodes running an outdated OS image or ignition to the reboot list.
+func (c *Controller) autoEnroll(ctx context.Context) error {
+	logger := slog.With(slog.String("operation", "autoEnroll"))
+
+	nodes, err := c.autoEnroller.listNodes(ctx)
+	if err != nil {
+		return err
+	}
+	rebootListEntries, err := c.necoStorage.GetRebootListEntries(ctx)
+	if err != nil {
+		return err
+	}
+	versions, err := c.autoEnroller.getVersions(ctx)
+	if err != nil {
+		return err
+	}
+	outdated, err := findOutdatedNodes(c.autoEnroller.config, nodes, rebootListEntries, versions)
+	if err != nil {
+		return err
+	}
+
+	for _, o := range outdated {
+		group, ok := o.node.Labels[c.config.GroupLabelKey]
+		if !ok {
+			logger.Warn("node has no group label; skipping", slog.String("node", o.node.Name), slog.String("groupLabelKey", c.config.GroupLabelKey))
+			continue
+		}
+		rt, err := c.config.MatchRebootTime(o.node)
+		if err != nil {
+			logger.Warn("no reboot time for the node; skipping", slog.String("node", o.node.Name), slog.Any("error", err))
+			continue
+		}
+		entry := &neco.RebootListEntry{
+			Node:       o.node.Name,
+			Group:      group,
+			RebootTime: rt.Name,
+			Status:     neco.RebootListEntryStatusPending,
+		}
+		err = c.necoStorage.RegisterRebootListEntry(ctx, entry)
+		if err != nil {
+			return err
+		}
+		err = c.recordEvent(ctx, entry, neco.RebootListEventAdded, o.reason)
+		if err != nil {
+			return err
+		}
+		logger.Info("enrolled outdated node", slog.String("node", entry.Node), slog.String("group", group), slog.String("reason", o.reason))
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package necorebooter
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/cybozu-go/neco"
+	sabakan "github.com/cybozu-go/sabakan/v3"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestFindOutdatedNodes(t *testing.T) {
+	newNode := func(name, role string) corev1.Node {
+		return corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{"cke.cybozu.com/role": role}}}
+	}
+	newMachine := func(role string, state sabakan.MachineState) *sabakan.Machine {
+		return &sabakan.Machine{Spec: sabakan.MachineSpec{Role: role}, Status: sabakan.MachineStatus{State: state}}
+	}
+	nodes := []corev1.Node{
+		newNode("10.0.0.1", "cs"), // up to date
+		newNode("10.0.0.2", "cs"), // outdated OS image
+		newNode("10.0.0.3", "ss"), // outdated ignition
+		newNode("10.0.0.4", "cs"), // outdated both
+		newNode("10.0.0.5", "cs"), // already in the reboot list
+		newNode("10.0.0.6", "cs"), // not a serf member
+		newNode("10.0.0.7", "cs"), // unhealthy
+		newNode("10.0.0.8", "ss"), // unknown ignition version
+		newNode("10.0.0.9", "boot"),
+	}
+	versions := &bootedVersions{
+		tags: map[string]map[string]string{
+			"10.0.0.1": {serfTagOSVersion: "2.0", serfTagVersion: "2024.01.01-1"},
+			"10.0.0.2": {serfTagOSVersion: "1.0", serfTagVersion: "2024.01.01-1"},
+			"10.0.0.3": {serfTagOSVersion: "2.0", serfTagVersion: "2023.12.01-1"},
+			"10.0.0.4": {serfTagOSVersion: "1.0", serfTagVersion: "2023.12.01-1"},
+			"10.0.0.5": {serfTagOSVersion: "1.0", serfTagVersion: "2024.01.01-1"},
+			"10.0.0.7": {serfTagOSVersion: "1.0", serfTagVersion: "2024.01.01-1"},
+			"10.0.0.8": {serfTagOSVersion: "2.0"},
+			"10.0.0.9": {serfTagOSVersion: "1.0", serfTagVersion: "2023.12.01-1"},
+		},
+		machines: map[string]*sabakan.Machine{
+			"10.0.0.1": newMachine("cs", sabakan.StateHealthy),
+			"10.0.0.2": newMachine("cs", sabakan.StateHealthy),
+			"10.0.0.3": newMachine("ss", sabakan.StateHealthy),
+			"10.0.0.4": newMachine("cs", sabakan.StateHealthy),
+			"10.0.0.5": newMachine("cs", sabakan.StateHealthy),
+			"10.0.0.6": newMachine("cs", sabakan.StateHealthy),
+			"10.0.0.7": newMachine("cs", sabakan.StateUnhealthy),
+			"10.0.0.8": newMachine("ss", sabakan.StateHealthy),
+			"10.0.0.9": newMachine("boot", sabakan.StateHealthy),
+		},
+		osImage:   "2.0",
+		ignitions: map[string]string{"cs": "2024.01.01-1", "ss": "2024.01.01-1", "boot": "2024.01.01-1"},
+	}
+	entries := []*neco.RebootListEntry{{Node: "10.0.0.5"}}
+
+	config := &AutoEnroll{
+		OSImage:  true,
+		Ignition: true,
+		Selector: LabelSelector{
+			MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "cke.cybozu.com/role", Operator: metav1.LabelSelectorOpIn, Values: []string{"cs", "ss"}},
+			},
+		},
+	}
+	names := func(outdated []outdatedNode) []string {
+		ret := []string{}
+		for _, o := range outdated {
+			ret = append(ret, o.node.Name)
+		}
+		return ret
+	}
+
+	outdated, err := findOutdatedNodes(config, nodes, entries, versions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(names(outdated), []string{"10.0.0.2", "10.0.0.3", "10.0.0.4"}) {
+		t.Fatal("unexpected outdated nodes", names(outdated))
+	}
+	if r := outdated[2].reason; !strings.Contains(r, "OS image 1.0") || !strings.Contains(r, "ignition 2023.12.01-1") {
+		t.Error("unexpected reason", r)
+	}
+
+	config.Ignition = false
+	outdated, err = findOutdatedNodes(config, nodes, entries, versions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(names(outdated), []string{"10.0.0.2", "10.0.0.4"}) {
+		t.Error("unexpected outdated nodes with osImage only", names(outdated))
+	}
+
+	config.OSImage = false
+	config.Ignition = true
+	config.Selector = LabelSelector{MatchLabels: map[string]string{"cke.cybozu.com/role": "ss"}}
+	outdated, err = findOutdatedNodes(config, nodes, entries, versions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(names(outdated), []string{"10.0.0.3"}) {
+		t.Error("unexpected outdated nodes with ignition only", names(outdated))
+	}
+}
@@ -1,31 +1,53 @@
 package necorebooter
 
 import (
+	"errors"
+	"fmt"
 	"io"
+	"time"
 
 	"github.com/cybozu-go/cke"
 	"github.com/cybozu-go/etcdutil"
 	"github.com/robfig/cron/v3"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/yaml"
 )
 
-const DefaultMetricsPort = 10082
+const (
+	DefaultMetricsPort      = 10082
+	DefaultHistoryRetention = 90 * 24 * time.Hour
+	DefaultResyncInterval   = 10 * time.Minute
+)
 
 type Config struct {
 	RebootTimes   []RebootTimes `json:"rebootTimes"`
 	TimeZone      string        `json:"timeZone"`
 	GroupLabelKey string        `json:"groupLabelKey"`
 	MetricsPort   int           `json:"metricsPort"`
+	GroupOrder    GroupOrder    `json:"groupOrder"`
+	HealthGate    *HealthGate   `json:"healthGate"`
+	AutoEnroll    *AutoEnroll   `json:"autoEnroll"`
+	// MaxQueuedNodes is the maximum number of nodes queued in the reboot queue at the same time.
+	// 0 means unlimited.
+	MaxQueuedNodes int `json:"maxQueuedNodes"`
+	// HistoryRetention is the retention period of the audit events of the reboot list.
+	HistoryRetention metav1.Duration `json:"historyRetention"`
+	// ResyncInterval is the interval of processing the reboot list without any changes in etcd.
+	ResyncInterval metav1.Duration `json:"resyncInterval"`
 }
 
 type RebootTimes struct {
 	Name          string        `json:"name"`
 	LabelSelector LabelSelector `json:"labelSelector"`
 	Times         Times         `json:"times"`
+	// MaxQueuedNodes is the maximum number of nodes of this RebootTime queued in the reboot queue
+	// at the same time.  0 means unlimited.
+	MaxQueuedNodes int `json:"maxQueuedNodes"`
 }
 
 type LabelSelector struct {
-	MatchLabels map[string]string `json:"matchLabels"`
+	MatchLabels      map[string]string                 `json:"matchLabels"`
+	MatchExpressions []metav1.LabelSelectorRequirement `json:"matchExpressions"`
 }
 
 type Times struct {
@@ -44,15 +66,66 @@ func LoadConfig(reader io.Reader) (*Config, error) {
 		return nil, err
 	}
 	config := &Config{
-		MetricsPort: DefaultMetricsPort,
+		MetricsPort:      DefaultMetricsPort,
+		HistoryRetention: metav1.Duration{Duration: DefaultHistoryRetention},
+		ResyncInterval:   metav1.Duration{Duration: DefaultResyncInterval},
 	}
 	err = yaml.Unmarshal(data, config)
 	if err != nil {
 		return nil, err
 	}
+	err = validateRebootTimes(config.RebootTimes)
+	if err != nil {
+		return nil, err
+	}
+	err = config.GroupOrder.validate()
+	if err != nil {
+		return nil, err
+	}
+	if config.HistoryRetention.Duration <= 0 {
+		return nil, errors.New("historyRetention must be positive")
+	}
+	if config.ResyncInterval.Duration <= 0 {
+		return nil, errors.New("resyncInterval must be positive")
+	}
+	if config.MaxQueuedNodes < 0 {
+		return nil, errors.New("maxQueuedNodes must not be negative")
+	}
+	for _, rt := range config.RebootTimes {
+		if rt.MaxQueuedNodes < 0 {
+			return nil, fmt.Errorf("maxQueuedNodes of %s must not be negative", rt.Name)
+		}
+	}
+	if config.HealthGate != nil {
+		config.HealthGate.setDefaults()
+		err = config.HealthGate.validate()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if config.AutoEnroll != nil {
+		config.AutoEnroll.setDefaults()
+		err = config.AutoEnroll.validate()
+		if err != nil {
+			return nil, err
+		}
+	}
 	return config, nil
 }
 
+// hasQueueLimit returns true if the number of queued nodes is limited.
+func (c *Config) hasQueueLimit() bool {
+	if c.MaxQueuedNodes > 0 {
+		return true
+	}
+	for _, rt := range c.RebootTimes {
+		if rt.MaxQueuedNodes > 0 {
+			return true
+		}
+	}
+	return false
+}
+
 func (c *Config) GetRebootTime() (map[string]RebootTime, error) {
 	rebootTime := map[string]RebootTime{}
 	for _, rt := range c.RebootTimes {
@@ -3,6 +3,7 @@ package necorebooter
 import (
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestLoadConfig(t *testing.T) {
@@ -25,8 +26,23 @@ rebootTimes:
     times:
       allow:
         - "* 0-23 * * 1-5"
+    maxQueuedNodes: 1
 groupLabelKey: topology.kubernetes.io/zone
 metricsPort: 9102
+groupOrder:
+  strategy: explicit
+  groups:
+    - rack1
+    - rack0
+maxQueuedNodes: 5
+historyRetention: 720h
+healthGate:
+  timeout: 10m
+  kubernetes: true
+  sabakan: true
+  prometheus:
+    url: http://prometheus:9090
+    query: up{instance=~"($nodes):9100"} == 0
 `
 	config, err := LoadConfig(strings.NewReader(fileContent))
 	if err != nil {
@@ -41,6 +57,117 @@ metricsPort: 9102
 	if config.MetricsPort != 9102 {
 		t.Error("MetricsPort is not expected value")
 	}
+	if config.GroupOrder.Strategy != GroupOrderExplicit || len(config.GroupOrder.Groups) != 2 {
+		t.Error("GroupOrder is not expected value", config.GroupOrder)
+	}
+
+	_, err = LoadConfig(strings.NewReader("groupOrder:\n  strategy: unknown\n"))
+	if err == nil {
+		t.Error("unknown strategy should be rejected")
+	}
+	if config.HistoryRetention.Duration != 720*time.Hour {
+		t.Error("HistoryRetention is not expected value", config.HistoryRetention)
+	}
+	if config.MaxQueuedNodes != 5 {
+		t.Error("MaxQueuedNodes is not expected value", config.MaxQueuedNodes)
+	}
+	if config.RebootTimes[0].MaxQueuedNodes != 0 || config.RebootTimes[1].MaxQueuedNodes != 1 {
+		t.Error("MaxQueuedNodes of RebootTimes is not expected value")
+	}
+	_, err = LoadConfig(strings.NewReader("maxQueuedNodes: -1\n"))
+	if err == nil {
+		t.Error("negative maxQueuedNodes should be rejected")
+	}
+	if config.HealthGate == nil {
+		t.Fatal("HealthGate is not loaded")
+	}
+	if config.HealthGate.Timeout.Duration != 10*time.Minute {
+		t.Error("HealthGate.Timeout is not expected value", config.HealthGate.Timeout)
+	}
+	if !config.HealthGate.Kubernetes || config.HealthGate.Serf || !config.HealthGate.Sabakan {
+		t.Error("HealthGate checks are not expected value", config.HealthGate)
+	}
+	if config.HealthGate.SerfAddress != DefaultSerfAddress {
+		t.Error("HealthGate.SerfAddress is not defaulted", config.HealthGate.SerfAddress)
+	}
+	if config.HealthGate.Prometheus == nil || config.HealthGate.Prometheus.URL != "http://prometheus:9090" {
+		t.Error("HealthGate.Prometheus is not expected value", config.HealthGate.Prometheus)
+	}
+
+	_, err = LoadConfig(strings.NewReader("healthGate:\n  prometheus:\n    url: http://prometheus:9090\n"))
+	if err == nil {
+		t.Error("prometheus check without query should be rejected")
+	}
+	config2, err := LoadConfig(strings.NewReader("healthGate:\n  serf: true\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config2.HealthGate.Timeout.Duration != DefaultHealthGateTimeout {
+		t.Error("HealthGate.Timeout is not defaulted", config2.HealthGate.Timeout)
+	}
+	if config2.HistoryRetention.Duration != DefaultHistoryRetention {
+		t.Error("HistoryRetention is not defaulted", config2.HistoryRetention)
+	}
+	if config2.ResyncInterval.Duration != DefaultResyncInterval {
+		t.Error("ResyncInterval is not defaulted", config2.ResyncInterval)
+	}
+	_, err = LoadConfig(strings.NewReader("resyncInterval: 0s\n"))
+	if err == nil {
+		t.Error("zero resyncInterval should be rejected")
+	}
+
+	config4, err := LoadConfig(strings.NewReader("autoEnroll:\n  osImage: true\n  selector:\n    matchLabels:\n      cke.cybozu.com/role: cs\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config4.AutoEnroll.Interval.Duration != DefaultAutoEnrollInterval || config4.AutoEnroll.SerfAddress != DefaultSerfAddress {
+		t.Error("AutoEnroll is not defaulted", config4.AutoEnroll)
+	}
+	_, err = LoadConfig(strings.NewReader("autoEnroll:\n  osImage: true\n"))
+	if err == nil {
+		t.Error("autoEnroll without selector should be rejected")
+	}
+	_, err = LoadConfig(strings.NewReader("autoEnroll:\n  selector:\n    matchLabels:\n      cke.cybozu.com/role: cs\n"))
+	if err == nil {
+		t.Error("autoEnroll without osImage nor ignition should be rejected")
+	}
+
+	config3, err := LoadConfig(strings.NewReader(`
+rebootTimes:
+  - name: gpu
+    labelSelector:
+      matchExpressions:
+        - key: gpu
+          operator: Exists
+  - name: others
+    labelSelector:
+      matchExpressions:
+        - key: gpu
+          operator: DoesNotExist
+        - key: cke.cybozu.com/role
+          operator: In
+          values: [cs, ss]
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exprs := config3.RebootTimes[1].LabelSelector.MatchExpressions; len(exprs) != 2 || len(exprs[1].Values) != 2 {
+		t.Error("MatchExpressions is not expected value", exprs)
+	}
+	_, err = LoadConfig(strings.NewReader(`
+rebootTimes:
+  - name: cs
+    labelSelector:
+      matchLabels:
+        cke.cybozu.com/role: cs
+  - name: rack0
+    labelSelector:
+      matchLabels:
+        topology.kubernetes.io/zone: rack0
+`))
+	if err == nil {
+		t.Error("overlapping label selectors should be rejected")
+	}
 	for _, rt := range config.RebootTimes {
 		if rt.Name == "test1" {
 			if rt.LabelSelector.MatchLabels["cke.cybozu.com/role"] != "test1" {
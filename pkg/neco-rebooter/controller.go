@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"log/slog"
 	"slices"
+	"strings"
 	"time"
 
 	"github.com/cybozu-go/cke"
@@ -23,9 +24,24 @@ type Controller struct {
 	rebootTimes   map[string]RebootTime
 	sessionTTL    time.Duration
 	electionValue string
-	interval      time.Duration
 	leaderKey     string
 	timeZone      *time.Location
+
+	// trigger is notified when the reboot list or the reboot queue is changed.
+	trigger chan struct{}
+	// wakeUpAt is the next time to process the reboot list without any changes.
+	wakeUpAt time.Time
+
+	groupOrder         []string
+	groupOrderRecorded bool
+
+	healthCheckers []HealthChecker
+	// nodes rebooted in healthGateGroup, which are checked before moving to the next group.
+	healthGateGroup     string
+	rebootedNodes       map[string]struct{}
+	healthGateStartedAt time.Time
+
+	autoEnroller *autoEnroller
 }
 
 type EntrySet struct {
@@ -42,15 +58,22 @@ type EntriesCollection struct {
 	OrphanedEntry  []EntrySet
 }
 
-var (
-	allGroups []string
-)
-
 func NewController(config *Config, rt map[string]RebootTime, ckeStorage *cke.Storage, etcdClient *clientv3.Client, necoStorage *storage.Storage, electionValue string) (*Controller, error) {
 	tz, err := time.LoadLocation(config.TimeZone)
 	if err != nil {
 		return nil, err
 	}
+	checkers, err := NewHealthCheckers(config.HealthGate)
+	if err != nil {
+		return nil, err
+	}
+	var enroller *autoEnroller
+	if config.AutoEnroll != nil {
+		enroller, err = newAutoEnroller(config.AutoEnroll)
+		if err != nil {
+			return nil, err
+		}
+	}
 	return &Controller{
 		config:        *config,
 		rebootTimes:   rt,
@@ -59,8 +82,11 @@ func NewController(config *Config, rt map[string]RebootTime, ckeStorage *cke.Sto
 		necoStorage:   *necoStorage,
 		sessionTTL:    1 * time.Minute,
 		electionValue: electionValue,
-		interval:      1 * time.Minute,
 		timeZone:      tz,
+		trigger:       make(chan struct{}, 1),
+
+		healthCheckers: checkers,
+		autoEnroller:   enroller,
 	}, nil
 
 }
@@ -89,18 +115,26 @@ func (c *Controller) removeCompletedEntry(ctx context.Context, entries []*neco.R
 		if err != nil {
 			return err
 		}
+		err = c.recordEvent(ctx, entry, neco.RebootListEventCompleted, "")
+		if err != nil {
+			return err
+		}
 		slog.With(slog.String("operation", "removeCompletedEntry")).Info("removed completed entry", slog.String("node", entry.Node))
 	}
 	return nil
 }
 
-func (c *Controller) dequeueAndCancelEntry(ctx context.Context, entries []EntrySet) error {
+func (c *Controller) dequeueAndCancelEntry(ctx context.Context, entries []EntrySet, eventType, reason string) error {
 	for _, entry := range entries {
 		entry.rebootListEntry.Status = neco.RebootListEntryStatusPending
 		err := c.necoStorage.UpdateRebootListEntry(ctx, entry.rebootListEntry)
 		if err != nil {
 			return err
 		}
+		err = c.recordEvent(ctx, entry.rebootListEntry, eventType, reason)
+		if err != nil {
+			return err
+		}
 		if entry.rebootQueueEntry != nil {
 			entry.rebootQueueEntry.Status = cke.RebootStatusCancelled
 			err = c.ckeStorage.UpdateRebootsEntry(ctx, entry.rebootQueueEntry)
@@ -138,11 +172,36 @@ func (c *Controller) addRebootListEntry(ctx context.Context, entries []*neco.Reb
 		if err != nil {
 			return err
 		}
+		err = c.recordEvent(ctx, entry, neco.RebootListEventQueued, "")
+		if err != nil {
+			return err
+		}
 		slog.With(slog.String("operation", "addRebootListEntry")).Info("AddRebootListEntry", slog.String("node", entry.Node), slog.String("group", entry.Group))
 	}
 	return nil
 }
 
+// recordEvent records an audit event of the reboot list entry by the leader.
+func (c *Controller) recordEvent(ctx context.Context, entry *neco.RebootListEntry, eventType, reason string) error {
+	return c.necoStorage.RecordRebootListEvents(ctx, neco.NewRebootListEvent(entry, eventType, "neco-rebooter@"+c.electionValue, reason))
+}
+
+// pruneEvents deletes the audit events older than the retention.
+func (c *Controller) pruneEvents(ctx context.Context) error {
+	retention := c.config.HistoryRetention.Duration
+	if retention <= 0 {
+		return nil
+	}
+	n, err := c.necoStorage.PruneRebootListEvents(ctx, timeNowFunc().Add(-retention), c.leaderKey)
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		slog.With(slog.String("operation", "pruneEvents")).Info("pruned old reboot list events", slog.Int("count", n), slog.String("retention", retention.String()))
+	}
+	return nil
+}
+
 func (c *Controller) moveToNextGroup(ctx context.Context, candidate []string, processingGroup string) (string, error) {
 	for _, group := range candidate {
 		if group != processingGroup {
@@ -157,23 +216,105 @@ func (c *Controller) moveToNextGroup(ctx context.Context, candidate []string, pr
 	return processingGroup, nil
 }
 
+// updateGroupOrder records the expected group order in etcd if it has been changed.
+func (c *Controller) updateGroupOrder(ctx context.Context, groups []string) error {
+	if c.groupOrderRecorded && slices.Equal(c.groupOrder, groups) {
+		return nil
+	}
+	err := c.necoStorage.UpdateGroupOrder(ctx, c.leaderKey, groups)
+	if err != nil {
+		return err
+	}
+	c.groupOrder = groups
+	c.groupOrderRecorded = true
+	return nil
+}
+
+// recordRebootedNodes remembers the nodes in the processing group whose reboot has been completed.
+func (c *Controller) recordRebootedNodes(rebootListEntries []*neco.RebootListEntry, rebootQueueEntries []*cke.RebootQueueEntry, processingGroup string) {
+	if len(c.healthCheckers) == 0 {
+		return
+	}
+	if c.healthGateGroup != processingGroup {
+		c.healthGateGroup = processingGroup
+		c.rebootedNodes = nil
+		c.healthGateStartedAt = time.Time{}
+	}
+	for _, entry := range rebootListEntries {
+		if entry.Group != processingGroup || entry.Status != neco.RebootListEntryStatusQueued {
+			continue
+		}
+		if findRebootQueueEntryFromRebootListEntry(rebootQueueEntries, *entry) != nil {
+			continue
+		}
+		if c.rebootedNodes == nil {
+			c.rebootedNodes = make(map[string]struct{})
+		}
+		c.rebootedNodes[entry.Node] = struct{}{}
+	}
+}
+
+// checkHealthGate returns passed == true if the nodes rebooted in the processing group
+// are healthy and neco-rebooter can move to the next group.
+// If the nodes do not become healthy within the timeout, neco-rebooter pauses itself
+// and checkHealthGate returns paused == true.
+func (c *Controller) checkHealthGate(ctx context.Context) (passed, paused bool, err error) {
+	if len(c.healthCheckers) == 0 || len(c.rebootedNodes) == 0 {
+		return true, false, nil
+	}
+	logger := slog.With(slog.String("operation", "checkHealthGate"), slog.String("group", c.healthGateGroup))
+
+	nodes := make([]string, 0, len(c.rebootedNodes))
+	for node := range c.rebootedNodes {
+		nodes = append(nodes, node)
+	}
+	slices.Sort(nodes)
+
+	now := timeNowFunc()
+	if c.healthGateStartedAt.IsZero() {
+		c.healthGateStartedAt = now
+	}
+	failures := []string{}
+	for _, checker := range c.healthCheckers {
+		err := checker.Check(ctx, nodes)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %s", checker.Name(), err.Error()))
+		}
+	}
+	if len(failures) == 0 {
+		logger.Info("health gate passed", slog.Any("nodes", nodes))
+		c.rebootedNodes = nil
+		c.healthGateStartedAt = time.Time{}
+		return true, false, nil
+	}
+
+	if now.Sub(c.healthGateStartedAt) < c.config.HealthGate.Timeout.Duration {
+		logger.Info("waiting for rebooted nodes to become healthy", slog.Any("failures", failures))
+		return false, false, nil
+	}
+
+	reason := fmt.Sprintf("health gate for group %s timed out: %s", c.healthGateGroup, strings.Join(failures, "; "))
+	err = c.necoStorage.PauseNecoRebooter(ctx, c.leaderKey, reason)
+	if err != nil {
+		return false, false, err
+	}
+	logger.Error("paused reboot list processing", slog.String("reason", reason))
+	// The gate is checked again after reboot list processing is re-enabled.
+	c.healthGateStartedAt = time.Time{}
+	return false, true, nil
+}
+
 func (c *Controller) isRebootable(entry *neco.RebootListEntry) bool {
 	rebootTime, ok := c.rebootTimes[entry.RebootTime]
 	if !ok {
 		slog.With(slog.String("operation", "isRebootable")).Error("reboot time not found", slog.String("rebootTime", entry.RebootTime), slog.String("node", entry.Node))
 		return false
 	}
-	for _, deny := range rebootTime.Deny {
-		if isWithinSchedule(deny, c.timeZone) {
-			return false
-		}
-	}
-	for _, allow := range rebootTime.Allow {
-		if isWithinSchedule(allow, c.timeZone) {
-			return true
-		}
+	now := timeNowFunc()
+	if entry.IsPostponed(now) {
+		return false
 	}
-	return false
+	return isAllowedAt(rebootTime, now.In(c.timeZone))
 }
 
 func (c *Controller) findRebootableNodeInGroup(rebootListEntries []*neco.RebootListEntry, group string) []neco.RebootListEntry {
@@ -228,6 +369,8 @@ func (c *Controller) collectEntries(rebootListEntries []*neco.RebootListEntry, r
 			orphanedEntry = append(orphanedEntry, EntrySet{rlEntry, rqEntry})
 		}
 	}
+	// entries are queued in this order, and CKE reboots nodes in the order of the queue.
+	slices.SortFunc(newEntry, compareRebootListEntries)
 	return EntriesCollection{
 		CompletedEntry: completedEntry,
 		TimedOutEntry:  timedOutEntry,
@@ -247,6 +390,9 @@ func (c *Controller) runOnce(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
+	defer func() {
+		c.wakeUpAt = c.nextWakeUp(timeNowFunc(), rebootListEntries)
+	}()
 	rebootQueueEntries, err := c.ckeStorage.GetRebootsEntries(ctx)
 	if err != nil {
 		return err
@@ -255,21 +401,21 @@ func (c *Controller) runOnce(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
-	// this logic is implemented to perist shuffuled groups between runOnce calls
-	currnetAllGroups := getAllGroups(rebootListEntries)
-	if !isEqualContents(allGroups, currnetAllGroups) {
-		slog.Info("groups changed, updating allGroups", slog.String("new", fmt.Sprintf("%v", currnetAllGroups)))
-		allGroups = currnetAllGroups
+	groupOrder := expectedGroupOrder(c.config.GroupOrder, rebootListEntries, processingGroup)
+	err = c.updateGroupOrder(ctx, groupOrder)
+	if err != nil {
+		return err
 	}
 
-	currentIndex := slices.Index(allGroups, processingGroup)
-	allGroupsCycled := cycleSlices(allGroups, currentIndex)
+	c.recordRebootedNodes(rebootListEntries, rebootQueueEntries, processingGroup)
+
 	candidate := []string{}
-	for _, group := range allGroupsCycled {
+	for _, group := range groupOrder {
 		if len(c.findRebootableNodeInGroup(rebootListEntries, group)) != 0 {
 			candidate = append(candidate, group)
 		}
 	}
+	priorities := groupPriorities(rebootListEntries)
 	if len(candidate) != 0 && enabled {
 		if len(rebootQueueEntries) != 0 {
 			isStuck := true
@@ -281,7 +427,7 @@ func (c *Controller) runOnce(ctx context.Context) error {
 			if isStuck {
 				slog.Info("rebootQueue is stuck, moving to next group")
 				collection := c.collectEntries(rebootListEntries, rebootQueueEntries, processingGroup)
-				err = c.dequeueAndCancelEntry(ctx, collection.QueuedEntry)
+				err = c.dequeueAndCancelEntry(ctx, collection.QueuedEntry, neco.RebootListEventTimedOut, "reboot queue is stuck")
 				if err != nil {
 					return err
 				}
@@ -291,7 +437,29 @@ func (c *Controller) runOnce(ctx context.Context) error {
 				}
 			}
 		} else {
-			processingGroup, err = c.moveToNextGroup(ctx, candidate, processingGroup)
+			// keep processing the current group while it has the highest priority.
+			next := candidate
+			if len(candidate) > 1 && candidate[0] == processingGroup && priorities[processingGroup] > priorities[candidate[1]] {
+				next = candidate[:1]
+			} else if keepThrottledGroup(&c.config, rebootListEntries, candidate, priorities, processingGroup, c.isRebootable) {
+				// keep feeding the entries waiting for the limits of queued nodes.
+				next = []string{processingGroup}
+			}
+			if slices.ContainsFunc(next, func(g string) bool { return g != processingGroup }) {
+				passed, paused, err := c.checkHealthGate(ctx)
+				if err != nil {
+					return err
+				}
+				if paused {
+					// Stop processing the entries as if neco-rebooter were enabled.
+					// The pause triggers the next run, which processes them as disabled.
+					return nil
+				}
+				if !passed {
+					next = nil
+				}
+			}
+			processingGroup, err = c.moveToNextGroup(ctx, next, processingGroup)
 			if err != nil {
 				return err
 			}
@@ -323,21 +491,29 @@ func (c *Controller) runOnce(ctx context.Context) error {
 		return err
 	}
 	if enabled {
-		err = c.addRebootListEntry(ctx, collection.NewEntry)
+		queued := make([]*neco.RebootListEntry, len(collection.QueuedEntry))
+		for i, entry := range collection.QueuedEntry {
+			queued[i] = entry.rebootListEntry
+		}
+		newEntry := limitNewEntries(&c.config, collection.NewEntry, queued)
+		if len(newEntry) < len(collection.NewEntry) {
+			slog.Info("the number of queued nodes reached the limit", slog.Int("queued", len(queued)), slog.Int("waiting", len(collection.NewEntry)-len(newEntry)))
+		}
+		err = c.addRebootListEntry(ctx, newEntry)
 		if err != nil {
 			return err
 		}
-		err = c.dequeueAndCancelEntry(ctx, collection.TimedOutEntry)
+		err = c.dequeueAndCancelEntry(ctx, collection.TimedOutEntry, neco.RebootListEventDequeued, "reboot window closed")
 		if err != nil {
 			return err
 		}
 	} else {
-		err = c.dequeueAndCancelEntry(ctx, collection.QueuedEntry)
+		err = c.dequeueAndCancelEntry(ctx, collection.QueuedEntry, neco.RebootListEventDequeued, "neco-rebooter is disabled")
 		if err != nil {
 			return err
 		}
 	}
-	return nil
+	return c.pruneEvents(ctx)
 }
 
 func (c *Controller) Run(ctx context.Context) error {
@@ -395,27 +571,77 @@ func (c *Controller) Run(ctx context.Context) error {
 	}()
 
 	ctx, cancel := context.WithCancelCause(ctx)
+
+	// The watches are created before the first runOnce so that the changes
+	// made after runOnce reads the keys are notified.
+	listCh, err := startWatch(ctx, &c.etcdClient, storage.KeyNecoRebooterPrefix)
+	if err != nil {
+		cancel(nil)
+		return fmt.Errorf("failed to watch reboot list: %s", err.Error())
+	}
+	queueCh, err := startWatch(ctx, c.ckeStorage.Client, cke.KeyRebootsPrefix)
+	if err != nil {
+		cancel(nil)
+		return fmt.Errorf("failed to watch reboot queue: %s", err.Error())
+	}
+
 	go func(ctx context.Context) {
-		ticker := time.NewTicker(c.interval)
+		err := c.watchRebootList(ctx, listCh)
+		if err != nil && ctx.Err() == nil {
+			cancel(fmt.Errorf("failed to watch reboot list: %s", err.Error()))
+		}
+	}(ctx)
+
+	go func(ctx context.Context) {
+		err := c.watchRebootQueue(ctx, queueCh)
+		if err != nil && ctx.Err() == nil {
+			cancel(fmt.Errorf("failed to watch reboot queue: %s", err.Error()))
+		}
+	}(ctx)
+
+	go func(ctx context.Context) {
+		// The reboot list is processed when the keys are changed, when the timer for the next
+		// window boundary fires, or at least once in the resync interval.
+		timer := time.NewTimer(0)
 		defer func() {
-			slog.Warn("ticker stopped")
-			ticker.Stop()
+			slog.Warn("reboot list processing stopped")
+			timer.Stop()
 		}()
 
 		for {
 			select {
 			case <-ctx.Done():
 				return
-			case <-ticker.C:
-				if err := c.runOnce(ctx); err != nil {
-					slog.Error("An error occurred in runOnce", "err", err)
-					cancel(err)
-					return
-				}
+			case <-c.trigger:
+			case <-timer.C:
+			}
+			if err := c.runOnce(ctx); err != nil {
+				slog.Error("An error occurred in runOnce", "err", err)
+				cancel(err)
+				return
 			}
+			timer.Reset(time.Until(c.wakeUpAt))
 		}
 	}(ctx)
 
+	if c.autoEnroller != nil {
+		go func(ctx context.Context) {
+			ticker := time.NewTicker(c.config.AutoEnroll.Interval.Duration)
+			defer ticker.Stop()
+			for {
+				// Failures of the enrollment do not affect the processing of the reboot list.
+				if err := c.autoEnroll(ctx); err != nil {
+					slog.Error("failed to enroll outdated nodes", "err", err)
+				}
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+				}
+			}
+		}(ctx)
+	}
+
 	go func(ctx context.Context) {
 		defer func() {
 			slog.Warn("watcher stopped")
@@ -2,6 +2,7 @@ package necorebooter
 
 import (
 	"context"
+	"errors"
 	"os"
 	"strings"
 	"testing"
@@ -13,6 +14,7 @@ import (
 	"github.com/cybozu-go/neco/storage"
 	clientv3 "go.etcd.io/etcd/client/v3"
 	"go.etcd.io/etcd/client/v3/concurrency"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 func newTestController() (*Controller, error) {
@@ -167,6 +169,13 @@ func TestRemoveCompletedEntry(t *testing.T) {
 	if len(rlEntries) != 0 {
 		t.Error("removeCompletedEntry failed")
 	}
+	events, err := c.necoStorage.GetRebootListEvents(context.Background(), "node1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].Type != neco.RebootListEventCompleted || events[0].Actor != "neco-rebooter@"+c.electionValue {
+		t.Error("completed event is not recorded", events)
+	}
 }
 
 func TestDequeueAndCancelEntry(t *testing.T) {
@@ -206,7 +215,7 @@ func TestDequeueAndCancelEntry(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	err = c.dequeueAndCancelEntry(context.Background(), entrySet)
+	err = c.dequeueAndCancelEntry(context.Background(), entrySet, neco.RebootListEventDequeued, "reboot window closed")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -230,6 +239,13 @@ func TestDequeueAndCancelEntry(t *testing.T) {
 	if rqEntries[0].Status != cke.RebootStatusCancelled {
 		t.Error("dequeueAndCancelEntry failed")
 	}
+	events, err := c.necoStorage.GetRebootListEvents(context.Background(), "node1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].Type != neco.RebootListEventDequeued || events[0].Reason != "reboot window closed" {
+		t.Error("dequeued event is not recorded", events)
+	}
 }
 
 func TestRemoveOrphanedEntry(t *testing.T) {
@@ -676,6 +692,7 @@ groupLabelKey: topology.kubernetes.io/zone
 		t.Error("number of rebootTimes is not expected, actual ", len(rt))
 	}
 	c.rebootTimes = rt
+	postponedUntil := time.Date(2024, 1, 2, 2, 0, 0, 0, time.UTC)
 	rebootListEntries := []*neco.RebootListEntry{
 		{
 			Node:       "node1",
@@ -696,11 +713,20 @@ groupLabelKey: topology.kubernetes.io/zone
 			RebootTime: "test3",
 			Status:     neco.RebootListEntryStatusPending,
 		},
+		// node4 is postponed
+		{
+			Node:           "node4",
+			Group:          "group1",
+			RebootTime:     "test1",
+			Status:         neco.RebootListEntryStatusPending,
+			PostponedUntil: &postponedUntil,
+		},
 	}
 	expectedResult := map[string]bool{
 		"node1": true,
 		"node2": false,
 		"node3": false,
+		"node4": false,
 	}
 	timeNowFunc = func() time.Time {
 		return time.Date(2024, 1, 2, 1, 0, 0, 0, time.UTC) // 2024-01-02 (Tuesday) 01:00:00
@@ -781,3 +807,67 @@ groupLabelKey: topology.kubernetes.io/zone
 		t.Errorf("result is not expected, actual %s", node[0].Node)
 	}
 }
+
+func TestCheckHealthGateTimeout(t *testing.T) {
+	c, err := newTestController()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		err := cleanupEtcd()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	err = c.necoStorage.EnableNecoRebooter(context.Background(), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2024, 1, 2, 1, 0, 0, 0, time.UTC)
+	timeNowFunc = func() time.Time {
+		return start.Add(time.Hour)
+	}
+	c.config.HealthGate = &HealthGate{Timeout: metav1.Duration{Duration: 30 * time.Minute}}
+	c.healthCheckers = []HealthChecker{&fakeHealthChecker{err: errors.New("node is not ready")}}
+	c.healthGateGroup = "group1"
+	c.rebootedNodes = map[string]struct{}{"node1": {}}
+	c.healthGateStartedAt = start
+
+	passed, paused, err := c.checkHealthGate(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if passed {
+		t.Error("gate should not pass after timeout")
+	}
+	if !paused {
+		t.Error("gate should report the pause")
+	}
+	enabled, err := c.necoStorage.IsNecoRebooterEnabled(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if enabled {
+		t.Error("neco-rebooter should be paused")
+	}
+	reason, err := c.necoStorage.GetNecoRebooterPauseReason(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(reason, "group1") || !strings.Contains(reason, "node is not ready") {
+		t.Error("unexpected pause reason", reason)
+	}
+
+	err = c.necoStorage.EnableNecoRebooter(context.Background(), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reason, err = c.necoStorage.GetNecoRebooterPauseReason(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reason != "" {
+		t.Error("pause reason should be cleared by enabling", reason)
+	}
+}
@@ -0,0 +1,311 @@
+package necorebooter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/cybozu-go/neco"
+	"github.com/cybozu-go/neco/ext"
+	sabakan "github.com/cybozu-go/sabakan/v3"
+	sabac "github.com/cybozu-go/sabakan/v3/client"
+	serf "github.com/hashicorp/serf/client"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+const (
+	DefaultHealthGateTimeout = 30 * time.Minute
+	DefaultSerfAddress       = "127.0.0.1:7373"
+
+	// PrometheusNodesPlaceholder in a Prometheus query is replaced with
+	// a regular expression that matches the addresses of the rebooted nodes.
+	PrometheusNodesPlaceholder = "$nodes"
+)
+
+// HealthGate is the configuration of the health gate between groups.
+// neco-rebooter does not move to the next group until all the enabled checks pass
+// for the nodes rebooted in the current group.
+type HealthGate struct {
+	Timeout     metav1.Duration  `json:"timeout"`
+	Kubernetes  bool             `json:"kubernetes"`
+	Kubeconfig  string           `json:"kubeconfig"`
+	Serf        bool             `json:"serf"`
+	SerfAddress string           `json:"serfAddress"`
+	Sabakan     bool             `json:"sabakan"`
+	Prometheus  *PrometheusCheck `json:"prometheus"`
+}
+
+// PrometheusCheck is a check by a Prometheus query.
+// The check passes when the query returns no results.
+type PrometheusCheck struct {
+	URL   string `json:"url"`
+	Query string `json:"query"`
+}
+
+func (g *HealthGate) setDefaults() {
+	if g.Timeout.Duration == 0 {
+		g.Timeout.Duration = DefaultHealthGateTimeout
+	}
+	if g.SerfAddress == "" {
+		g.SerfAddress = DefaultSerfAddress
+	}
+}
+
+func (g *HealthGate) validate() error {
+	if g.Timeout.Duration < 0 {
+		return errors.New("healthGate.timeout must not be negative")
+	}
+	if g.Prometheus != nil {
+		if g.Prometheus.URL == "" {
+			return errors.New("healthGate.prometheus.url is required")
+		}
+		if g.Prometheus.Query == "" {
+			return errors.New("healthGate.prometheus.query is required")
+		}
+	}
+	return nil
+}
+
+// HealthChecker checks whether the rebooted nodes are healthy.
+// Nodes are identified by their IPv4 addresses.
+type HealthChecker interface {
+	Name() string
+	Check(ctx context.Context, nodes []string) error
+}
+
+// NewHealthCheckers creates the health checkers enabled in the gate.
+func NewHealthCheckers(gate *HealthGate) ([]HealthChecker, error) {
+	if gate == nil {
+		return nil, nil
+	}
+	checkers := []HealthChecker{}
+	if gate.Kubernetes {
+		checkers = append(checkers, &kubernetesHealthChecker{kubeconfig: gate.Kubeconfig})
+	}
+	if gate.Serf {
+		checkers = append(checkers, &serfHealthChecker{address: gate.SerfAddress})
+	}
+	if gate.Sabakan {
+		c, err := sabac.NewClient(neco.SabakanLocalEndpoint, ext.LocalHTTPClient())
+		if err != nil {
+			return nil, err
+		}
+		checkers = append(checkers, &sabakanHealthChecker{client: c})
+	}
+	if gate.Prometheus != nil {
+		checkers = append(checkers, &prometheusHealthChecker{
+			url:   gate.Prometheus.URL,
+			query: gate.Prometheus.Query,
+			http:  ext.LocalHTTPClient(),
+		})
+	}
+	return checkers, nil
+}
+
+type kubernetesHealthChecker struct {
+	kubeconfig string
+	client     kubernetes.Interface
+}
+
+func (k *kubernetesHealthChecker) Name() string {
+	return "kubernetes"
+}
+
+func (k *kubernetesHealthChecker) getClient() (kubernetes.Interface, error) {
+	if k.client != nil {
+		return k.client, nil
+	}
+	client, err := newKubernetesClient(k.kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+	k.client = client
+	return client, nil
+}
+
+// newKubernetesClient creates a Kubernetes client from the kubeconfig file.
+// If kubeconfig is empty, a kubeconfig is issued by ckecli.
+func newKubernetesClient(kubeconfig string) (kubernetes.Interface, error) {
+	var config clientcmd.ClientConfig
+	if kubeconfig != "" {
+		config = clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+			&clientcmd.ClientConfigLoadingRules{ExplicitPath: kubeconfig}, nil)
+	} else {
+		out, err := exec.Command(neco.CKECLIBin, "kubernetes", "issue").Output()
+		if err != nil {
+			return nil, fmt.Errorf("failed to issue kubeconfig: %w", err)
+		}
+		var cfg *clientcmdapi.Config
+		cfg, err = clientcmd.Load(out)
+		if err != nil {
+			return nil, err
+		}
+		config = clientcmd.NewDefaultClientConfig(*cfg, nil)
+	}
+	restConfig, err := config.ClientConfig()
+	if err != nil {
+		return nil, err
+	}
+	return kubernetes.NewForConfig(restConfig)
+}
+
+func (k *kubernetesHealthChecker) Check(ctx context.Context, nodes []string) error {
+	client, err := k.getClient()
+	if err != nil {
+		return err
+	}
+	for _, node := range nodes {
+		n, err := client.CoreV1().Nodes().Get(ctx, node, metav1.GetOptions{})
+		if err != nil {
+			// The credential may have been expired. Create a new client next time.
+			k.client = nil
+			return err
+		}
+		if !isNodeReady(n) {
+			return fmt.Errorf("node %s is not ready", node)
+		}
+	}
+	return nil
+}
+
+func isNodeReady(node *corev1.Node) bool {
+	for _, cond := range node.Status.Conditions {
+		if cond.Type == corev1.NodeReady {
+			return cond.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+type serfHealthChecker struct {
+	address string
+}
+
+func (s *serfHealthChecker) Name() string {
+	return "serf"
+}
+
+func (s *serfHealthChecker) Check(ctx context.Context, nodes []string) error {
+	c, err := serf.NewRPCClient(s.address)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	members, err := c.Members()
+	if err != nil {
+		return err
+	}
+	status := make(map[string]string)
+	for _, m := range members {
+		status[m.Addr.String()] = m.Status
+	}
+	for _, node := range nodes {
+		st, ok := status[node]
+		if !ok {
+			return fmt.Errorf("node %s is not a serf member", node)
+		}
+		if st != "alive" {
+			return fmt.Errorf("node %s is %s", node, st)
+		}
+	}
+	return nil
+}
+
+type sabakanHealthChecker struct {
+	client *sabac.Client
+}
+
+func (s *sabakanHealthChecker) Name() string {
+	return "sabakan"
+}
+
+func (s *sabakanHealthChecker) Check(ctx context.Context, nodes []string) error {
+	for _, node := range nodes {
+		machines, err := s.client.MachinesGet(ctx, map[string]string{"ipv4": node})
+		if err != nil {
+			return err
+		}
+		if len(machines) != 1 {
+			return fmt.Errorf("machine %s is not found", node)
+		}
+		if st := machines[0].Status.State; st != sabakan.StateHealthy {
+			return fmt.Errorf("machine %s is %s", node, st)
+		}
+	}
+	return nil
+}
+
+type prometheusHealthChecker struct {
+	url   string
+	query string
+	http  *http.Client
+}
+
+type prometheusResponse struct {
+	Status string `json:"status"`
+	Error  string `json:"error"`
+	Data   struct {
+		Result []json.RawMessage `json:"result"`
+	} `json:"data"`
+}
+
+func (p *prometheusHealthChecker) Name() string {
+	return "prometheus"
+}
+
+// nodesRegexp returns a regular expression matching nodes that can be embedded in a PromQL string literal.
+func nodesRegexp(nodes []string) string {
+	quoted := make([]string, len(nodes))
+	for i, node := range nodes {
+		quoted[i] = strings.ReplaceAll(regexp.QuoteMeta(node), `\`, `\\`)
+	}
+	return strings.Join(quoted, "|")
+}
+
+func (p *prometheusHealthChecker) Check(ctx context.Context, nodes []string) error {
+	query := strings.ReplaceAll(p.query, PrometheusNodesPlaceholder, nodesRegexp(nodes))
+	u, err := url.Parse(p.url)
+	if err != nil {
+		return err
+	}
+	u = u.JoinPath("/api/v1/query")
+	u.RawQuery = url.Values{"query": {query}}.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := p.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	var pr prometheusResponse
+	if err := json.Unmarshal(body, &pr); err != nil {
+		return fmt.Errorf("failed to parse the response from prometheus: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK || pr.Status != "success" {
+		return fmt.Errorf("query failed: status=%d, error=%s", resp.StatusCode, pr.Error)
+	}
+	if len(pr.Data.Result) != 0 {
+		return fmt.Errorf("query returned %d results", len(pr.Data.Result))
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+package necorebooter
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cybozu-go/cke"
+	"github.com/cybozu-go/neco"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+type fakeHealthChecker struct {
+	err     error
+	checked []string
+}
+
+func (f *fakeHealthChecker) Name() string {
+	return "fake"
+}
+
+func (f *fakeHealthChecker) Check(ctx context.Context, nodes []string) error {
+	f.checked = nodes
+	return f.err
+}
+
+func TestNodesRegexp(t *testing.T) {
+	actual := nodesRegexp([]string{"10.0.0.1", "10.0.0.2"})
+	expected := `10\\.0\\.0\\.1|10\\.0\\.0\\.2`
+	if actual != expected {
+		t.Errorf("expected %s, actual %s", expected, actual)
+	}
+}
+
+func TestPrometheusHealthChecker(t *testing.T) {
+	var result string
+	var query string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/query" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		query = r.URL.Query().Get("query")
+		w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":` + result + `}}`))
+	}))
+	defer srv.Close()
+
+	p := &prometheusHealthChecker{
+		url:   srv.URL,
+		query: `up{instance=~"($nodes):9100"} == 0`,
+		http:  srv.Client(),
+	}
+
+	result = `[]`
+	err := p.Check(context.Background(), []string{"10.0.0.1"})
+	if err != nil {
+		t.Error(err)
+	}
+	if query != `up{instance=~"(10\\.0\\.0\\.1):9100"} == 0` {
+		t.Error("unexpected query", query)
+	}
+
+	result = `[{"metric":{"instance":"10.0.0.1:9100"},"value":[0,"0"]}]`
+	err = p.Check(context.Background(), []string{"10.0.0.1"})
+	if err == nil {
+		t.Error("check should fail when the query returns results")
+	}
+
+	p.url = srv.URL + "/notfound"
+	err = p.Check(context.Background(), []string{"10.0.0.1"})
+	if err == nil {
+		t.Error("check should fail when the query fails")
+	}
+}
+
+func TestKubernetesHealthChecker(t *testing.T) {
+	newNode := func(name string, status corev1.ConditionStatus) *corev1.Node {
+		return &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status: corev1.NodeStatus{
+				Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: status}},
+			},
+		}
+	}
+	k := &kubernetesHealthChecker{
+		client: fake.NewSimpleClientset(
+			newNode("10.0.0.1", corev1.ConditionTrue),
+			newNode("10.0.0.2", corev1.ConditionFalse),
+		),
+	}
+
+	err := k.Check(context.Background(), []string{"10.0.0.1"})
+	if err != nil {
+		t.Error(err)
+	}
+	err = k.Check(context.Background(), []string{"10.0.0.1", "10.0.0.2"})
+	if err == nil {
+		t.Error("check should fail when a node is not ready")
+	}
+}
+
+func TestRecordRebootedNodes(t *testing.T) {
+	c := &Controller{healthCheckers: []HealthChecker{&fakeHealthChecker{}}}
+	rl := []*neco.RebootListEntry{
+		{Index: 0, Node: "10.0.0.1", Group: "rack0", Status: neco.RebootListEntryStatusQueued},
+		{Index: 1, Node: "10.0.0.2", Group: "rack0", Status: neco.RebootListEntryStatusQueued},
+		{Index: 2, Node: "10.0.0.3", Group: "rack0", Status: neco.RebootListEntryStatusPending},
+		{Index: 3, Node: "10.0.0.4", Group: "rack1", Status: neco.RebootListEntryStatusQueued},
+	}
+	rq := []*cke.RebootQueueEntry{
+		{Index: 0, Node: "10.0.0.2", Status: cke.RebootStatusRebooting},
+		{Index: 1, Node: "10.0.0.4", Status: cke.RebootStatusQueued},
+	}
+
+	c.recordRebootedNodes(rl, rq, "rack0")
+	if len(c.rebootedNodes) != 1 {
+		t.Fatal("unexpected rebooted nodes", c.rebootedNodes)
+	}
+	if _, ok := c.rebootedNodes["10.0.0.1"]; !ok {
+		t.Error("10.0.0.1 should be recorded", c.rebootedNodes)
+	}
+
+	c.recordRebootedNodes(rl, nil, "rack1")
+	if len(c.rebootedNodes) != 1 {
+		t.Fatal("rebooted nodes should be reset when the group is changed", c.rebootedNodes)
+	}
+	if _, ok := c.rebootedNodes["10.0.0.4"]; !ok {
+		t.Error("10.0.0.4 should be recorded", c.rebootedNodes)
+	}
+}
+
+func TestCheckHealthGate(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	timeNowFunc = func() time.Time {
+		return now
+	}
+	defer func() {
+		timeNowFunc = time.Now
+	}()
+
+	checker := &fakeHealthChecker{err: errors.New("not ready")}
+	c := &Controller{
+		config: Config{
+			HealthGate: &HealthGate{Timeout: metav1.Duration{Duration: 10 * time.Minute}},
+		},
+		healthCheckers:  []HealthChecker{checker},
+		healthGateGroup: "rack0",
+	}
+
+	passed, _, err := c.checkHealthGate(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !passed {
+		t.Error("gate should pass when no nodes have been rebooted")
+	}
+
+	c.rebootedNodes = map[string]struct{}{"10.0.0.2": {}, "10.0.0.1": {}}
+	passed, _, err = c.checkHealthGate(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if passed {
+		t.Error("gate should not pass while the check fails")
+	}
+	if len(checker.checked) != 2 || checker.checked[0] != "10.0.0.1" {
+		t.Error("unexpected checked nodes", checker.checked)
+	}
+	if !c.healthGateStartedAt.Equal(now) {
+		t.Error("healthGateStartedAt is not recorded", c.healthGateStartedAt)
+	}
+
+	now = now.Add(5 * time.Minute)
+	checker.err = nil
+	passed, _, err = c.checkHealthGate(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !passed {
+		t.Error("gate should pass when the check succeeds")
+	}
+	if len(c.rebootedNodes) != 0 || !c.healthGateStartedAt.IsZero() {
+		t.Error("gate state should be reset after passing")
+	}
+}
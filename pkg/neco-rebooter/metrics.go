@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/cybozu-go/neco"
 	"github.com/cybozu-go/neco/storage"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
@@ -28,6 +29,8 @@ type collector struct {
 	isEnabled        *prometheus.Desc
 	processingGroup  *prometheus.Desc
 	rebootListStatus *prometheus.Desc
+	overdue          *prometheus.Desc
+	groupOrder       *prometheus.Desc
 	storage          storage.Storage
 	hostname         string
 }
@@ -64,6 +67,18 @@ func NewCollector(storage storage.Storage, hostname string) *collector {
 			[]string{"node", "rebootTime", "group", "status"},
 			nil,
 		),
+		overdue: prometheus.NewDesc(
+			"neco_rebooter_reboot_list_overdue",
+			"",
+			[]string{"node", "rebootTime", "group"},
+			nil,
+		),
+		groupOrder: prometheus.NewDesc(
+			"neco_rebooter_group_order",
+			"",
+			[]string{"group"},
+			nil,
+		),
 		storage:  storage,
 		hostname: hostname,
 	}
@@ -86,6 +101,8 @@ func (c *collector) Describe(ch chan<- *prometheus.Desc) {
 	ch <- c.isEnabled
 	ch <- c.processingGroup
 	ch <- c.rebootListStatus
+	ch <- c.overdue
+	ch <- c.groupOrder
 }
 
 func (c *collector) Collect(ch chan<- prometheus.Metric) {
@@ -96,6 +113,8 @@ func (c *collector) Collect(ch chan<- prometheus.Metric) {
 	c.updateIsEnabled(ch, ctx)
 	c.updateProcessingGroup(ch, ctx)
 	c.updateRebootListStatus(ch, ctx)
+	c.updateOverdue(ch, ctx)
+	c.updateGroupOrder(ch, ctx)
 }
 
 func (c *collector) updateLeader(ch chan<- prometheus.Metric, ctx context.Context) {
@@ -160,3 +179,29 @@ func (c *collector) updateRebootListStatus(ch chan<- prometheus.Metric, ctx cont
 		ch <- prometheus.MustNewConstMetric(c.rebootListStatus, prometheus.GaugeValue, 1, entry.Node, entry.RebootTime, entry.Group, entry.Status)
 	}
 }
+
+func (c *collector) updateOverdue(ch chan<- prometheus.Metric, ctx context.Context) {
+	entries, err := c.storage.GetRebootListEntries(ctx)
+	if err != nil {
+		slog.Error("failed to get reboot list", "err", err)
+		return
+	}
+	now := time.Now()
+	for _, entry := range entries {
+		if entry.Status == neco.RebootListEntryStatusCancelled || !entry.IsOverdue(now) {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(c.overdue, prometheus.GaugeValue, 1, entry.Node, entry.RebootTime, entry.Group)
+	}
+}
+
+func (c *collector) updateGroupOrder(ch chan<- prometheus.Metric, ctx context.Context) {
+	groups, err := c.storage.GetGroupOrder(ctx)
+	if err != nil {
+		slog.Error("failed to get group order", "err", err)
+		return
+	}
+	for i, group := range groups {
+		ch <- prometheus.MustNewConstMetric(c.groupOrder, prometheus.GaugeValue, float64(i), group)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/cybozu-go/neco"
 )
@@ -25,12 +26,14 @@ func TestMetrics(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
+	deadline := time.Now().Add(-time.Hour)
 	rebootListEntries := []*neco.RebootListEntry{
 		{
 			Node:       "node1",
 			Group:      "group1",
 			RebootTime: "test1",
 			Status:     neco.RebootListEntryStatusPending,
+			Deadline:   &deadline,
 		},
 		{
 			Node:       "node2",
@@ -55,6 +58,10 @@ func TestMetrics(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
+	err = c.necoStorage.UpdateGroupOrder(context.Background(), c.leaderKey, []string{"group2", "group1", "group3"})
+	if err != nil {
+		t.Fatal(err)
+	}
 	hostname, err := os.Hostname()
 	if err != nil {
 		t.Fatal(err)
@@ -98,6 +105,14 @@ func TestMetrics(t *testing.T) {
 			name:   "test3",
 			expect: `neco_rebooter_reboot_list_status{group="group3",node="node3",rebootTime="test3",status="Cancelled"} 1`,
 		},
+		{
+			name:   "groupOrder",
+			expect: `neco_rebooter_group_order{group="group1"} 1`,
+		},
+		{
+			name:   "overdue",
+			expect: `neco_rebooter_reboot_list_overdue{group="group1",node="node1",rebootTime="test1"} 1`,
+		},
 	}
 	metrics := rec.Body.String()
 	for _, tc := range cases {
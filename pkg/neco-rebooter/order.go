@@ -0,0 +1,141 @@
+package necorebooter
+
+import (
+	"cmp"
+	"fmt"
+	"log/slog"
+	"math"
+	"slices"
+
+	"github.com/cybozu-go/neco"
+)
+
+// Group ordering strategies.
+const (
+	GroupOrderRandom               = "random"
+	GroupOrderAlphabetical         = "alphabetical"
+	GroupOrderOldestEntryFirst     = "oldest-entry-first"
+	GroupOrderFewestRemainingFirst = "fewest-remaining-first"
+	GroupOrderExplicit             = "explicit"
+)
+
+var (
+	allGroups []string
+)
+
+// GroupOrder specifies the order in which groups are processed.
+type GroupOrder struct {
+	// Strategy is one of the group ordering strategies.
+	// The default is GroupOrderRandom.
+	Strategy string `json:"strategy"`
+
+	// Groups is the order of groups for GroupOrderExplicit.
+	// Groups not listed here are processed after the listed ones in
+	// alphabetical order.
+	Groups []string `json:"groups"`
+}
+
+func (o GroupOrder) validate() error {
+	switch o.Strategy {
+	case "", GroupOrderRandom, GroupOrderAlphabetical, GroupOrderOldestEntryFirst, GroupOrderFewestRemainingFirst:
+		if len(o.Groups) != 0 {
+			return fmt.Errorf("groups can be specified only for %s strategy", GroupOrderExplicit)
+		}
+	case GroupOrderExplicit:
+		if len(o.Groups) == 0 {
+			return fmt.Errorf("groups must be specified for %s strategy", GroupOrderExplicit)
+		}
+	default:
+		return fmt.Errorf("unknown group order strategy: %s", o.Strategy)
+	}
+	return nil
+}
+
+// cyclic returns true if the order does not depend on the entries in the
+// reboot list.  Such order is processed in a round-robin manner.
+func (o GroupOrder) cyclic() bool {
+	switch o.Strategy {
+	case GroupOrderOldestEntryFirst, GroupOrderFewestRemainingFirst:
+		return false
+	}
+	return true
+}
+
+// orderGroups returns all the groups in the reboot list in the order of the strategy.
+func orderGroups(order GroupOrder, rebootListEntries []*neco.RebootListEntry) []string {
+	switch order.Strategy {
+	case "", GroupOrderRandom:
+		// this logic is implemented to perist shuffuled groups between runOnce calls
+		currnetAllGroups := getAllGroups(rebootListEntries)
+		if !isEqualContents(allGroups, currnetAllGroups) {
+			slog.Info("groups changed, updating allGroups", slog.String("new", fmt.Sprintf("%v", currnetAllGroups)))
+			allGroups = currnetAllGroups
+		}
+		return slices.Clone(allGroups)
+	}
+
+	groups := make([]string, 0)
+	oldest := make(map[string]int64)
+	remaining := make(map[string]int)
+	for _, entry := range rebootListEntries {
+		if _, ok := oldest[entry.Group]; !ok {
+			groups = append(groups, entry.Group)
+			oldest[entry.Group] = math.MaxInt64
+		}
+		if entry.Status == neco.RebootListEntryStatusCancelled {
+			continue
+		}
+		oldest[entry.Group] = min(oldest[entry.Group], entry.Index)
+		remaining[entry.Group]++
+	}
+	slices.Sort(groups)
+
+	switch order.Strategy {
+	case GroupOrderOldestEntryFirst:
+		slices.SortStableFunc(groups, func(a, b string) int {
+			return cmp.Compare(oldest[a], oldest[b])
+		})
+	case GroupOrderFewestRemainingFirst:
+		slices.SortStableFunc(groups, func(a, b string) int {
+			return cmp.Compare(remaining[a], remaining[b])
+		})
+	case GroupOrderExplicit:
+		slices.SortStableFunc(groups, func(a, b string) int {
+			ia := slices.Index(order.Groups, a)
+			ib := slices.Index(order.Groups, b)
+			switch {
+			case ia < 0 && ib < 0:
+				return 0
+			case ia < 0:
+				return 1
+			case ib < 0:
+				return -1
+			}
+			return cmp.Compare(ia, ib)
+		})
+	}
+	return groups
+}
+
+// expectedGroupOrder returns all the groups in the reboot list in the order
+// to be processed.  The processing group comes first if it is in the list.
+//
+// For cyclic strategies, the groups after the processing group come next
+// in a round-robin manner.  Groups with higher priority come before the
+// others except for the processing group.
+func expectedGroupOrder(order GroupOrder, rebootListEntries []*neco.RebootListEntry, processingGroup string) []string {
+	groups := orderGroups(order, rebootListEntries)
+	idx := slices.Index(groups, processingGroup)
+	if order.cyclic() {
+		groups = cycleSlices(groups, idx)
+	} else if idx > 0 {
+		groups = append([]string{processingGroup}, slices.Delete(groups, idx, idx+1)...)
+	}
+
+	rest := groups
+	if len(groups) > 0 && groups[0] == processingGroup {
+		rest = groups[1:]
+	}
+	sortGroupsByPriority(rest, groupPriorities(rebootListEntries))
+	return groups
+}
@@ -0,0 +1,143 @@
+package necorebooter
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/cybozu-go/neco"
+)
+
+func TestOrderGroups(t *testing.T) {
+	rl := []*neco.RebootListEntry{
+		{Index: 0, Group: "rack2", Status: neco.RebootListEntryStatusCancelled},
+		{Index: 1, Group: "rack3"},
+		{Index: 2, Group: "rack1"},
+		{Index: 3, Group: "rack1"},
+		{Index: 4, Group: "rack2"},
+		{Index: 5, Group: "rack0"},
+		{Index: 6, Group: "rack0"},
+		{Index: 7, Group: "rack0"},
+	}
+
+	cases := []struct {
+		name     string
+		order    GroupOrder
+		expected []string
+	}{
+		{
+			name:     "alphabetical",
+			order:    GroupOrder{Strategy: GroupOrderAlphabetical},
+			expected: []string{"rack0", "rack1", "rack2", "rack3"},
+		},
+		{
+			name:     "oldest-entry-first",
+			order:    GroupOrder{Strategy: GroupOrderOldestEntryFirst},
+			expected: []string{"rack3", "rack1", "rack2", "rack0"},
+		},
+		{
+			name:     "fewest-remaining-first",
+			order:    GroupOrder{Strategy: GroupOrderFewestRemainingFirst},
+			expected: []string{"rack2", "rack3", "rack1", "rack0"},
+		},
+		{
+			name:     "explicit",
+			order:    GroupOrder{Strategy: GroupOrderExplicit, Groups: []string{"rack2", "rack9", "rack0"}},
+			expected: []string{"rack2", "rack0", "rack1", "rack3"},
+		},
+	}
+	for _, tt := range cases {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			actual := orderGroups(tt.order, rl)
+			if !slices.Equal(tt.expected, actual) {
+				t.Errorf("unexpected result: want=%v, got=%v", tt.expected, actual)
+			}
+		})
+	}
+
+	random := orderGroups(GroupOrder{}, rl)
+	if !isEqualContents(random, []string{"rack0", "rack1", "rack2", "rack3"}) {
+		t.Errorf("unexpected result: %v", random)
+	}
+	if !slices.Equal(random, orderGroups(GroupOrder{Strategy: GroupOrderRandom}, rl)) {
+		t.Error("random order should be kept while the groups are not changed")
+	}
+}
+
+func TestExpectedGroupOrder(t *testing.T) {
+	rl := []*neco.RebootListEntry{
+		{Index: 0, Group: "rack3"},
+		{Index: 1, Group: "rack1", Priority: 10},
+		{Index: 2, Group: "rack0"},
+		{Index: 3, Group: "rack2"},
+		{Index: 4, Group: "rack2"},
+	}
+
+	cases := []struct {
+		name            string
+		order           GroupOrder
+		processingGroup string
+		expected        []string
+	}{
+		{
+			name:            "cyclic",
+			order:           GroupOrder{Strategy: GroupOrderAlphabetical},
+			processingGroup: "rack2",
+			expected:        []string{"rack2", "rack1", "rack3", "rack0"},
+		},
+		{
+			name:            "cyclic-no-processing-group",
+			order:           GroupOrder{Strategy: GroupOrderAlphabetical},
+			processingGroup: "rack9",
+			expected:        []string{"rack1", "rack0", "rack2", "rack3"},
+		},
+		{
+			name:            "non-cyclic",
+			order:           GroupOrder{Strategy: GroupOrderFewestRemainingFirst},
+			processingGroup: "rack2",
+			expected:        []string{"rack2", "rack1", "rack0", "rack3"},
+		},
+		{
+			name:            "processing-group-with-priority",
+			order:           GroupOrder{Strategy: GroupOrderOldestEntryFirst},
+			processingGroup: "rack1",
+			expected:        []string{"rack1", "rack3", "rack0", "rack2"},
+		},
+	}
+	for _, tt := range cases {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			actual := expectedGroupOrder(tt.order, rl, tt.processingGroup)
+			if !slices.Equal(tt.expected, actual) {
+				t.Errorf("unexpected result: want=%v, got=%v", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestGroupOrderValidate(t *testing.T) {
+	valid := []GroupOrder{
+		{},
+		{Strategy: GroupOrderRandom},
+		{Strategy: GroupOrderAlphabetical},
+		{Strategy: GroupOrderOldestEntryFirst},
+		{Strategy: GroupOrderFewestRemainingFirst},
+		{Strategy: GroupOrderExplicit, Groups: []string{"rack0"}},
+	}
+	for _, o := range valid {
+		if err := o.validate(); err != nil {
+			t.Errorf("%v should be valid: %v", o, err)
+		}
+	}
+
+	invalid := []GroupOrder{
+		{Strategy: "unknown"},
+		{Strategy: GroupOrderExplicit},
+		{Strategy: GroupOrderAlphabetical, Groups: []string{"rack0"}},
+	}
+	for _, o := range invalid {
+		if err := o.validate(); err == nil {
+			t.Errorf("%v should be invalid", o)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package necorebooter
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+)
+
+const (
+	// AnnotationRebootTime is the annotation of a node to specify the name of RebootTime
+	// regardless of the label selectors.
+	AnnotationRebootTime = "rebooter.neco.cybozu.io/reboot-time"
+	// AnnotationOptOut is the annotation of a node to exclude the node from neco-rebooter.
+	// The value is parsed by strconv.ParseBool.
+	AnnotationOptOut = "rebooter.neco.cybozu.io/opt-out"
+)
+
+// Selector returns the labels.Selector of s.
+// The semantics is the same as Kubernetes's LabelSelector.
+func (s LabelSelector) Selector() (labels.Selector, error) {
+	return metav1.LabelSelectorAsSelector(&metav1.LabelSelector{
+		MatchLabels:      s.MatchLabels,
+		MatchExpressions: s.MatchExpressions,
+	})
+}
+
+// String returns the string representation of s such as "key1=value1,key2 in (a,b)".
+func (s LabelSelector) String() string {
+	sel, err := s.Selector()
+	if err != nil {
+		return fmt.Sprintf("<invalid: %v>", err)
+	}
+	return sel.String()
+}
+
+// validateRebootTimes checks that the names of RebootTimes are unique, the label selectors
+// are valid, and no node can match multiple label selectors.
+func validateRebootTimes(rebootTimes []RebootTimes) error {
+	selectors := make([]labels.Selector, len(rebootTimes))
+	for i, rt := range rebootTimes {
+		if rt.Name == "" {
+			return errors.New("name of rebootTimes must not be empty")
+		}
+		if slices.ContainsFunc(rebootTimes[:i], func(r RebootTimes) bool { return r.Name == rt.Name }) {
+			return fmt.Errorf("duplicate rebootTimes name: %s", rt.Name)
+		}
+		sel, err := rt.LabelSelector.Selector()
+		if err != nil {
+			return fmt.Errorf("invalid labelSelector of %s: %w", rt.Name, err)
+		}
+		selectors[i] = sel
+	}
+
+	for i := range rebootTimes {
+		for j := i + 1; j < len(rebootTimes); j++ {
+			if !areDisjoint(selectors[i], selectors[j]) {
+				return fmt.Errorf("labelSelectors of %s (%s) and %s (%s) may select the same node; make them mutually exclusive or use the %s annotation",
+					rebootTimes[i].Name, selectors[i], rebootTimes[j].Name, selectors[j], AnnotationRebootTime)
+			}
+		}
+	}
+	return nil
+}
+
+// areDisjoint returns true if no set of labels can match both a and b.
+// This is conservative; it may return false for some disjoint selectors,
+// e.g. those using GreaterThan or LessThan.
+func areDisjoint(a, b labels.Selector) bool {
+	ra, _ := a.Requirements()
+	rb, _ := b.Requirements()
+	for _, x := range ra {
+		for _, y := range rb {
+			if x.Key() == y.Key() && (conflicts(x, y) || conflicts(y, x)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// conflicts returns true if no value of the key can satisfy both x and y.
+func conflicts(x, y labels.Requirement) bool {
+	switch x.Operator() {
+	case selection.In, selection.Equals, selection.DoubleEquals:
+		switch y.Operator() {
+		case selection.In, selection.Equals, selection.DoubleEquals:
+			return !x.Values().HasAny(y.Values().UnsortedList()...)
+		case selection.NotIn, selection.NotEquals:
+			return y.Values().IsSuperset(x.Values())
+		case selection.DoesNotExist:
+			return true
+		}
+	case selection.Exists:
+		return y.Operator() == selection.DoesNotExist
+	}
+	return false
+}
+
+// MatchRebootTime returns the RebootTimes for the node.
+// The node annotation AnnotationRebootTime takes precedence over the label selectors,
+// and nodes annotated with AnnotationOptOut are rejected.
+func (c *Config) MatchRebootTime(node *corev1.Node) (*RebootTimes, error) {
+	if v, ok := node.Annotations[AnnotationOptOut]; ok {
+		optOut, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("node: %s has invalid %s annotation: %w", node.Name, AnnotationOptOut, err)
+		}
+		if optOut {
+			return nil, fmt.Errorf("node: %s is opted out by %s annotation", node.Name, AnnotationOptOut)
+		}
+	}
+
+	if name, ok := node.Annotations[AnnotationRebootTime]; ok {
+		for i := range c.RebootTimes {
+			if c.RebootTimes[i].Name == name {
+				return &c.RebootTimes[i], nil
+			}
+		}
+		return nil, fmt.Errorf("node: %s has %s annotation with unknown reboot time %q", node.Name, AnnotationRebootTime, name)
+	}
+
+	var matched []*RebootTimes
+	for i := range c.RebootTimes {
+		sel, err := c.RebootTimes[i].LabelSelector.Selector()
+		if err != nil {
+			return nil, err
+		}
+		if sel.Matches(labels.Set(node.Labels)) {
+			matched = append(matched, &c.RebootTimes[i])
+		}
+	}
+	switch len(matched) {
+	case 0:
+		return nil, fmt.Errorf("node: %s does not match any reboot time", node.Name)
+	case 1:
+		return matched[0], nil
+	}
+	names := make([]string, len(matched))
+	for i, rt := range matched {
+		names[i] = rt.Name
+	}
+	return nil, fmt.Errorf("node: %s matches multiple reboot times: %s", node.Name, strings.Join(names, ", "))
+}
@@ -0,0 +1,221 @@
+package necorebooter
+
+import (
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestValidateRebootTimes(t *testing.T) {
+	testCases := []struct {
+		name      string
+		selectors []LabelSelector
+		valid     bool
+	}{
+		{
+			name: "different values",
+			selectors: []LabelSelector{
+				{MatchLabels: map[string]string{"role": "cs"}},
+				{MatchLabels: map[string]string{"role": "ss"}},
+			},
+			valid: true,
+		},
+		{
+			name: "In and NotIn",
+			selectors: []LabelSelector{
+				{MatchLabels: map[string]string{"role": "cs"}, MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "rack", Operator: metav1.LabelSelectorOpIn, Values: []string{"0", "1"}},
+				}},
+				{MatchLabels: map[string]string{"role": "cs"}, MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "rack", Operator: metav1.LabelSelectorOpNotIn, Values: []string{"0", "1"}},
+				}},
+			},
+			valid: true,
+		},
+		{
+			name: "Exists and DoesNotExist",
+			selectors: []LabelSelector{
+				{MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "gpu", Operator: metav1.LabelSelectorOpExists},
+				}},
+				{MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "gpu", Operator: metav1.LabelSelectorOpDoesNotExist},
+				}},
+			},
+			valid: true,
+		},
+		{
+			name: "different keys",
+			selectors: []LabelSelector{
+				{MatchLabels: map[string]string{"role": "cs"}},
+				{MatchLabels: map[string]string{"rack": "0"}},
+			},
+		},
+		{
+			name: "partially overlapping In",
+			selectors: []LabelSelector{
+				{MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "rack", Operator: metav1.LabelSelectorOpIn, Values: []string{"0", "1"}},
+				}},
+				{MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "rack", Operator: metav1.LabelSelectorOpNotIn, Values: []string{"0"}},
+				}},
+			},
+		},
+		{
+			name: "empty selector",
+			selectors: []LabelSelector{
+				{MatchLabels: map[string]string{"role": "cs"}},
+				{},
+			},
+		},
+		{
+			name: "invalid operator",
+			selectors: []LabelSelector{
+				{MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "rack", Operator: "Unknown", Values: []string{"0"}},
+				}},
+			},
+		},
+		{
+			name: "Exists with values",
+			selectors: []LabelSelector{
+				{MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "rack", Operator: metav1.LabelSelectorOpExists, Values: []string{"0"}},
+				}},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rts := make([]RebootTimes, len(tc.selectors))
+			for i, sel := range tc.selectors {
+				rts[i] = RebootTimes{Name: string(rune('a' + i)), LabelSelector: sel}
+			}
+			err := validateRebootTimes(rts)
+			if tc.valid && err != nil {
+				t.Error("unexpected error:", err)
+			}
+			if !tc.valid && err == nil {
+				t.Error("error is expected")
+			}
+		})
+	}
+
+	err := validateRebootTimes([]RebootTimes{{Name: "a"}, {Name: "a"}})
+	if err == nil || !strings.Contains(err.Error(), "duplicate") {
+		t.Error("duplicate names should be rejected:", err)
+	}
+}
+
+func TestMatchRebootTime(t *testing.T) {
+	config := &Config{
+		RebootTimes: []RebootTimes{
+			{
+				Name: "cs",
+				LabelSelector: LabelSelector{
+					MatchLabels: map[string]string{"role": "cs"},
+					MatchExpressions: []metav1.LabelSelectorRequirement{
+						{Key: "gpu", Operator: metav1.LabelSelectorOpDoesNotExist},
+					},
+				},
+			},
+			{
+				Name: "gpu",
+				LabelSelector: LabelSelector{
+					MatchExpressions: []metav1.LabelSelectorRequirement{
+						{Key: "gpu", Operator: metav1.LabelSelectorOpExists},
+					},
+				},
+			},
+			{
+				Name: "ss",
+				LabelSelector: LabelSelector{
+					MatchExpressions: []metav1.LabelSelectorRequirement{
+						{Key: "role", Operator: metav1.LabelSelectorOpIn, Values: []string{"ss", "ss2"}},
+						{Key: "gpu", Operator: metav1.LabelSelectorOpDoesNotExist},
+					},
+				},
+			},
+		},
+	}
+
+	newNode := func(labels, annotations map[string]string) *corev1.Node {
+		return &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node", Labels: labels, Annotations: annotations}}
+	}
+	testCases := []struct {
+		name     string
+		node     *corev1.Node
+		expected string
+	}{
+		{
+			name:     "matchLabels",
+			node:     newNode(map[string]string{"role": "cs"}, nil),
+			expected: "cs",
+		},
+		{
+			name:     "Exists",
+			node:     newNode(map[string]string{"role": "cs", "gpu": "a100"}, nil),
+			expected: "gpu",
+		},
+		{
+			name:     "In",
+			node:     newNode(map[string]string{"role": "ss2"}, nil),
+			expected: "ss",
+		},
+		{
+			name: "no match",
+			node: newNode(map[string]string{"role": "boot"}, nil),
+		},
+		{
+			name:     "override",
+			node:     newNode(map[string]string{"role": "cs"}, map[string]string{AnnotationRebootTime: "ss"}),
+			expected: "ss",
+		},
+		{
+			name: "override with unknown name",
+			node: newNode(map[string]string{"role": "cs"}, map[string]string{AnnotationRebootTime: "unknown"}),
+		},
+		{
+			name: "opt-out",
+			node: newNode(map[string]string{"role": "cs"}, map[string]string{AnnotationOptOut: "true", AnnotationRebootTime: "ss"}),
+		},
+		{
+			name:     "opt-out is false",
+			node:     newNode(map[string]string{"role": "cs"}, map[string]string{AnnotationOptOut: "false"}),
+			expected: "cs",
+		},
+		{
+			name: "invalid opt-out",
+			node: newNode(map[string]string{"role": "cs"}, map[string]string{AnnotationOptOut: "yes please"}),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rt, err := config.MatchRebootTime(tc.node)
+			if tc.expected == "" {
+				if err == nil {
+					t.Error("error is expected, but matched", rt.Name)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if rt.Name != tc.expected {
+				t.Errorf("expected %s, actual %s", tc.expected, rt.Name)
+			}
+		})
+	}
+
+	// MatchRebootTime reports overlapping selectors of a config that is not validated.
+	config.RebootTimes = append(config.RebootTimes, RebootTimes{Name: "all"})
+	_, err := config.MatchRebootTime(newNode(map[string]string{"role": "cs"}, nil))
+	if err == nil || !strings.Contains(err.Error(), "cs, all") {
+		t.Error("multiple matches should be reported:", err)
+	}
+}
@@ -0,0 +1,158 @@
+package necorebooter
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/cybozu-go/neco"
+)
+
+// simulationStep is the resolution of the simulation.
+// The cron schedules have the resolution of a minute, and the windows open or close only at minute boundaries.
+const simulationStep = time.Minute
+
+// Window is a time range [Start, End).
+type Window struct {
+	Start time.Time
+	End   time.Time
+}
+
+// SimulateWindows returns the windows between from and to in which the reboot is allowed by rt.
+// The windows are evaluated in the same way as the controller does.
+func SimulateWindows(rt RebootTime, from, to time.Time) []Window {
+	windows := []Window{}
+	var current *Window
+	for t := from.Truncate(simulationStep); t.Before(to); t = t.Add(simulationStep) {
+		if !isAllowedAt(rt, t) {
+			current = nil
+			continue
+		}
+		if current == nil {
+			windows = append(windows, Window{Start: t})
+			current = &windows[len(windows)-1]
+		}
+		current.End = t.Add(simulationStep)
+	}
+	return windows
+}
+
+// ScheduleEntry is a projected schedule of a reboot list entry.
+type ScheduleEntry struct {
+	Node       string
+	Group      string
+	RebootTime string
+	// EnqueuedAt is the time when the node is first added to the reboot queue.
+	EnqueuedAt *time.Time
+	// CompletedAt is the time when the reboot of the node is expected to be completed.
+	CompletedAt *time.Time
+}
+
+type simulatedQueueEntry struct {
+	entry      *neco.RebootListEntry
+	finishedAt *time.Time
+}
+
+// SimulateSchedule projects when the entries in the reboot list would be enqueued and rebooted
+// between from and to.  Each reboot is assumed to take rebootDuration, and nodes in the reboot
+// queue are assumed to be rebooted one by one.
+func SimulateSchedule(config *Config, rebootTimes map[string]RebootTime, entries []*neco.RebootListEntry, from, to time.Time, rebootDuration time.Duration) ([]ScheduleEntry, error) {
+	tz, err := time.LoadLocation(config.TimeZone)
+	if err != nil {
+		return nil, err
+	}
+	if rebootDuration <= 0 {
+		return nil, fmt.Errorf("reboot duration must be positive: %s", rebootDuration)
+	}
+
+	schedule := make([]ScheduleEntry, 0, len(entries))
+	scheduleIndex := make(map[string]int)
+	list := make([]*neco.RebootListEntry, 0, len(entries))
+	for _, entry := range entries {
+		if entry.Status == neco.RebootListEntryStatusCancelled {
+			continue
+		}
+		e := *entry
+		e.Status = neco.RebootListEntryStatusPending
+		list = append(list, &e)
+		scheduleIndex[e.Node] = len(schedule)
+		schedule = append(schedule, ScheduleEntry{Node: e.Node, Group: e.Group, RebootTime: e.RebootTime})
+	}
+
+	isRebootable := func(entry *neco.RebootListEntry, t time.Time) bool {
+		rt, ok := rebootTimes[entry.RebootTime]
+		return ok && !entry.IsPostponed(t) && isAllowedAt(rt, t.In(tz))
+	}
+
+	processingGroup := ""
+	queue := []*simulatedQueueEntry{}
+	for t := from.Truncate(simulationStep); t.Before(to) && len(list) != 0; t = t.Add(simulationStep) {
+		// complete the reboot
+		if len(queue) != 0 && queue[0].finishedAt != nil && !queue[0].finishedAt.After(t) {
+			done := queue[0]
+			queue = queue[1:]
+			list = slices.DeleteFunc(list, func(e *neco.RebootListEntry) bool { return e == done.entry })
+			completedAt := *done.finishedAt
+			schedule[scheduleIndex[done.entry.Node]].CompletedAt = &completedAt
+		}
+
+		// move to the next group
+		groupOrder := expectedGroupOrder(config.GroupOrder, list, processingGroup)
+		candidate := []string{}
+		for _, group := range groupOrder {
+			if slices.ContainsFunc(list, func(e *neco.RebootListEntry) bool { return e.Group == group && isRebootable(e, t) }) {
+				candidate = append(candidate, group)
+			}
+		}
+		if len(candidate) != 0 && len(queue) == 0 {
+			priorities := groupPriorities(list)
+			keep := len(candidate) > 1 && candidate[0] == processingGroup && priorities[processingGroup] > priorities[candidate[1]]
+			keep = keep || keepThrottledGroup(config, list, candidate, priorities, processingGroup, func(e *neco.RebootListEntry) bool { return isRebootable(e, t) })
+			if !keep {
+				for _, group := range candidate {
+					if group != processingGroup {
+						processingGroup = group
+						break
+					}
+				}
+			}
+		}
+
+		// enqueue the rebootable entries and dequeue the timed out entries
+		newEntry := []*neco.RebootListEntry{}
+		for _, entry := range list {
+			if entry.Status == neco.RebootListEntryStatusPending && entry.Group == processingGroup && isRebootable(entry, t) {
+				newEntry = append(newEntry, entry)
+			}
+		}
+		slices.SortFunc(newEntry, compareRebootListEntries)
+		queued := make([]*neco.RebootListEntry, len(queue))
+		for i, q := range queue {
+			queued[i] = q.entry
+		}
+		newEntry = limitNewEntries(config, newEntry, queued)
+		for _, entry := range newEntry {
+			entry.Status = neco.RebootListEntryStatusQueued
+			queue = append(queue, &simulatedQueueEntry{entry: entry})
+			s := &schedule[scheduleIndex[entry.Node]]
+			if s.EnqueuedAt == nil {
+				enqueuedAt := t
+				s.EnqueuedAt = &enqueuedAt
+			}
+		}
+		queue = slices.DeleteFunc(queue, func(q *simulatedQueueEntry) bool {
+			if q.finishedAt == nil && !isRebootable(q.entry, t) {
+				q.entry.Status = neco.RebootListEntryStatusPending
+				return true
+			}
+			return false
+		})
+
+		// start the reboot
+		if len(queue) != 0 && queue[0].finishedAt == nil {
+			finishedAt := t.Add(rebootDuration)
+			queue[0].finishedAt = &finishedAt
+		}
+	}
+	return schedule, nil
+}
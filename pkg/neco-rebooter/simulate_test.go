@@ -0,0 +1,108 @@
+package necorebooter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cybozu-go/neco"
+)
+
+func TestSimulateWindows(t *testing.T) {
+	config := &Config{
+		RebootTimes: []RebootTimes{
+			{
+				Name: "test",
+				Times: Times{
+					Deny:  []string{"* 3 * * *"},
+					Allow: []string{"* 0-6 * * 1-5"},
+				},
+			},
+		},
+	}
+	rts, err := config.GetRebootTime()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC) // Monday
+	windows := SimulateWindows(rts["test"], from, from.Add(24*time.Hour))
+	// isWithinSchedule regards the minute before the schedule as within the schedule.
+	expected := []Window{
+		{Start: from, End: from.Add(2*time.Hour + 59*time.Minute)},
+		{Start: from.Add(3*time.Hour + 59*time.Minute), End: from.Add(6*time.Hour + 59*time.Minute)},
+		{Start: from.Add(23*time.Hour + 59*time.Minute), End: from.Add(24 * time.Hour)},
+	}
+	if len(windows) != len(expected) {
+		t.Fatalf("unexpected windows: %v", windows)
+	}
+	for i := range expected {
+		if !windows[i].Start.Equal(expected[i].Start) || !windows[i].End.Equal(expected[i].End) {
+			t.Errorf("window %d: expected %v, actual %v", i, expected[i], windows[i])
+		}
+	}
+}
+
+func TestSimulateSchedule(t *testing.T) {
+	config := &Config{
+		RebootTimes: []RebootTimes{
+			{Name: "all", Times: Times{Allow: []string{"* * * * *"}}},
+			{Name: "night", Times: Times{Allow: []string{"* 0-5 * * *"}}},
+		},
+		TimeZone:   "UTC",
+		GroupOrder: GroupOrder{Strategy: GroupOrderAlphabetical},
+	}
+	rts, err := config.GetRebootTime()
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := []*neco.RebootListEntry{
+		{Index: 0, Node: "node0", Group: "rack0", RebootTime: "all"},
+		{Index: 1, Node: "node1", Group: "rack1", RebootTime: "all"},
+		{Index: 2, Node: "node2", Group: "rack0", RebootTime: "all"},
+		{Index: 3, Node: "node3", Group: "rack1", RebootTime: "night"},
+		{Index: 4, Node: "node4", Group: "rack1", RebootTime: "all", Status: neco.RebootListEntryStatusCancelled},
+	}
+
+	from := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	schedule, err := SimulateSchedule(config, rts, entries, from, from.Add(24*time.Hour), 10*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(schedule) != 4 {
+		t.Fatalf("unexpected schedule: %v", schedule)
+	}
+
+	at := func(d time.Duration) *time.Time {
+		t := from.Add(d)
+		return &t
+	}
+	expected := []struct {
+		enqueued  *time.Time
+		completed *time.Time
+	}{
+		{at(0), at(10 * time.Minute)},
+		{at(20 * time.Minute), at(30 * time.Minute)},
+		{at(0), at(20 * time.Minute)},
+		// node3 is rebootable after 23:59, and it is processed after rack0 becomes empty.
+		{at(11*time.Hour + 59*time.Minute), at(12*time.Hour + 9*time.Minute)},
+	}
+	for i, e := range expected {
+		s := schedule[i]
+		if s.EnqueuedAt == nil || !s.EnqueuedAt.Equal(*e.enqueued) {
+			t.Errorf("%s: expected enqueued at %v, actual %v", s.Node, e.enqueued, s.EnqueuedAt)
+		}
+		if s.CompletedAt == nil || !s.CompletedAt.Equal(*e.completed) {
+			t.Errorf("%s: expected completed at %v, actual %v", s.Node, e.completed, s.CompletedAt)
+		}
+	}
+
+	// node2 waits for node0 when the number of queued nodes is limited.
+	config.MaxQueuedNodes = 1
+	schedule, err = SimulateSchedule(config, rts, entries, from, from.Add(24*time.Hour), 10*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := schedule[2]; s.EnqueuedAt == nil || !s.EnqueuedAt.Equal(*at(10 * time.Minute)) {
+		t.Errorf("%s: unexpected enqueued time %v", s.Node, s.EnqueuedAt)
+	}
+}
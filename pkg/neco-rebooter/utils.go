@@ -1,6 +1,7 @@
 package necorebooter
 
 import (
+	"cmp"
 	"math/rand/v2"
 	"slices"
 	"time"
@@ -13,7 +14,10 @@ import (
 var timeNowFunc = time.Now
 
 func isWithinSchedule(schedule cron.Schedule, timeZone *time.Location) bool {
-	now := timeNowFunc().In(timeZone)
+	return isWithinScheduleAt(schedule, timeNowFunc().In(timeZone))
+}
+
+func isWithinScheduleAt(schedule cron.Schedule, now time.Time) bool {
 	next := schedule.Next(now)
 	// If the next scheduled time is within 1 minutesfrom now, we consider that we are within the schedule.
 	if next.Sub(now) <= time.Second*60 {
@@ -23,6 +27,22 @@ func isWithinSchedule(schedule cron.Schedule, timeZone *time.Location) bool {
 	}
 }
 
+// isAllowedAt returns true if the reboot is allowed at t by rt.
+// Deny rules take precedence over allow rules.
+func isAllowedAt(rt RebootTime, t time.Time) bool {
+	for _, deny := range rt.Deny {
+		if isWithinScheduleAt(deny, t) {
+			return false
+		}
+	}
+	for _, allow := range rt.Allow {
+		if isWithinScheduleAt(allow, t) {
+			return true
+		}
+	}
+	return false
+}
+
 func findRebootQueueEntryFromRebootListEntry(rebootQueueEntries []*cke.RebootQueueEntry, rebootListEntry neco.RebootListEntry) *cke.RebootQueueEntry {
 	for _, entry := range rebootQueueEntries {
 		if entry.Node == rebootListEntry.Node {
@@ -75,3 +95,91 @@ func cycleSlices(slice []string, offset int) []string {
 	}
 	return append(slice[offset:], slice[:offset]...)
 }
+
+// compareRebootListEntries orders entries by priority in descending order,
+// then by deadline, and then by index.  Entries without deadline come after
+// entries with deadline.
+func compareRebootListEntries(a, b *neco.RebootListEntry) int {
+	if c := cmp.Compare(b.Priority, a.Priority); c != 0 {
+		return c
+	}
+	switch {
+	case a.Deadline != nil && b.Deadline != nil:
+		if c := a.Deadline.Compare(*b.Deadline); c != 0 {
+			return c
+		}
+	case a.Deadline != nil:
+		return -1
+	case b.Deadline != nil:
+		return 1
+	}
+	return cmp.Compare(a.Index, b.Index)
+}
+
+// groupPriorities returns the priority of each group, which is the highest
+// priority of the entries in the group.  Cancelled entries are ignored.
+func groupPriorities(rebootListEntries []*neco.RebootListEntry) map[string]int {
+	priorities := make(map[string]int)
+	for _, entry := range rebootListEntries {
+		if entry.Status == neco.RebootListEntryStatusCancelled {
+			continue
+		}
+		p, ok := priorities[entry.Group]
+		if !ok || entry.Priority > p {
+			priorities[entry.Group] = entry.Priority
+		}
+	}
+	return priorities
+}
+
+// sortGroupsByPriority sorts groups by their priorities in descending order.
+// Groups with the same priority keep their order.
+func sortGroupsByPriority(groups []string, priorities map[string]int) {
+	slices.SortStableFunc(groups, func(a, b string) int {
+		return cmp.Compare(priorities[b], priorities[a])
+	})
+}
+
+// limitNewEntries returns the entries in newEntry that can be queued without exceeding
+// the limits of queued nodes in the config.  queued is the entries already queued.
+// newEntry should be sorted in the order of enqueueing.
+func limitNewEntries(config *Config, newEntry, queued []*neco.RebootListEntry) []*neco.RebootListEntry {
+	limits := make(map[string]int)
+	for _, rt := range config.RebootTimes {
+		limits[rt.Name] = rt.MaxQueuedNodes
+	}
+	total := len(queued)
+	counts := make(map[string]int)
+	for _, entry := range queued {
+		counts[entry.RebootTime]++
+	}
+
+	ret := make([]*neco.RebootListEntry, 0, len(newEntry))
+	for _, entry := range newEntry {
+		if config.MaxQueuedNodes > 0 && total >= config.MaxQueuedNodes {
+			break
+		}
+		if limit := limits[entry.RebootTime]; limit > 0 && counts[entry.RebootTime] >= limit {
+			continue
+		}
+		ret = append(ret, entry)
+		total++
+		counts[entry.RebootTime]++
+	}
+	return ret
+}
+
+// keepThrottledGroup returns true if the processing group should be kept because some of its
+// entries are waiting for the limits of queued nodes.  The processing group is not kept if
+// another group has higher priority.
+func keepThrottledGroup(config *Config, entries []*neco.RebootListEntry, candidate []string, priorities map[string]int, processingGroup string, isRebootable func(*neco.RebootListEntry) bool) bool {
+	if !config.hasQueueLimit() || !slices.Contains(candidate, processingGroup) {
+		return false
+	}
+	if slices.ContainsFunc(candidate, func(g string) bool { return priorities[g] > priorities[processingGroup] }) {
+		return false
+	}
+	return slices.ContainsFunc(entries, func(e *neco.RebootListEntry) bool {
+		return e.Group == processingGroup && e.Status == neco.RebootListEntryStatusPending && isRebootable(e)
+	})
+}
@@ -201,3 +201,141 @@ func TestCycleSlices(t *testing.T) {
 		t.Errorf("unexpected result: want=%v, got=%v", expected4, actual)
 	}
 }
+
+func TestCompareRebootListEntries(t *testing.T) {
+	early := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	late := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	entries := []*neco.RebootListEntry{
+		{Index: 0, Node: "node0"},
+		{Index: 1, Node: "node1", Deadline: &late},
+		{Index: 2, Node: "node2", Priority: 10},
+		{Index: 3, Node: "node3", Deadline: &early},
+		{Index: 4, Node: "node4", Priority: -1},
+		{Index: 5, Node: "node5", Priority: 10, Deadline: &late},
+	}
+	slices.SortFunc(entries, compareRebootListEntries)
+
+	expected := []string{"node5", "node2", "node3", "node1", "node0", "node4"}
+	actual := make([]string, len(entries))
+	for i, entry := range entries {
+		actual[i] = entry.Node
+	}
+	if !slices.Equal(expected, actual) {
+		t.Errorf("unexpected result: want=%v, got=%v", expected, actual)
+	}
+}
+
+func TestSortGroupsByPriority(t *testing.T) {
+	rl := []*neco.RebootListEntry{
+		{Group: "group1"},
+		{Group: "group2", Priority: 5},
+		{Group: "group2"},
+		{Group: "group3", Priority: 10, Status: neco.RebootListEntryStatusCancelled},
+		{Group: "group3", Priority: 1},
+		{Group: "group4", Priority: -1},
+	}
+	priorities := groupPriorities(rl)
+	if priorities["group2"] != 5 || priorities["group3"] != 1 || priorities["group4"] != -1 {
+		t.Errorf("unexpected priorities: %v", priorities)
+	}
+
+	groups := []string{"group4", "group1", "group3", "group2"}
+	sortGroupsByPriority(groups, priorities)
+	expected := []string{"group2", "group3", "group1", "group4"}
+	if !slices.Equal(expected, groups) {
+		t.Errorf("unexpected result: want=%v, got=%v", expected, groups)
+	}
+}
+
+func TestLimitNewEntries(t *testing.T) {
+	nodes := func(entries []*neco.RebootListEntry) []string {
+		ret := []string{}
+		for _, e := range entries {
+			ret = append(ret, e.Node)
+		}
+		return ret
+	}
+	newEntry := []*neco.RebootListEntry{
+		{Node: "ss1", RebootTime: "ss"},
+		{Node: "cs1", RebootTime: "cs"},
+		{Node: "ss2", RebootTime: "ss"},
+		{Node: "cs2", RebootTime: "cs"},
+		{Node: "cs3", RebootTime: "cs"},
+	}
+
+	testCases := []struct {
+		name     string
+		config   Config
+		queued   []*neco.RebootListEntry
+		expected []string
+	}{
+		{
+			name:     "unlimited",
+			config:   Config{},
+			expected: []string{"ss1", "cs1", "ss2", "cs2", "cs3"},
+		},
+		{
+			name:     "global",
+			config:   Config{MaxQueuedNodes: 3},
+			queued:   []*neco.RebootListEntry{{Node: "cs0", RebootTime: "cs"}},
+			expected: []string{"ss1", "cs1"},
+		},
+		{
+			name: "per-reboot-time",
+			config: Config{
+				RebootTimes: []RebootTimes{{Name: "ss", MaxQueuedNodes: 1}},
+			},
+			expected: []string{"ss1", "cs1", "cs2", "cs3"},
+		},
+		{
+			name: "per-reboot-time-queued",
+			config: Config{
+				RebootTimes: []RebootTimes{{Name: "ss", MaxQueuedNodes: 1}, {Name: "cs", MaxQueuedNodes: 2}},
+			},
+			queued:   []*neco.RebootListEntry{{Node: "ss0", RebootTime: "ss"}},
+			expected: []string{"cs1", "cs2"},
+		},
+		{
+			name: "both",
+			config: Config{
+				MaxQueuedNodes: 2,
+				RebootTimes:    []RebootTimes{{Name: "ss", MaxQueuedNodes: 1}},
+			},
+			expected: []string{"ss1", "cs1"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual := nodes(limitNewEntries(&tc.config, newEntry, tc.queued))
+			if !slices.Equal(tc.expected, actual) {
+				t.Errorf("unexpected result: want=%v, got=%v", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestKeepThrottledGroup(t *testing.T) {
+	rl := []*neco.RebootListEntry{
+		{Node: "node0", Group: "group0", Status: neco.RebootListEntryStatusPending},
+		{Node: "node1", Group: "group1", Status: neco.RebootListEntryStatusPending},
+	}
+	rebootable := func(*neco.RebootListEntry) bool { return true }
+	limited := &Config{MaxQueuedNodes: 1}
+	candidate := []string{"group0", "group1"}
+
+	if keepThrottledGroup(&Config{}, rl, candidate, map[string]int{}, "group0", rebootable) {
+		t.Error("group should not be kept without limits")
+	}
+	if !keepThrottledGroup(limited, rl, candidate, map[string]int{}, "group0", rebootable) {
+		t.Error("group should be kept while it has waiting entries")
+	}
+	if keepThrottledGroup(limited, rl, candidate, map[string]int{"group1": 1}, "group0", rebootable) {
+		t.Error("group should not be kept if another group has higher priority")
+	}
+	if keepThrottledGroup(limited, rl, candidate, map[string]int{}, "group0", func(*neco.RebootListEntry) bool { return false }) {
+		t.Error("group should not be kept if it has no rebootable entries")
+	}
+	if keepThrottledGroup(limited, rl, []string{"group1"}, map[string]int{}, "group0", rebootable) {
+		t.Error("group should not be kept if it is not a candidate")
+	}
+}
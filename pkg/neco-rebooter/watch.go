@@ -0,0 +1,135 @@
+package necorebooter
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/cybozu-go/neco"
+	"github.com/cybozu-go/neco/storage"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// healthGateRetryInterval is the interval to check the health gate again
+// while waiting for the rebooted nodes to become healthy.
+const healthGateRetryInterval = time.Minute
+
+// notify requests the controller to process the reboot list.
+// Requests made while the controller is processing are coalesced into one.
+func (c *Controller) notify() {
+	select {
+	case c.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// startWatch starts watching the keys under prefix, and returns after the watch is created.
+// Since the watch starts from the revision at the creation, the changes made after
+// startWatch returns are never missed.
+func startWatch(ctx context.Context, client *clientv3.Client, prefix string) (clientv3.WatchChan, error) {
+	ch := client.Watch(ctx, prefix, clientv3.WithPrefix(), clientv3.WithCreatedNotify())
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case resp, ok := <-ch:
+		if !ok {
+			return nil, errors.New("watch is closed")
+		}
+		if resp.Err() != nil {
+			return nil, resp.Err()
+		}
+		if !resp.Created {
+			return nil, errors.New("watch is not created")
+		}
+	}
+	return ch, nil
+}
+
+// watchRebootList watches the keys of neco-rebooter except for the history.
+// ch should be started by startWatch for storage.KeyNecoRebooterPrefix.
+func (c *Controller) watchRebootList(ctx context.Context, ch clientv3.WatchChan) error {
+	return c.notifyOnChange(ctx, ch, func(key string) bool {
+		return !strings.HasPrefix(key, storage.KeyNecoRebooterHistoryPrefix)
+	})
+}
+
+// watchRebootQueue watches the reboot queue of CKE.
+// ch should be started by startWatch for cke.KeyRebootsPrefix.
+func (c *Controller) watchRebootQueue(ctx context.Context, ch clientv3.WatchChan) error {
+	return c.notifyOnChange(ctx, ch, func(string) bool { return true })
+}
+
+func (c *Controller) notifyOnChange(ctx context.Context, ch clientv3.WatchChan, filter func(key string) bool) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case resp, ok := <-ch:
+			if !ok {
+				return errors.New("watch is closed")
+			}
+			if resp.Err() != nil {
+				return resp.Err()
+			}
+			for _, ev := range resp.Events {
+				if filter(string(ev.Kv.Key)) {
+					c.notify()
+					break
+				}
+			}
+		}
+	}
+}
+
+// nextWakeUp returns the next time to process the reboot list without any changes in etcd.
+// That is when a reboot window of the entries opens or closes, a postponed entry becomes
+// rebootable, or the health gate is checked again.  The result is not later than the
+// resync interval from now.
+func (c *Controller) nextWakeUp(now time.Time, rebootListEntries []*neco.RebootListEntry) time.Time {
+	next := now.Add(c.config.ResyncInterval.Duration)
+	if len(c.healthCheckers) != 0 && len(c.rebootedNodes) != 0 {
+		next = minTime(next, now.Add(healthGateRetryInterval))
+	}
+
+	rebootTimes := make(map[string]struct{})
+	for _, entry := range rebootListEntries {
+		if entry.Status == neco.RebootListEntryStatusCancelled {
+			continue
+		}
+		rebootTimes[entry.RebootTime] = struct{}{}
+		if entry.IsPostponed(now) {
+			next = minTime(next, *entry.PostponedUntil)
+		}
+	}
+	for name := range rebootTimes {
+		rt, ok := c.rebootTimes[name]
+		if !ok {
+			continue
+		}
+		if t, ok := nextWindowBoundary(rt, now.In(c.timeZone), next); ok {
+			next = minTime(next, t)
+		}
+	}
+	return next
+}
+
+// nextWindowBoundary returns the first time after now and not after limit
+// at which the reboot window of rt opens or closes.
+// The schedules are evaluated with the resolution of a minute as the controller does.
+func nextWindowBoundary(rt RebootTime, now, limit time.Time) (time.Time, bool) {
+	allowed := isAllowedAt(rt, now)
+	for t := now.Truncate(simulationStep).Add(simulationStep); !t.After(limit); t = t.Add(simulationStep) {
+		if isAllowedAt(rt, t) != allowed {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func minTime(a, b time.Time) time.Time {
+	if b.Before(a) {
+		return b
+	}
+	return a
+}
@@ -0,0 +1,188 @@
+package necorebooter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cybozu-go/cke"
+	"github.com/cybozu-go/neco"
+	"github.com/cybozu-go/neco/storage"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestNextWindowBoundary(t *testing.T) {
+	config := &Config{
+		RebootTimes: []RebootTimes{
+			{
+				Name: "test",
+				Times: Times{
+					Deny:  []string{"* 3 * * *"},
+					Allow: []string{"* 0-6 * * *"},
+				},
+			},
+		},
+	}
+	rts, err := config.GetRebootTime()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rt := rts["test"]
+
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		name     string
+		now      time.Time
+		limit    time.Time
+		expected time.Time
+	}{
+		{
+			name:     "closing by deny",
+			now:      base.Add(time.Hour + 30*time.Second),
+			limit:    base.Add(24 * time.Hour),
+			expected: base.Add(2*time.Hour + 59*time.Minute),
+		},
+		{
+			name:     "opening after deny",
+			now:      base.Add(3 * time.Hour),
+			limit:    base.Add(24 * time.Hour),
+			expected: base.Add(3*time.Hour + 59*time.Minute),
+		},
+		{
+			name:     "opening",
+			now:      base.Add(12 * time.Hour),
+			limit:    base.Add(24 * time.Hour),
+			expected: base.Add(23*time.Hour + 59*time.Minute),
+		},
+		{
+			name:  "beyond limit",
+			now:   base.Add(12 * time.Hour),
+			limit: base.Add(13 * time.Hour),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			next, ok := nextWindowBoundary(rt, tc.now, tc.limit)
+			if tc.expected.IsZero() {
+				if ok {
+					t.Error("unexpected boundary", next)
+				}
+				return
+			}
+			if !ok || !next.Equal(tc.expected) {
+				t.Errorf("expected %v, actual %v", tc.expected, next)
+			}
+			if isAllowedAt(rt, next) == isAllowedAt(rt, tc.now) {
+				t.Error("window does not change at", next)
+			}
+		})
+	}
+}
+
+func TestNextWakeUp(t *testing.T) {
+	config := &Config{
+		RebootTimes: []RebootTimes{
+			{Name: "all", Times: Times{Allow: []string{"* * * * *"}}},
+			{Name: "night", Times: Times{Allow: []string{"* 0-5 * * *"}}},
+		},
+		ResyncInterval: metav1.Duration{Duration: 10 * time.Minute},
+	}
+	rts, err := config.GetRebootTime()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &Controller{config: *config, rebootTimes: rts, timeZone: time.UTC}
+
+	now := time.Date(2024, 1, 1, 5, 50, 0, 0, time.UTC)
+	if next := c.nextWakeUp(now, nil); !next.Equal(now.Add(10 * time.Minute)) {
+		t.Error("resync interval is not honored", next)
+	}
+
+	entries := []*neco.RebootListEntry{{Node: "node1", RebootTime: "all"}}
+	if next := c.nextWakeUp(now, entries); !next.Equal(now.Add(10 * time.Minute)) {
+		t.Error("unexpected wake up time for the always open window", next)
+	}
+
+	// the window of night closes at 5:59.
+	entries = append(entries, &neco.RebootListEntry{Node: "node2", RebootTime: "night"})
+	if next := c.nextWakeUp(now, entries); !next.Equal(now.Add(9 * time.Minute)) {
+		t.Error("window boundary is not honored", next)
+	}
+
+	// windows of cancelled entries are ignored.
+	entries[1].Status = neco.RebootListEntryStatusCancelled
+	if next := c.nextWakeUp(now, entries); !next.Equal(now.Add(10 * time.Minute)) {
+		t.Error("window of cancelled entry is not ignored", next)
+	}
+
+	until := now.Add(5 * time.Minute)
+	entries[0].PostponedUntil = &until
+	if next := c.nextWakeUp(now, entries); !next.Equal(until) {
+		t.Error("postponement is not honored", next)
+	}
+
+	c.healthCheckers = []HealthChecker{&fakeHealthChecker{}}
+	c.rebootedNodes = map[string]struct{}{"node0": {}}
+	if next := c.nextWakeUp(now, entries); !next.Equal(now.Add(healthGateRetryInterval)) {
+		t.Error("health gate retry is not honored", next)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	c, err := newTestController()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		err := cleanupEtcd()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}()
+	c.trigger = make(chan struct{}, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	listCh, err := startWatch(ctx, &c.etcdClient, storage.KeyNecoRebooterPrefix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	queueCh, err := startWatch(ctx, c.ckeStorage.Client, cke.KeyRebootsPrefix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go c.watchRebootList(ctx, listCh)
+	go c.watchRebootQueue(ctx, queueCh)
+
+	expectTrigger := func(expected bool) {
+		t.Helper()
+		select {
+		case <-c.trigger:
+			if !expected {
+				t.Error("unexpected trigger")
+			}
+		case <-time.After(time.Second):
+			if expected {
+				t.Error("trigger is not notified")
+			}
+		}
+	}
+
+	err = c.necoStorage.RegisterRebootListEntry(ctx, &neco.RebootListEntry{Node: "node1", Group: "group1", RebootTime: "test", Status: neco.RebootListEntryStatusPending})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectTrigger(true)
+
+	err = c.ckeStorage.RegisterRebootsEntry(ctx, cke.NewRebootQueueEntry("node1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectTrigger(true)
+
+	err = c.necoStorage.RecordRebootListEvents(ctx, neco.RebootListEvent{Time: time.Now(), Type: neco.RebootListEventAdded, Node: "node1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectTrigger(false)
+}
@@ -18,6 +18,8 @@ import (
 	"github.com/cybozu-go/well"
 )
 
+var flagMetricsAddr = flag.String("metrics-addr", ":10083", "listen address of the metrics server")
+
 func main() {
 	flag.Parse()
 	well.LogConfig{}.Apply()
@@ -59,6 +61,21 @@ func main() {
 		if err != nil {
 			return err
 		}
+		collector, err := worker.NewCollector(ctx, st, op, mylrn)
+		if err != nil {
+			return err
+		}
+		well.Go(func(ctx context.Context) error {
+			// Updates should not be blocked by the metrics server.
+			err := neco.RunMetricsServer(ctx, *flagMetricsAddr, worker.GetMetricsHandler(collector))
+			if err != nil {
+				log.Error("metrics server failed", map[string]interface{}{
+					log.FnError: err,
+				})
+			}
+			return nil
+		})
+
 		w := worker.NewWorker(ec, op, version, mylrn)
 		return w.Run(ctx)
 	})
@@ -34,6 +34,17 @@ Possible keys are:
     lb-address-block-internet-cn - LoadBalancer address block for internet-cn.
 	release-time				 - Time range of neco-updater checking latest neco release.
 	release-timezone			 - Timezone of release-time.
+    canary-servers               - LRNs of boot servers to be updated first.
+    canary-soak-period           - Duration to watch canary boot servers before rolling out the update.
+    canary-probe-url             - URL to probe canary boot servers during the soak.
+    auto-rollback                - "true" to roll back a failed update to the last completed release.
+    history-retention            - Retention period of update histories.
+    deny-windows                 - Periods during which updates are not started.
+    notification.slack           - Slack WebHook URL.  Same as "slack".
+    notification.webhook         - URL of a generic webhook to post notifications as JSON.
+    notification.webhook-template - Go template to generate the JSON payload for notification.webhook.
+    notification.teams           - Microsoft Teams incoming webhook URL.
+    notification.alertmanager    - Alertmanager URL to push an alert when an update fails.
 	`,
 
 	Args: cobra.ExactArgs(1),
@@ -48,6 +59,17 @@ Possible keys are:
 		"external-ip-address-block",
 		"release-time",
 		"release-timezone",
+		"canary-servers",
+		"canary-soak-period",
+		"canary-probe-url",
+		"auto-rollback",
+		"history-retention",
+		"deny-windows",
+		"notification.slack",
+		"notification.webhook",
+		"notification.webhook-template",
+		"notification.teams",
+		"notification.alertmanager",
 	},
 	Run: func(cmd *cobra.Command, args []string) {
 		etcd, err := neco.EtcdClient()
@@ -65,7 +87,7 @@ Possible keys are:
 					return err
 				}
 				fmt.Println(env)
-			case "slack":
+			case "slack", "notification.slack":
 				slack, err := st.GetSlackNotification(ctx)
 				if err != nil {
 					return err
@@ -143,6 +165,74 @@ Possible keys are:
 					return err
 				}
 				fmt.Println(tz)
+			case "canary-servers":
+				lrns, err := st.GetCanaryServers(ctx)
+				if err != nil {
+					return err
+				}
+				if lrns == nil {
+					return storage.ErrNotFound
+				}
+				fmt.Println(lrns)
+			case "canary-soak-period":
+				period, err := st.GetCanarySoakPeriod(ctx)
+				if err != nil {
+					return err
+				}
+				fmt.Println(period.String())
+			case "canary-probe-url":
+				u, err := st.GetCanaryProbeURL(ctx)
+				if err != nil {
+					return err
+				}
+				fmt.Println(u)
+			case "auto-rollback":
+				enabled, err := st.GetAutoRollback(ctx)
+				if err != nil {
+					return err
+				}
+				fmt.Println(enabled)
+			case "history-retention":
+				retention, err := st.GetHistoryRetention(ctx)
+				if err != nil {
+					return err
+				}
+				fmt.Println(retention.String())
+			case "deny-windows":
+				windows, err := st.GetDenyWindows(ctx)
+				if err != nil {
+					return err
+				}
+				if windows == nil {
+					return storage.ErrNotFound
+				}
+				for _, w := range windows {
+					fmt.Println(w)
+				}
+			case "notification.webhook":
+				webhook, err := st.GetWebhookNotification(ctx)
+				if err != nil {
+					return err
+				}
+				fmt.Println(webhook)
+			case "notification.webhook-template":
+				tmpl, err := st.GetWebhookTemplate(ctx)
+				if err != nil {
+					return err
+				}
+				fmt.Println(tmpl)
+			case "notification.teams":
+				teams, err := st.GetTeamsNotification(ctx)
+				if err != nil {
+					return err
+				}
+				fmt.Println(teams)
+			case "notification.alertmanager":
+				am, err := st.GetAlertmanagerNotification(ctx)
+				if err != nil {
+					return err
+				}
+				fmt.Println(am)
 			default:
 				return errors.New("unknown key: " + key)
 			}
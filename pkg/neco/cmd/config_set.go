@@ -6,11 +6,14 @@ import (
 	"fmt"
 	"net"
 	"net/url"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/cybozu-go/log"
 	"github.com/cybozu-go/neco"
+	"github.com/cybozu-go/neco/ext"
 	"github.com/cybozu-go/neco/storage"
 	"github.com/cybozu-go/well"
 	"github.com/robfig/cron"
@@ -38,6 +41,21 @@ Possible keys are:
 	lb-address-block-internet-cn - LoadBalancer address block for internet-cn.
 	release-time				 - Time range of neco-updater checking latest neco release.
 	release-timezone			 - Timezone of release-time.
+    canary-servers               - LRNs of boot servers to be updated first.  Clear the value if no LRN is given.
+    canary-soak-period           - Duration to watch canary boot servers before rolling out the update.
+    canary-probe-url             - URL to probe canary boot servers during the soak.  "{ip}" is replaced with their IP address.
+                                   Clear the value if no URL is given.
+    auto-rollback                - "true" to roll back a failed update to the last completed release.
+    history-retention            - Retention period of update histories.
+    deny-windows                 - Periods during which updates are not started.  Clear the value if no window is given.
+                                   Each window is "START/END [DESCRIPTION]" in release-timezone.
+                                   START and END are "YYYY-MM-DD", "YYYY-MM-DDThh:mm", or "MM-DD" for every year.
+    notification.slack           - Slack WebHook URL.  Same as "slack".
+    notification.webhook         - URL of a generic webhook to post notifications as JSON.
+    notification.webhook-template - Go template to generate the JSON payload for notification.webhook.
+    notification.teams           - Microsoft Teams incoming webhook URL.
+    notification.alertmanager    - Alertmanager URL to push an alert when an update fails.
+                                   Notification keys are cleared if no value is given.
 	`,
 
 	Args: func(cmd *cobra.Command, args []string) error {
@@ -45,7 +63,7 @@ Possible keys are:
 			return fmt.Errorf("accepts %d arg(s), received %d", 1, len(args))
 		}
 		switch args[0] {
-		case "env", "slack", "proxy", "check-update-interval", "worker-timeout", "node-proxy", "external-ip-address-block":
+		case "env", "slack", "proxy", "check-update-interval", "worker-timeout", "node-proxy", "external-ip-address-block", "canary-soak-period", "auto-rollback", "history-retention":
 			if len(args) != 2 {
 				return fmt.Errorf("accepts %d arg(s), received %d", 2, len(args))
 			}
@@ -63,6 +81,17 @@ Possible keys are:
 		"external-ip-address-block",
 		"release-time",
 		"release-timezone",
+		"canary-servers",
+		"canary-soak-period",
+		"canary-probe-url",
+		"auto-rollback",
+		"history-retention",
+		"deny-windows",
+		"notification.slack",
+		"notification.webhook",
+		"notification.webhook-template",
+		"notification.teams",
+		"notification.alertmanager",
 	},
 	Run: func(cmd *cobra.Command, args []string) {
 		etcd, err := neco.EtcdClient()
@@ -195,6 +224,88 @@ Possible keys are:
 					return err
 				}
 				return st.PutReleaseTimeZone(ctx, value)
+			case "canary-servers":
+				if len(args) == 1 {
+					return st.DeleteCanaryServers(ctx)
+				}
+				lrns := make([]int, 0, len(args)-1)
+				for _, value := range args[1:] {
+					lrn, err := strconv.Atoi(value)
+					if err != nil {
+						return err
+					}
+					if lrn < 0 {
+						return errors.New("invalid LRN: " + value)
+					}
+					lrns = append(lrns, lrn)
+				}
+				sort.Ints(lrns)
+				return st.PutCanaryServers(ctx, lrns)
+			case "canary-soak-period":
+				value = args[1]
+				duration, err := time.ParseDuration(value)
+				if err != nil {
+					return err
+				}
+				return st.PutCanarySoakPeriod(ctx, duration)
+			case "canary-probe-url":
+				if len(args) == 1 {
+					return st.DeleteCanaryProbeURL(ctx)
+				}
+				value = args[1]
+				u, err := url.Parse(strings.ReplaceAll(value, "{ip}", neco.BootNode0IP(0).String()))
+				if err != nil {
+					return err
+				}
+				if !u.IsAbs() {
+					return errors.New("invalid URL")
+				}
+				return st.PutCanaryProbeURL(ctx, value)
+			case "auto-rollback":
+				value = args[1]
+				enabled, err := strconv.ParseBool(value)
+				if err != nil {
+					return err
+				}
+				return st.PutAutoRollback(ctx, enabled)
+			case "history-retention":
+				value = args[1]
+				duration, err := time.ParseDuration(value)
+				if err != nil {
+					return err
+				}
+				return st.PutHistoryRetention(ctx, duration)
+			case "deny-windows":
+				if len(args) == 1 {
+					return st.DeleteDenyWindows(ctx)
+				}
+				loc, err := st.GetReleaseTimeLocation(ctx)
+				if err != nil {
+					return err
+				}
+				_, err = neco.ParseDenyWindows(args[1:], loc)
+				if err != nil {
+					return err
+				}
+				return st.PutDenyWindows(ctx, args[1:])
+			case "notification.slack":
+				return putNotificationURL(ctx, args, st.PutSlackNotification, st.DeleteSlackNotification)
+			case "notification.webhook":
+				return putNotificationURL(ctx, args, st.PutWebhookNotification, st.DeleteWebhookNotification)
+			case "notification.webhook-template":
+				if len(args) == 1 {
+					return st.DeleteWebhookTemplate(ctx)
+				}
+				value = args[1]
+				_, err := ext.ParseWebhookTemplate(value)
+				if err != nil {
+					return err
+				}
+				return st.PutWebhookTemplate(ctx, value)
+			case "notification.teams":
+				return putNotificationURL(ctx, args, st.PutTeamsNotification, st.DeleteTeamsNotification)
+			case "notification.alertmanager":
+				return putNotificationURL(ctx, args, st.PutAlertmanagerNotification, st.DeleteAlertmanagerNotification)
 			}
 			return errors.New("unknown key: " + key)
 		})
@@ -206,6 +317,22 @@ Possible keys are:
 	},
 }
 
+// putNotificationURL stores the URL of a notification backend.
+// If no URL is given, this deletes the URL to disable the backend.
+func putNotificationURL(ctx context.Context, args []string, put func(context.Context, string) error, del func(context.Context) error) error {
+	if len(args) == 1 {
+		return del(ctx)
+	}
+	u, err := url.Parse(args[1])
+	if err != nil {
+		return err
+	}
+	if !u.IsAbs() {
+		return errors.New("invalid URL")
+	}
+	return put(ctx, args[1])
+}
+
 func init() {
 	configCmd.AddCommand(configSetCmd)
 }
@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/cybozu-go/neco"
+	"github.com/spf13/cobra"
+)
+
+// historyCmd is the root subcommand of "neco history".
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "update history related commands",
+	Long:  `Show the history of update processes recorded by neco-updater.`,
+	Args:  cobra.ExactArgs(0),
+	Run:   historyListCmd.Run,
+}
+
+func historyDuration(h *neco.UpdateHistory) string {
+	if h.EndedAt == nil {
+		return "-"
+	}
+	return h.EndedAt.Sub(h.StartedAt).Round(time.Second).String()
+}
+
+func showHistories(w io.Writer, hs []*neco.UpdateHistory) error {
+	tw := tabwriter.NewWriter(w, 0, 1, 2, ' ', 0)
+	fmt.Fprintln(tw, "STARTED\tVERSION\tWAVE\tSERVERS\tDURATION\tRESULT")
+	for _, h := range hs {
+		wave := h.Wave.String()
+		if h.RollbackFrom != "" {
+			wave = "rollback"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%v\t%s\t%s\n",
+			h.StartedAt.Format(time.RFC3339), h.Version, wave, h.Servers, historyDuration(h), h.Cond.String())
+	}
+	return tw.Flush()
+}
+
+func showHistory(w io.Writer, h *neco.UpdateHistory) error {
+	fmt.Fprintln(w, "   version:", h.Version)
+	fmt.Fprintln(w, "   members:", h.Servers)
+	if h.Wave != neco.WaveAll {
+		fmt.Fprintln(w, "      wave:", h.Wave.String())
+	}
+	if h.RollbackFrom != "" {
+		fmt.Fprintln(w, "  rollback: from", h.RollbackFrom)
+	}
+	if h.ResumeStep > 0 {
+		fmt.Fprintln(w, "   resumed: from step", h.ResumeStep)
+	}
+	fmt.Fprintln(w, "   started:", h.StartedAt.Format(time.RFC3339))
+	if h.EndedAt != nil {
+		fmt.Fprintln(w, "     ended:", h.EndedAt.Format(time.RFC3339))
+	}
+	fmt.Fprintln(w, "    result:", h.Cond.String())
+	if len(h.Message) > 0 {
+		fmt.Fprintln(w, "   message:", h.Message)
+	}
+
+	lrns := make([]int, 0, len(h.Steps))
+	for lrn := range h.Steps {
+		lrns = append(lrns, lrn)
+	}
+	sort.Ints(lrns)
+
+	for _, lrn := range lrns {
+		fmt.Fprintf(w, "\nBoot server %d\n", lrn)
+		tw := tabwriter.NewWriter(w, 0, 1, 2, ' ', 0)
+		fmt.Fprintln(tw, "    STEP\tNAME\tSTARTED\tDURATION\tRESULT\tMESSAGE")
+		for _, st := range h.Steps[lrn] {
+			name := st.Name
+			if name == "" {
+				name = "-"
+			}
+			fmt.Fprintf(tw, "    %d\t%s\t%s\t%s\t%s\t%s\n",
+				st.Step, name, st.StartedAt.Format(time.RFC3339), st.EndedAt.Sub(st.StartedAt).Round(time.Millisecond).String(), st.Cond.String(), st.Message)
+		}
+		err := tw.Flush()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(historyCmd)
+}
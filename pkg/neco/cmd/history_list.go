@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"context"
+	"os"
+
+	"github.com/cybozu-go/log"
+	"github.com/cybozu-go/neco"
+	"github.com/cybozu-go/neco/storage"
+	"github.com/cybozu-go/well"
+	"github.com/spf13/cobra"
+)
+
+var historyListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "list the update histories",
+	Long:  `List the update histories in the order of the start time.`,
+	Args:  cobra.ExactArgs(0),
+	Run: func(cmd *cobra.Command, args []string) {
+		etcd, err := neco.EtcdClient()
+		if err != nil {
+			log.ErrorExit(err)
+		}
+		defer etcd.Close()
+		st := storage.NewStorage(etcd)
+		well.Go(func(ctx context.Context) error {
+			hs, err := st.ListHistories(ctx)
+			if err != nil {
+				return err
+			}
+			return showHistories(os.Stdout, hs)
+		})
+		well.Stop()
+		err = well.Wait()
+		if err != nil {
+			log.ErrorExit(err)
+		}
+	},
+}
+
+func init() {
+	historyCmd.AddCommand(historyListCmd)
+}
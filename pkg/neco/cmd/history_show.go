@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/cybozu-go/log"
+	"github.com/cybozu-go/neco"
+	"github.com/cybozu-go/neco/storage"
+	"github.com/cybozu-go/well"
+	"github.com/spf13/cobra"
+)
+
+var historyShowCmd = &cobra.Command{
+	Use:   "show VERSION",
+	Short: "show the update histories of a version",
+	Long: `Show the update histories of VERSION with the step timings of each boot server.
+
+If the version has been updated more than once, all of them are shown.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		etcd, err := neco.EtcdClient()
+		if err != nil {
+			log.ErrorExit(err)
+		}
+		defer etcd.Close()
+		st := storage.NewStorage(etcd)
+		version := args[0]
+		well.Go(func(ctx context.Context) error {
+			hs, err := st.ListHistories(ctx)
+			if err != nil {
+				return err
+			}
+
+			found := false
+			for _, h := range hs {
+				if h.Version != version {
+					continue
+				}
+				h, err = st.GetHistory(ctx, h.ID)
+				if err != nil {
+					return err
+				}
+				if found {
+					fmt.Println()
+				}
+				found = true
+				fmt.Printf("Update %s\n", h.ID)
+				err = showHistory(os.Stdout, h)
+				if err != nil {
+					return err
+				}
+			}
+			if !found {
+				return errors.New("no history for version " + version)
+			}
+			return nil
+		})
+		well.Stop()
+		err = well.Wait()
+		if err != nil {
+			log.ErrorExit(err)
+		}
+	},
+}
+
+func init() {
+	historyCmd.AddCommand(historyShowCmd)
+}
@@ -1,10 +1,11 @@
 package cmd
 
 import (
-	"fmt"
+	"context"
 	"log/slog"
 	"os"
 	"os/exec"
+	osuser "os/user"
 	"path/filepath"
 
 	"github.com/cybozu-go/cke"
@@ -12,7 +13,7 @@ import (
 	necorebooter "github.com/cybozu-go/neco/pkg/neco-rebooter"
 	"github.com/cybozu-go/neco/storage"
 	"github.com/spf13/cobra"
-	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/client-go/util/homedir"
@@ -108,17 +109,68 @@ func loadKubeConfig() error {
 	return nil
 }
 
-func matchRebootTimes(node v1.Node, rebootTimes []necorebooter.RebootTimes) (*necorebooter.RebootTimes, error) {
-REBOOT_TIMES_LOOP:
-	for _, rt := range rebootTimes {
-		for key, value := range rt.LabelSelector.MatchLabels {
-			if node.ObjectMeta.Labels[key] != value {
-				continue REBOOT_TIMES_LOOP
-			}
+// rebooterActor returns the name of the user who runs the command for the audit events.
+func rebooterActor() string {
+	if u := os.Getenv("SUDO_USER"); u != "" {
+		return u
+	}
+	u, err := osuser.Current()
+	if err != nil {
+		return "unknown"
+	}
+	return u.Username
+}
+
+// recordRebootListEvents records the audit events of the entries operated by the user.
+func recordRebootListEvents(ctx context.Context, entries []*neco.RebootListEntry, eventType, reason string) error {
+	actor := rebooterActor()
+	events := make([]neco.RebootListEvent, len(entries))
+	for i, entry := range entries {
+		events[i] = neco.NewRebootListEvent(entry, eventType, actor, reason)
+	}
+	return necoStorage.RecordRebootListEvents(ctx, events...)
+}
+
+type rebooterSelectorOpts struct {
+	group         string
+	node          string
+	rebootTime    string
+	labelSelector string
+}
+
+func (o *rebooterSelectorOpts) addFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&o.group, "group", "", "select entries in the group")
+	cmd.Flags().StringVar(&o.node, "node", "", "select entries whose node name matches the glob pattern")
+	cmd.Flags().StringVar(&o.rebootTime, "reboot-time", "", "select entries with the RebootTime")
+	cmd.Flags().StringVarP(&o.labelSelector, "selector", "l", "", "select entries whose Kubernetes node matches the label selector")
+}
+
+func (o *rebooterSelectorOpts) isEmpty() bool {
+	return o.group == "" && o.node == "" && o.rebootTime == "" && o.labelSelector == ""
+}
+
+// selector builds the selector of reboot list entries.
+// The label selector is resolved to the names of the matching Kubernetes nodes.
+func (o *rebooterSelectorOpts) selector(ctx context.Context) (*neco.RebootListEntrySelector, error) {
+	sel := &neco.RebootListEntrySelector{
+		Group:       o.group,
+		NodePattern: o.node,
+		RebootTime:  o.rebootTime,
+	}
+	if o.labelSelector != "" {
+		nodes, err := KubeClient.CoreV1().Nodes().List(ctx, metav1.ListOptions{LabelSelector: o.labelSelector})
+		if err != nil {
+			return nil, err
 		}
-		return &rt, nil
+		sel.Nodes = make([]string, 0, len(nodes.Items))
+		for _, n := range nodes.Items {
+			sel.Nodes = append(sel.Nodes, n.Name)
+		}
+	}
+	if err := sel.Validate(); err != nil {
+		return nil, err
 	}
-	return nil, fmt.Errorf("node: %s does not match any reboot time", node.Name)
+	return sel, nil
 }
 
 func init() {
@@ -8,6 +8,7 @@ import (
 	"os/signal"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/cybozu-go/neco"
 	"github.com/spf13/cobra"
@@ -15,14 +16,33 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+var rebooterAddOpts struct {
+	priority int
+	deadline string
+	reason   string
+}
+
 var rebooterAddCmd = &cobra.Command{
 	Use:   "add FILE",
 	Short: "append the nodes written in FILE to the reboot list",
-	Long:  `Append the nodes written in FILE to the reboot list.`,
-	Args:  cobra.ExactArgs(1),
+	Long: `Append the nodes written in FILE to the reboot list.
+
+Entries with higher --priority are processed first.
+--deadline is the time by which the nodes must be rebooted, given in
+RFC3339 format or as a duration from now such as "72h".`,
+	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 		defer stop()
+
+		var deadline *time.Time
+		if rebooterAddOpts.deadline != "" {
+			d, err := parseFutureTime(rebooterAddOpts.deadline, time.Now())
+			if err != nil {
+				return fmt.Errorf("invalid --deadline: %w", err)
+			}
+			deadline = &d
+		}
 		f := os.Stdin
 		if args[0] != "-" {
 			var err error
@@ -67,7 +87,7 @@ var rebooterAddCmd = &cobra.Command{
 			if !ok {
 				return fmt.Errorf("node has no groupKey label (%s)", config.GroupLabelKey)
 			}
-			rt, err := matchRebootTimes(*kubeNode, config.RebootTimes)
+			rt, err := config.MatchRebootTime(kubeNode)
 			if err != nil {
 				fmt.Println(err)
 				continue
@@ -78,12 +98,18 @@ var rebooterAddCmd = &cobra.Command{
 				Group:      group,
 				RebootTime: rt.Name,
 				Status:     neco.RebootListEntryStatusPending,
+				Priority:   rebooterAddOpts.priority,
+				Deadline:   deadline,
 			}
 			if !flagDryRun {
 				err := necoStorage.RegisterRebootListEntry(ctx, &newEntry)
 				if err != nil {
 					return err
 				}
+				err = recordRebootListEvents(ctx, []*neco.RebootListEntry{&newEntry}, neco.RebootListEventAdded, rebooterAddOpts.reason)
+				if err != nil {
+					return err
+				}
 			}
 		}
 		return nil
@@ -99,7 +125,25 @@ func validateNode(node string, kubeNode corev1.NodeList) (*corev1.Node, error) {
 	return nil, fmt.Errorf("%s is not a valid node IP address", node)
 }
 
+// parseFutureTime parses s as RFC3339 time or a duration from now.
+func parseFutureTime(s string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t.UTC(), nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time: %s", s)
+	}
+	if d <= 0 {
+		return time.Time{}, fmt.Errorf("time must be in the future: %s", s)
+	}
+	return now.Add(d).UTC(), nil
+}
+
 func init() {
 	rebooterAddCmd.Flags().BoolVar(&flagDryRun, "dry-run", false, "dry-run")
+	rebooterAddCmd.Flags().IntVar(&rebooterAddOpts.priority, "priority", 0, "priority of the reboot; higher is processed first")
+	rebooterAddCmd.Flags().StringVar(&rebooterAddOpts.deadline, "deadline", "", "time by which the nodes must be rebooted (RFC3339 or duration)")
+	rebooterAddCmd.Flags().StringVar(&rebooterAddOpts.reason, "reason", "", "reason of the reboot recorded in the history")
 	rebooterCmd.AddCommand(rebooterAddCmd)
 }
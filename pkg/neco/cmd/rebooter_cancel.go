@@ -2,6 +2,8 @@ package cmd
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"os"
 	"os/signal"
 	"strconv"
@@ -11,28 +13,64 @@ import (
 	"github.com/spf13/cobra"
 )
 
+var rebooterCancelOpts struct {
+	rebooterSelectorOpts
+	reason string
+}
+
 var rebooterCancelCmd = &cobra.Command{
-	Use:   "cancel INDEX",
-	Short: "cancel the specified reboot list entry",
-	Long:  `Cancel the specified reboot list entry.`,
-	Args:  cobra.ExactArgs(1),
+	Use:   "cancel [INDEX]",
+	Short: "cancel the specified reboot list entries",
+	Long: `Cancel the specified reboot list entries.
+
+Entries are specified by INDEX or by the selector flags.  When multiple
+selector flags are given, entries that match all of them are cancelled.
+The entries are cancelled in transactions.`,
+	Args: cobra.MaximumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 		defer stop()
-		index, err := strconv.ParseInt(args[0], 10, 64)
+		if len(args) == 1 {
+			if !rebooterCancelOpts.isEmpty() {
+				return errors.New("INDEX and selector flags cannot be specified at the same time")
+			}
+			index, err := strconv.ParseInt(args[0], 10, 64)
+			if err != nil {
+				return err
+			}
+			entry, err := necoStorage.GetRebootListEntry(ctx, index)
+			if err != nil {
+				return err
+			}
+			entry.Status = neco.RebootListEntryStatusCancelled
+			err = necoStorage.UpdateRebootListEntry(ctx, entry)
+			if err != nil {
+				return err
+			}
+			return recordRebootListEvents(ctx, []*neco.RebootListEntry{entry}, neco.RebootListEventCancelled, rebooterCancelOpts.reason)
+		}
+
+		if rebooterCancelOpts.isEmpty() {
+			return errors.New("INDEX or selector flags must be specified")
+		}
+		sel, err := rebooterCancelOpts.selector(ctx)
 		if err != nil {
 			return err
 		}
-		entry, err := necoStorage.GetRebootListEntry(ctx, index)
+		entries, err := necoStorage.CancelRebootListEntries(ctx, sel)
 		if err != nil {
 			return err
 		}
-		entry.Status = neco.RebootListEntryStatusCancelled
-		return necoStorage.UpdateRebootListEntry(ctx, entry)
+		for _, entry := range entries {
+			fmt.Printf("cancelled the entry. index:%d, node:%s\n", entry.Index, entry.Node)
+		}
+		return recordRebootListEvents(ctx, entries, neco.RebootListEventCancelled, rebooterCancelOpts.reason)
 	},
 }
 
 func init() {
+	rebooterCancelOpts.addFlags(rebooterCancelCmd)
+	rebooterCancelCmd.Flags().StringVar(&rebooterCancelOpts.reason, "reason", "", "reason of the cancellation recorded in the history")
 	rebooterCmd.AddCommand(rebooterCancelCmd)
 
 }
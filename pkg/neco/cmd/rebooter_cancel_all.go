@@ -11,6 +11,10 @@ import (
 	"github.com/spf13/cobra"
 )
 
+var rebooterCancelAllOpts struct {
+	reason string
+}
+
 var rebooterCancelAllCmd = &cobra.Command{
 	Use:   "cancel-all",
 	Short: "cancel all the reboot list entries",
@@ -31,11 +35,12 @@ var rebooterCancelAllCmd = &cobra.Command{
 				return err
 			}
 		}
-		return nil
+		return recordRebootListEvents(ctx, entries, neco.RebootListEventCancelled, rebooterCancelAllOpts.reason)
 	},
 }
 
 func init() {
+	rebooterCancelAllCmd.Flags().StringVar(&rebooterCancelAllOpts.reason, "reason", "", "reason of the cancellation recorded in the history")
 	rebooterCmd.AddCommand(rebooterCancelAllCmd)
 
 }
@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var rebooterHistoryOpts struct {
+	output string
+}
+
+var rebooterHistoryCmd = &cobra.Command{
+	Use:   "history [NODE]",
+	Short: "show the history of the reboot list",
+	Long: `Show the history of the reboot list.
+
+This shows the events of the reboot list entries such as added, queued,
+dequeued, postponed, cancelled, completed, and timed out, in the order of time.
+If NODE is given, only the events of the node are shown.
+Old events are deleted by neco-rebooter after the retention period.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		var node string
+		if len(args) == 1 {
+			node = args[0]
+		}
+		events, err := necoStorage.GetRebootListEvents(ctx, node)
+		if err != nil {
+			return err
+		}
+
+		if rebooterHistoryOpts.output == "simple" {
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 1, 1, ' ', 0)
+			w.Write([]byte("Time\tType\tIndex\tNode\tGroup\tActor\tReason\n"))
+			for _, ev := range events {
+				w.Write([]byte(fmt.Sprintf("%v\t%v\t%v\t%v\t%v\t%v\t%v\t\n", ev.Time.Format(time.RFC3339), ev.Type, ev.Index, ev.Node, ev.Group, ev.Actor, ev.Reason)))
+			}
+			return w.Flush()
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "    ")
+		return enc.Encode(events)
+	},
+}
+
+func init() {
+	rebooterHistoryCmd.Flags().StringVarP(&rebooterHistoryOpts.output, "output", "o", "json", "Output format [json,simple]")
+	rebooterCmd.AddCommand(rebooterHistoryCmd)
+}
@@ -13,7 +13,9 @@ import (
 var rebooterIsEnabledCmd = &cobra.Command{
 	Use:   "is-enabled",
 	Short: "show reboot list status",
-	Long:  `Show whether the processing of the reboot list is enabled or not.  "true" if enabled.`,
+	Long: `Show whether the processing of the reboot list is enabled or not.  "true" if enabled.
+
+If neco-rebooter paused the processing by itself, the reason is shown in the standard error.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 		defer stop()
@@ -22,6 +24,16 @@ var rebooterIsEnabledCmd = &cobra.Command{
 			return err
 		}
 		fmt.Println(enabled)
+		if enabled {
+			return nil
+		}
+		reason, err := necoStorage.GetNecoRebooterPauseReason(ctx)
+		if err != nil {
+			return err
+		}
+		if reason != "" {
+			fmt.Fprintln(os.Stderr, "paused:", reason)
+		}
 		return nil
 	},
 }
@@ -8,6 +8,7 @@ import (
 	"os/signal"
 	"syscall"
 	"text/tabwriter"
+	"time"
 
 	"github.com/spf13/cobra"
 )
@@ -29,9 +30,21 @@ var rebooterListCmd = &cobra.Command{
 		}
 		if rebootListListOptions.Output == "simple" {
 			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 1, 1, ' ', 0)
-			w.Write([]byte("Index\tNode\tGroup\tRebootTime\tStatus\n"))
+			w.Write([]byte("Index\tNode\tGroup\tRebootTime\tStatus\tPriority\tDeadline\n"))
+			now := time.Now()
 			for _, entry := range entries {
-				w.Write([]byte(fmt.Sprintf("%v\t%v\t%v\t%v\t%v\t\n", entry.Index, entry.Node, entry.Group, entry.RebootTime, entry.Status)))
+				deadline := "-"
+				if entry.Deadline != nil {
+					deadline = entry.Deadline.Format(time.RFC3339)
+					if entry.IsOverdue(now) {
+						deadline += " (overdue)"
+					}
+				}
+				status := entry.Status
+				if entry.IsPostponed(now) {
+					status += " (until " + entry.PostponedUntil.Format(time.RFC3339) + ")"
+				}
+				w.Write([]byte(fmt.Sprintf("%v\t%v\t%v\t%v\t%v\t%v\t%v\t\n", entry.Index, entry.Node, entry.Group, entry.RebootTime, status, entry.Priority, deadline)))
 			}
 			return w.Flush()
 		} else {
@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/cybozu-go/neco"
+	"github.com/spf13/cobra"
+)
+
+var rebooterPostponeOpts struct {
+	rebooterSelectorOpts
+	until  string
+	reason string
+}
+
+var rebooterPostponeCmd = &cobra.Command{
+	Use:   "postpone [INDEX] --until TIME",
+	Short: "postpone the specified reboot list entries",
+	Long: `Postpone the specified reboot list entries.
+
+The entries are moved back to Pending, and are not processed until TIME.
+If the nodes are in the reboot queue of CKE, neco-rebooter cancels them.
+TIME is given in RFC3339 format or as a duration from now such as "24h".
+
+Entries are specified by INDEX or by the selector flags.  When multiple
+selector flags are given, entries that match all of them are postponed.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		if rebooterPostponeOpts.until == "" {
+			return errors.New("--until must be specified")
+		}
+		until, err := parseFutureTime(rebooterPostponeOpts.until, time.Now())
+		if err != nil {
+			return fmt.Errorf("invalid --until: %w", err)
+		}
+
+		var sel *neco.RebootListEntrySelector
+		switch {
+		case len(args) == 1 && !rebooterPostponeOpts.isEmpty():
+			return errors.New("INDEX and selector flags cannot be specified at the same time")
+		case len(args) == 1:
+			index, err := strconv.ParseInt(args[0], 10, 64)
+			if err != nil {
+				return err
+			}
+			entry, err := necoStorage.GetRebootListEntry(ctx, index)
+			if err != nil {
+				return err
+			}
+			sel = &neco.RebootListEntrySelector{Nodes: []string{entry.Node}}
+		case rebooterPostponeOpts.isEmpty():
+			return errors.New("INDEX or selector flags must be specified")
+		default:
+			sel, err = rebooterPostponeOpts.selector(ctx)
+			if err != nil {
+				return err
+			}
+		}
+
+		entries, err := necoStorage.PostponeRebootListEntries(ctx, sel, until)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			fmt.Printf("postponed the entry until %s. index:%d, node:%s\n", until.Format(time.RFC3339), entry.Index, entry.Node)
+		}
+		reason := "postponed until " + until.Format(time.RFC3339)
+		if rebooterPostponeOpts.reason != "" {
+			reason += ": " + rebooterPostponeOpts.reason
+		}
+		return recordRebootListEvents(ctx, entries, neco.RebootListEventPostponed, reason)
+	},
+}
+
+func init() {
+	rebooterPostponeOpts.addFlags(rebooterPostponeCmd)
+	rebooterPostponeCmd.Flags().StringVar(&rebooterPostponeOpts.until, "until", "", "time until which the entries are postponed (RFC3339 or duration)")
+	rebooterPostponeCmd.Flags().StringVar(&rebooterPostponeOpts.reason, "reason", "", "reason of the postponement recorded in the history")
+	rebooterCmd.AddCommand(rebooterPostponeCmd)
+}
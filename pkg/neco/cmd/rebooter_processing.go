@@ -10,10 +10,17 @@ import (
 	"github.com/spf13/cobra"
 )
 
+var rebooterShowProcessingGroupOpts struct {
+	order bool
+}
+
 var rebooterShowProcessingGroupCmd = &cobra.Command{
 	Use:   "show-processing-group",
 	Short: "show the processing group",
-	Long:  `Show the processing group.`,
+	Long: `Show the processing group.
+
+With --order, this shows all the groups in the reboot list in the order
+expected to be processed by neco-rebooter.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 		defer stop()
@@ -22,12 +29,28 @@ var rebooterShowProcessingGroupCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
-		fmt.Println(pg)
+		if !rebooterShowProcessingGroupOpts.order {
+			fmt.Println(pg)
+			return nil
+		}
+
+		groups, err := necoStorage.GetGroupOrder(ctx)
+		if err != nil {
+			return err
+		}
+		for _, group := range groups {
+			if group == pg {
+				fmt.Println(group, "(processing)")
+				continue
+			}
+			fmt.Println(group)
+		}
 		return nil
 	},
 }
 
 func init() {
+	rebooterShowProcessingGroupCmd.Flags().BoolVar(&rebooterShowProcessingGroupOpts.order, "order", false, "show the expected order of groups")
 	rebooterCmd.AddCommand(rebooterShowProcessingGroupCmd)
 
 }
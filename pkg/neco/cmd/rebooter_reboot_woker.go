@@ -124,7 +124,7 @@ RETRY:
 		if !ok {
 			return fmt.Errorf("node has no groupKey label (%s)", config.GroupLabelKey)
 		}
-		rt, err := matchRebootTimes(node, config.RebootTimes)
+		rt, err := config.MatchRebootTime(&node)
 		if err != nil {
 			fmt.Println(err)
 			continue
@@ -141,6 +141,10 @@ RETRY:
 			if err != nil {
 				return err
 			}
+			err = recordRebootListEvents(ctx, []*neco.RebootListEntry{&newEntry}, neco.RebootListEventAdded, "reboot-worker")
+			if err != nil {
+				return err
+			}
 		}
 	}
 	// reboot non-kubernetes nodes immediately
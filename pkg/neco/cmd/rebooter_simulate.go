@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/cybozu-go/neco"
+	necorebooter "github.com/cybozu-go/neco/pkg/neco-rebooter"
+	"github.com/spf13/cobra"
+)
+
+var rebooterSimulateOpts struct {
+	from           string
+	to             string
+	rebootList     string
+	rebootDuration time.Duration
+}
+
+var rebooterSimulateCmd = &cobra.Command{
+	Use:   "simulate",
+	Short: "simulate the reboot times in the config file",
+	Long: `Simulate the reboot times in the config file.
+
+This shows the windows in which the reboot is allowed for each RebootTime
+between --from and --to.  The windows are evaluated in the same way as
+neco-rebooter does; neco-rebooter regards a minute before the scheduled time
+as within the schedule.
+
+--from is given in RFC3339 format.  --to is given in RFC3339 format or as
+a duration from --from such as "24h".
+
+If --reboot-list is given, this also shows the projected schedule of the
+entries in the list.  The list is in the format of "neco rebooter list".
+Each reboot is assumed to take --reboot-duration, and nodes are assumed
+to be rebooted one by one.
+
+This command does not access etcd nor Kubernetes.`,
+	Args: cobra.NoArgs,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		configFile, err := os.Open(flagConfigFile)
+		if err != nil {
+			return err
+		}
+		defer configFile.Close()
+		config, err = necorebooter.LoadConfig(configFile)
+		return err
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		from := time.Now()
+		if rebooterSimulateOpts.from != "" {
+			t, err := time.Parse(time.RFC3339, rebooterSimulateOpts.from)
+			if err != nil {
+				return fmt.Errorf("invalid --from: %w", err)
+			}
+			from = t
+		}
+		to, err := parseSimulateTo(rebooterSimulateOpts.to, from)
+		if err != nil {
+			return err
+		}
+		tz, err := time.LoadLocation(config.TimeZone)
+		if err != nil {
+			return err
+		}
+		from = from.In(tz)
+		to = to.In(tz)
+
+		rebootTimes, err := config.GetRebootTime()
+		if err != nil {
+			return err
+		}
+
+		out := cmd.OutOrStdout()
+		for _, rt := range config.RebootTimes {
+			fmt.Fprintf(out, "RebootTime: %s\n", rt.Name)
+			fmt.Fprintf(out, "Selector: %s\n", rt.LabelSelector)
+			windows := necorebooter.SimulateWindows(rebootTimes[rt.Name], from, to)
+			if len(windows) == 0 {
+				fmt.Fprintln(out, "  (no windows)")
+			}
+			for _, w := range windows {
+				fmt.Fprintf(out, "  %s - %s (%s)\n", w.Start.Format(time.RFC3339), w.End.Format(time.RFC3339), w.End.Sub(w.Start))
+			}
+			fmt.Fprintln(out)
+		}
+
+		if rebooterSimulateOpts.rebootList == "" {
+			return nil
+		}
+		data, err := os.ReadFile(rebooterSimulateOpts.rebootList)
+		if err != nil {
+			return err
+		}
+		var entries []*neco.RebootListEntry
+		err = json.Unmarshal(data, &entries)
+		if err != nil {
+			return err
+		}
+		schedule, err := necorebooter.SimulateSchedule(config, rebootTimes, entries, from, to, rebooterSimulateOpts.rebootDuration)
+		if err != nil {
+			return err
+		}
+
+		formatTime := func(t *time.Time) string {
+			if t == nil {
+				return "-"
+			}
+			return t.In(tz).Format(time.RFC3339)
+		}
+		w := tabwriter.NewWriter(out, 0, 1, 1, ' ', 0)
+		w.Write([]byte("Node\tGroup\tRebootTime\tEnqueued\tCompleted\n"))
+		for _, s := range schedule {
+			w.Write([]byte(fmt.Sprintf("%v\t%v\t%v\t%v\t%v\t\n", s.Node, s.Group, s.RebootTime, formatTime(s.EnqueuedAt), formatTime(s.CompletedAt))))
+		}
+		return w.Flush()
+	},
+}
+
+func parseSimulateTo(s string, from time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		if !t.After(from) {
+			return time.Time{}, fmt.Errorf("--to must be after --from: %s", s)
+		}
+		return t, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return time.Time{}, fmt.Errorf("invalid --to: %s", s)
+	}
+	return from.Add(d), nil
+}
+
+func init() {
+	rebooterSimulateCmd.Flags().StringVar(&rebooterSimulateOpts.from, "from", "", "start of the simulation in RFC3339 (default: now)")
+	rebooterSimulateCmd.Flags().StringVar(&rebooterSimulateOpts.to, "to", "168h", "end of the simulation in RFC3339 or duration from --from")
+	rebooterSimulateCmd.Flags().StringVar(&rebooterSimulateOpts.rebootList, "reboot-list", "", "reboot list file in JSON to project the schedule")
+	rebooterSimulateCmd.Flags().DurationVar(&rebooterSimulateOpts.rebootDuration, "reboot-duration", 10*time.Minute, "time to reboot a node")
+	rebooterCmd.AddCommand(rebooterSimulateCmd)
+}
@@ -0,0 +1,15 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var sabakanStateSetterCmd = &cobra.Command{
+	Use:   "sabakan-state-setter",
+	Short: "sabakan-state-setter related commands",
+	Long:  `sabakan-state-setter related commands.`,
+}
+
+func init() {
+	rootCmd.AddCommand(sabakanStateSetterCmd)
+}
@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/cybozu-go/log"
+	"github.com/cybozu-go/neco"
+	"github.com/cybozu-go/neco/storage"
+	"github.com/cybozu-go/well"
+	"github.com/spf13/cobra"
+)
+
+var sabakanStateSetterAckCmd = &cobra.Command{
+	Use:   "ack",
+	Short: "acknowledge the tripped circuit breaker of sabakan-state-setter",
+	Long: `Acknowledge the tripped circuit breaker of sabakan-state-setter.
+
+The state transitions held by the circuit breaker are applied in the next cycle.`,
+
+	Args: cobra.ExactArgs(0),
+	Run: func(cmd *cobra.Command, args []string) {
+		etcd, err := neco.EtcdClient()
+		if err != nil {
+			log.ErrorExit(err)
+		}
+		defer etcd.Close()
+		st := storage.NewStorage(etcd)
+
+		well.Go(func(ctx context.Context) error {
+			reason, err := st.GetCircuitBreaker(ctx)
+			if err == storage.ErrNotFound {
+				return errors.New("circuit breaker is not tripped")
+			}
+			if err != nil {
+				return err
+			}
+			fmt.Println("tripped:", reason)
+			return st.AckCircuitBreaker(ctx)
+		})
+		well.Stop()
+		err = well.Wait()
+		if err != nil {
+			log.ErrorExit(err)
+		}
+	},
+}
+
+func init() {
+	sabakanStateSetterCmd.AddCommand(sabakanStateSetterAckCmd)
+}
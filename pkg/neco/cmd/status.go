@@ -11,6 +11,7 @@ import (
 	"github.com/cybozu-go/log"
 	"github.com/cybozu-go/neco"
 	"github.com/cybozu-go/neco/storage"
+	"github.com/cybozu-go/neco/updater"
 	"github.com/cybozu-go/well"
 	"github.com/spf13/cobra"
 )
@@ -26,6 +27,18 @@ func showStatus(ctx context.Context, st storage.Storage, w io.Writer) error {
 	statuses := ss.Statuses
 
 	fmt.Fprintln(w, "Boot servers:", lrns)
+	if len(ss.Canary) > 0 {
+		fmt.Fprintln(w, "Canary servers:", ss.Canary)
+	}
+	if ss.Pinned != "" {
+		fmt.Fprintln(w, "Pinned:", ss.Pinned)
+	}
+	if reason, _ := updater.Frozen(ss, time.Now()); reason != "" {
+		fmt.Fprintln(w, "Frozen:", reason)
+	}
+	for _, dw := range ss.DenyWindows {
+		fmt.Fprintln(w, "Deny window:", dw.String())
+	}
 	fmt.Fprintln(w, "Update process")
 	if req == nil {
 		fmt.Fprintln(w, "    status: clear")
@@ -35,15 +48,27 @@ func showStatus(ctx context.Context, st storage.Storage, w io.Writer) error {
 	switch {
 	case checkUpdateAborted(req.Version, statuses):
 		fmt.Fprintln(w, "    status: aborted")
-	case neco.UpdateCompleted(req.Version, req.Servers, statuses):
+	case neco.UpdateCompleted(req.Version, req.Members(), statuses):
 		fmt.Fprintln(w, "    status: completed")
 	default:
 		fmt.Fprintln(w, "    status: running")
 	}
 
 	fmt.Fprintln(w, "   version:", req.Version)
-	fmt.Fprintln(w, "   members:", req.Servers)
+	fmt.Fprintln(w, "   members:", req.Members())
 	fmt.Fprintln(w, "   started:", req.StartedAt.Format(time.RFC3339))
+	if req.Wave != neco.WaveAll {
+		fmt.Fprintln(w, "      wave:", req.Wave.String())
+	}
+	if req.RollbackFrom != "" {
+		fmt.Fprintln(w, "  rollback: from", req.RollbackFrom)
+	}
+	if req.ResumeStep > 0 {
+		fmt.Fprintln(w, "   resumed: from step", req.ResumeStep)
+	}
+	if req.SoakStartedAt != nil {
+		fmt.Fprintln(w, "      soak:", req.SoakStartedAt.Format(time.RFC3339), "-", req.SoakStartedAt.Add(ss.SoakPeriod).Format(time.RFC3339))
+	}
 
 	if len(statuses) == 0 {
 		return nil
@@ -61,7 +86,11 @@ func showStatus(ctx context.Context, st storage.Storage, w io.Writer) error {
 			continue
 		}
 		fmt.Fprintf(w, "\nBoot server %d\n", lrn)
-		fmt.Fprintln(w, "    step:", status.Step)
+		if len(status.StepName) > 0 {
+			fmt.Fprintf(w, "    step: %s (%d)\n", status.StepName, status.Step)
+		} else {
+			fmt.Fprintln(w, "    step:", status.Step)
+		}
 		fmt.Fprintln(w, "    condition:", status.Cond.String())
 		if len(status.Message) > 0 {
 			fmt.Fprintln(w, "    message:", status.Message)
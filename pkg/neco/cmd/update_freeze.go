@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"context"
+	"time"
+
+	"github.com/cybozu-go/log"
+	"github.com/cybozu-go/neco"
+	"github.com/cybozu-go/neco/storage"
+	"github.com/cybozu-go/well"
+	"github.com/spf13/cobra"
+)
+
+var updateFreezeOpts struct {
+	reason string
+}
+
+var updateFreezeCmd = &cobra.Command{
+	Use:   "freeze",
+	Short: "freeze updates by neco-updater",
+	Long: `Freeze updates by neco-updater until "neco update unfreeze" is run.
+
+While updates are frozen, neco-updater does not start updating a new release
+nor rolling out a release to the rest of boot servers after the canary wave.
+The update process already running is not stopped.`,
+
+	Args: cobra.ExactArgs(0),
+	Run: func(cmd *cobra.Command, args []string) {
+		etcd, err := neco.EtcdClient()
+		if err != nil {
+			log.ErrorExit(err)
+		}
+		defer etcd.Close()
+		st := storage.NewStorage(etcd)
+
+		well.Go(func(ctx context.Context) error {
+			return st.PutUpdateFreeze(ctx, neco.UpdateFreeze{
+				Reason:   updateFreezeOpts.reason,
+				FrozenAt: time.Now().UTC(),
+			})
+		})
+		well.Stop()
+		err = well.Wait()
+		if err != nil {
+			log.ErrorExit(err)
+		}
+	},
+}
+
+func init() {
+	updateFreezeCmd.Flags().StringVar(&updateFreezeOpts.reason, "reason", "", "reason of the freeze")
+	updateCmd.AddCommand(updateFreezeCmd)
+}
@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/cybozu-go/log"
+	"github.com/cybozu-go/neco"
+	"github.com/cybozu-go/neco/ext"
+	"github.com/cybozu-go/neco/storage"
+	"github.com/cybozu-go/well"
+	version "github.com/hashicorp/go-version"
+	"github.com/spf13/cobra"
+)
+
+var updatePinCmd = &cobra.Command{
+	Use:   "pin VERSION",
+	Short: "pin the neco release to be installed by neco-updater",
+	Long: `Pin the neco release to be installed by neco-updater to VERSION
+until "neco update unpin" is run.
+
+While a release is pinned, neco-updater installs VERSION instead of
+the latest release found on GitHub.  If VERSION is older than the
+installed release, boot servers are downgraded to VERSION.
+
+VERSION must be a release for the environment of this data center.
+The existence of the release is not checked in "test" and "none"
+environments.`,
+
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		_, err := version.NewVersion(args[0])
+		if err != nil {
+			log.ErrorExit(fmt.Errorf("invalid version: %s: %w", args[0], err))
+		}
+
+		etcd, err := neco.EtcdClient()
+		if err != nil {
+			log.ErrorExit(err)
+		}
+		defer etcd.Close()
+		st := storage.NewStorage(etcd)
+
+		well.Go(func(ctx context.Context) error {
+			err := checkRelease(ctx, st, args[0])
+			if err != nil {
+				return err
+			}
+			return st.PutUpdatePin(ctx, args[0])
+		})
+		well.Stop()
+		err = well.Wait()
+		if err != nil {
+			log.ErrorExit(err)
+		}
+	},
+}
+
+// checkRelease checks that the neco release version exists on GitHub
+// for the environment of this data center.
+func checkRelease(ctx context.Context, st storage.Storage, version string) error {
+	env, err := st.GetEnvConfig(ctx)
+	if err != nil {
+		return err
+	}
+	switch env {
+	case neco.NoneEnv, neco.TestEnv:
+		return nil
+	}
+
+	hc, err := ext.GitHubHTTPClient(ctx, st)
+	if err != nil {
+		return err
+	}
+	gh := neco.NewGitHubClient(hc)
+	tag := neco.ReleaseTag(env, version)
+	_, resp, err := gh.Repositories.GetReleaseByTag(ctx, neco.GitHubRepoOwner, neco.GitHubRepoName, tag)
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return fmt.Errorf("release %s is not found for %s environment", tag, env)
+		}
+		return fmt.Errorf("failed to get release %s: %w", tag, err)
+	}
+	return nil
+}
+
+func init() {
+	updateCmd.AddCommand(updatePinCmd)
+}
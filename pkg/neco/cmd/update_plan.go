@@ -0,0 +1,301 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"runtime/debug"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/cybozu-go/log"
+	"github.com/cybozu-go/neco"
+	"github.com/cybozu-go/neco/ext"
+	"github.com/cybozu-go/neco/progs/sabakan"
+	"github.com/cybozu-go/neco/storage"
+	"github.com/cybozu-go/neco/worker"
+	"github.com/cybozu-go/well"
+	"github.com/google/go-github/v50/github"
+	"github.com/spf13/cobra"
+)
+
+const ckeModulePath = "github.com/cybozu-go/cke"
+
+var updatePlanOpts struct {
+	output string
+}
+
+type updatePlan struct {
+	Version string            `json:"version"`
+	Servers []int             `json:"servers"`
+	Items   []worker.PlanItem `json:"items"`
+}
+
+func showUpdatePlan(w io.Writer, plan *updatePlan) error {
+	if updatePlanOpts.output == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "    ")
+		return enc.Encode(plan)
+	}
+
+	fmt.Fprintln(w, "Target version:", plan.Version)
+	fmt.Fprintln(w, "Boot servers:", plan.Servers)
+	if len(plan.Items) == 0 {
+		fmt.Fprintln(w, "No artifacts will be updated.")
+		return nil
+	}
+
+	fmt.Fprintln(w)
+	tw := tabwriter.NewWriter(w, 0, 1, 2, ' ', 0)
+	fmt.Fprintln(tw, "KIND\tNAME\tSERVERS\tINSTALLED\tTARGET\tSTEP")
+	for _, item := range plan.Items {
+		servers := "-"
+		if len(item.Servers) > 0 {
+			servers = fmt.Sprint(item.Servers)
+		}
+		installed := item.Installed
+		if installed == "" {
+			installed = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			item.Kind, item.Name, servers, installed, item.Target, item.Step)
+	}
+	return tw.Flush()
+}
+
+// releaseArtifacts represents the artifacts of a neco release.
+type releaseArtifacts struct {
+	artifacts  *neco.ArtifactSet
+	ckeVersion string
+}
+
+// installedCKEVersion returns the version of CKE built into this neco binary.
+func installedCKEVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+	for _, dep := range info.Deps {
+		if dep.Path == ckeModulePath {
+			return dep.Version
+		}
+	}
+	return ""
+}
+
+// ckeVersionInGoMod returns the version of CKE required in go.mod.
+func ckeVersionInGoMod(data []byte) string {
+	s := bufio.NewScanner(bytes.NewReader(data))
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) > 0 && fields[0] == "require" {
+			fields = fields[1:]
+		}
+		if len(fields) >= 2 && fields[0] == ckeModulePath {
+			return fields[1]
+		}
+	}
+	return ""
+}
+
+// loadReleaseArtifacts returns the artifacts of the neco release version.
+// The artifacts of releases other than the installed one are read from
+// artifacts.go and go.mod of the release tag on GitHub.
+func loadReleaseArtifacts(ctx context.Context, st storage.Storage, installed, version string) (*releaseArtifacts, error) {
+	if version == installed {
+		return &releaseArtifacts{
+			artifacts:  &neco.CurrentArtifacts,
+			ckeVersion: installedCKEVersion(),
+		}, nil
+	}
+
+	env, err := st.GetEnvConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	switch env {
+	case neco.NoneEnv, neco.TestEnv:
+		return nil, fmt.Errorf("artifacts of %s are unknown in %s environment", version, env)
+	}
+
+	hc, err := ext.GitHubHTTPClient(ctx, st)
+	if err != nil {
+		return nil, err
+	}
+	gh := neco.NewGitHubClient(hc)
+	tag := neco.ReleaseTag(env, version)
+	download := func(path string) ([]byte, error) {
+		r, _, err := gh.Repositories.DownloadContents(ctx, neco.GitHubRepoOwner, neco.GitHubRepoName, path,
+			&github.RepositoryContentGetOptions{Ref: tag})
+		if err != nil {
+			return nil, fmt.Errorf("failed to download %s of %s: %w", path, tag, err)
+		}
+		defer r.Close()
+		return io.ReadAll(r)
+	}
+
+	src, err := download("artifacts.go")
+	if err != nil {
+		return nil, err
+	}
+	artifacts, err := neco.ParseArtifactSet(src)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse artifacts.go of %s: %w", tag, err)
+	}
+	gomod, err := download("go.mod")
+	if err != nil {
+		return nil, err
+	}
+
+	return &releaseArtifacts{
+		artifacts:  artifacts,
+		ckeVersion: ckeVersionInGoMod(gomod),
+	}, nil
+}
+
+func makeUpdatePlan(ctx context.Context, st storage.Storage, installed, version string) (*updatePlan, error) {
+	ss, err := st.NewSnapshot(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	release, err := loadReleaseArtifacts(ctx, st, installed, version)
+	if err != nil {
+		return nil, err
+	}
+	target := release.artifacts
+	items, err := worker.Plan(ctx, st, ss.Servers, target)
+	if err != nil {
+		return nil, err
+	}
+
+	if version != installed {
+		ckeVersion := installedCKEVersion()
+		if ckeVersion != release.ckeVersion {
+			items = append(items, worker.PlanItem{
+				Kind:      worker.PlanKindCKE,
+				Name:      "cke",
+				Installed: ckeVersion,
+				Target:    release.ckeVersion,
+				Step:      worker.CKEStep,
+			})
+		}
+	}
+
+	contents, err := worker.PlanContents(ctx, st, version)
+	if err != nil {
+		return nil, err
+	}
+	items = append(items, contents...)
+
+	var images []neco.ContainerImage
+	for _, img := range target.Images {
+		if slices.Contains(neco.SabakanImages, img.Name) {
+			images = append(images, img)
+		}
+	}
+	missing, err := sabakan.MissingImageAssets(ctx, ext.LocalHTTPClient(), images)
+	if err != nil {
+		log.Warn("failed to get the uploaded assets", map[string]interface{}{
+			log.FnError: err,
+		})
+	}
+	for _, img := range missing {
+		var installed string
+		if cur, err := neco.CurrentArtifacts.FindContainerImage(img.Name); err == nil && cur.Tag != img.Tag {
+			installed = cur.Tag
+		}
+		items = append(items, worker.PlanItem{
+			Kind:      worker.PlanKindAsset,
+			Name:      img.Name,
+			Installed: installed,
+			Target:    img.Tag,
+			Step:      worker.OSImageStep,
+		})
+	}
+
+	osImage, err := sabakan.UploadedOSImage(ctx, ext.LocalHTTPClient())
+	if err != nil {
+		log.Warn("failed to get the uploaded OS image", map[string]interface{}{
+			log.FnError: err,
+		})
+	} else if osImage != target.OSImage.Version {
+		items = append(items, worker.PlanItem{
+			Kind:      worker.PlanKindOSImage,
+			Name:      target.OSImage.Channel,
+			Installed: osImage,
+			Target:    target.OSImage.Version,
+			Step:      worker.OSImageStep,
+		})
+	}
+
+	return &updatePlan{
+		Version: version,
+		Servers: ss.Servers,
+		Items:   items,
+	}, nil
+}
+
+var updatePlanCmd = &cobra.Command{
+	Use:   "plan [VERSION]",
+	Short: "show artifacts to be updated by the neco package",
+	Long: `Show the differences between the artifacts installed on boot servers
+and the artifacts of the neco package VERSION, together with the update
+steps which install them and restart the services.
+
+Installed versions are read from install/<LRN>/containers and
+install/<LRN>/debs records in etcd, from the contents statuses in etcd
+for the contents of sabakan and CKE, and from sabakan for the OS image
+and the container images for worker nodes.  Nothing is written.
+
+VERSION defaults to the neco package installed on this server.
+The artifacts of other versions are read from the release tag on GitHub.`,
+
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		switch updatePlanOpts.output {
+		case "table", "json":
+		default:
+			log.ErrorExit(fmt.Errorf("invalid output format: %s", updatePlanOpts.output))
+		}
+
+		installed, err := neco.GetDebianVersion(neco.NecoPackageName)
+		if err != nil {
+			log.ErrorExit(err)
+		}
+		version := installed
+		if len(args) == 1 {
+			version = args[0]
+		}
+
+		etcd, err := neco.EtcdClient()
+		if err != nil {
+			log.ErrorExit(err)
+		}
+		defer etcd.Close()
+		st := storage.NewStorage(etcd)
+
+		well.Go(func(ctx context.Context) error {
+			plan, err := makeUpdatePlan(ctx, st, installed, version)
+			if err != nil {
+				return err
+			}
+			return showUpdatePlan(os.Stdout, plan)
+		})
+		well.Stop()
+		err = well.Wait()
+		if err != nil {
+			log.ErrorExit(err)
+		}
+	},
+}
+
+func init() {
+	updatePlanCmd.Flags().StringVarP(&updatePlanOpts.output, "output", "o", "table", "Output format [table,json]")
+	updateCmd.AddCommand(updatePlanCmd)
+}
@@ -0,0 +1,29 @@
+package cmd
+
+import "testing"
+
+func TestCKEVersionInGoMod(t *testing.T) {
+	gomod := `module github.com/cybozu-go/neco
+
+go 1.23
+
+require (
+	github.com/cybozu-go/cke v1.30.0
+	github.com/cybozu-go/cke-tools v1.0.0
+)
+
+require github.com/cybozu-go/log v1.7.0
+`
+	if v := ckeVersionInGoMod([]byte(gomod)); v != "v1.30.0" {
+		t.Error("unexpected version", v)
+	}
+
+	gomod = "module github.com/cybozu-go/neco\n\nrequire github.com/cybozu-go/cke v1.29.1\n"
+	if v := ckeVersionInGoMod([]byte(gomod)); v != "v1.29.1" {
+		t.Error("unexpected version", v)
+	}
+
+	if v := ckeVersionInGoMod([]byte("module github.com/cybozu-go/neco\n")); v != "" {
+		t.Error("unexpected version", v)
+	}
+}
@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"context"
+
+	"github.com/cybozu-go/log"
+	"github.com/cybozu-go/neco"
+	"github.com/cybozu-go/neco/storage"
+	"github.com/cybozu-go/well"
+	"github.com/spf13/cobra"
+)
+
+var updateResumeCmd = &cobra.Command{
+	Use:   "resume",
+	Short: "resume the aborted update process from the failed step",
+	Long: `Resume the aborted update process from the failed step.
+
+This keeps the steps which have been completed for the current version,
+and restarts all the workers from the smallest step among boot servers
+which have not completed the update.  Workers synchronize before running
+the step.
+
+Contents update statuses are kept unlike "neco recover".`,
+
+	Args: cobra.ExactArgs(0),
+	Run: func(cmd *cobra.Command, args []string) {
+		etcd, err := neco.EtcdClient()
+		if err != nil {
+			log.ErrorExit(err)
+		}
+		defer etcd.Close()
+		st := storage.NewStorage(etcd)
+
+		well.Go(func(ctx context.Context) error {
+			req, err := st.ResumeRequest(ctx)
+			if err != nil {
+				return err
+			}
+			log.Info("update resumed", map[string]interface{}{
+				"version": req.Version,
+				"step":    req.ResumeStep,
+				"members": req.Members(),
+			})
+			return nil
+		})
+		well.Stop()
+		err = well.Wait()
+		if err != nil {
+			log.ErrorExit(err)
+		}
+	},
+}
+
+func init() {
+	updateCmd.AddCommand(updateResumeCmd)
+}
@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"github.com/cybozu-go/log"
+	"github.com/cybozu-go/neco"
+	"github.com/cybozu-go/neco/storage"
+	"github.com/cybozu-go/well"
+	"github.com/spf13/cobra"
+)
+
+var updateUnfreezeCmd = &cobra.Command{
+	Use:   "unfreeze",
+	Short: "unfreeze updates by neco-updater",
+	Long: `Unfreeze updates frozen by "neco update freeze".
+
+Deny windows configured by "neco config set deny-windows" are still respected.`,
+
+	Args: cobra.ExactArgs(0),
+	Run: func(cmd *cobra.Command, args []string) {
+		etcd, err := neco.EtcdClient()
+		if err != nil {
+			log.ErrorExit(err)
+		}
+		defer etcd.Close()
+		st := storage.NewStorage(etcd)
+
+		well.Go(st.DeleteUpdateFreeze)
+		well.Stop()
+		err = well.Wait()
+		if err != nil {
+			log.ErrorExit(err)
+		}
+	},
+}
+
+func init() {
+	updateCmd.AddCommand(updateUnfreezeCmd)
+}
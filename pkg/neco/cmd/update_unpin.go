@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"github.com/cybozu-go/log"
+	"github.com/cybozu-go/neco"
+	"github.com/cybozu-go/neco/storage"
+	"github.com/cybozu-go/well"
+	"github.com/spf13/cobra"
+)
+
+var updateUnpinCmd = &cobra.Command{
+	Use:   "unpin",
+	Short: "unpin the neco release pinned by neco update pin",
+	Long: `Unpin the neco release pinned by "neco update pin".
+
+neco-updater installs the latest release again.`,
+
+	Args: cobra.ExactArgs(0),
+	Run: func(cmd *cobra.Command, args []string) {
+		etcd, err := neco.EtcdClient()
+		if err != nil {
+			log.ErrorExit(err)
+		}
+		defer etcd.Close()
+		st := storage.NewStorage(etcd)
+
+		well.Go(st.DeleteUpdatePin)
+		well.Stop()
+		err = well.Wait()
+		if err != nil {
+			log.ErrorExit(err)
+		}
+	},
+}
+
+func init() {
+	updateCmd.AddCommand(updateUnpinCmd)
+}
@@ -0,0 +1,177 @@
+package sss
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/cybozu-go/log"
+	"github.com/cybozu-go/neco/storage"
+	"github.com/cybozu-go/sabakan/v3"
+)
+
+// transition is a state transition of a machine to be applied to sabakan.
+type transition struct {
+	machine *machine
+	state   sabakan.MachineState
+}
+
+// isDegrading returns true if the transition makes the machine unhealthy or unreachable.
+func (t *transition) isDegrading() bool {
+	return t.state == sabakan.StateUnhealthy || t.state == sabakan.StateUnreachable
+}
+
+// check returns the reason to trip the circuit breaker.
+// An empty string is returned if the degrading transitions are within the limits.
+func (cb *circuitBreaker) check(machines []*machine, degrading []*transition) string {
+	if cb.MaxTransitions > 0 && len(degrading) > cb.MaxTransitions {
+		return fmt.Sprintf("%d machines would become unhealthy or unreachable; the maximum is %d", len(degrading), cb.MaxTransitions)
+	}
+	if cb.MaxTransitionsPercent == 0 {
+		return ""
+	}
+
+	total := make(map[string]int)
+	for _, m := range machines {
+		total[m.Type]++
+	}
+	count := make(map[string]int)
+	for _, t := range degrading {
+		count[t.machine.Type]++
+	}
+	types := make([]string, 0, len(count))
+	for typ := range count {
+		types = append(types, typ)
+	}
+	slices.Sort(types)
+	for _, typ := range types {
+		n := count[typ]
+		// A single machine never trips the circuit breaker by the percentage.
+		if n > 1 && n*100 > total[typ]*cb.MaxTransitionsPercent {
+			return fmt.Sprintf("%d of %d machines of %s would become unhealthy or unreachable; the maximum is %d%%", n, total[typ], typ, cb.MaxTransitionsPercent)
+		}
+	}
+	return ""
+}
+
+// applyCircuitBreaker returns the transitions to be applied in this cycle.
+// If too many machines would become unhealthy or unreachable at once, the circuit breaker
+// is tripped and those transitions are held.  If RequireAck is set, the transitions are held
+// until the circuit breaker is acknowledged, and then all of them are applied at once.
+func (c *Controller) applyCircuitBreaker(ctx context.Context, machines []*machine, transitions []*transition) ([]*transition, error) {
+	cb := c.circuitBreaker
+	if cb == nil {
+		return transitions, nil
+	}
+
+	var applied, held []*transition
+	for _, t := range transitions {
+		if t.isDegrading() {
+			held = append(held, t)
+		} else {
+			applied = append(applied, t)
+		}
+	}
+
+	if c.breakerReason != "" {
+		if c.isCircuitBreakerAcked(ctx) {
+			err := c.resetCircuitBreaker(ctx)
+			if err != nil {
+				return nil, err
+			}
+			log.Info("circuit breaker is acknowledged; applying held state transitions", map[string]interface{}{
+				"reason":      c.breakerReason,
+				"transitions": len(held),
+			})
+			c.breakerReason = ""
+			c.tripped = false
+			circuitBreakerTrippedGauge.Set(0)
+			circuitBreakerHeldGauge.Set(0)
+			return transitions, nil
+		}
+		log.Warn("circuit breaker is tripped; waiting for acknowledgement", map[string]interface{}{
+			"reason":      c.breakerReason,
+			"transitions": len(held),
+		})
+	} else {
+		reason := cb.check(machines, held)
+		if reason == "" {
+			c.tripped = false
+			circuitBreakerTrippedGauge.Set(0)
+			circuitBreakerHeldGauge.Set(0)
+			return transitions, nil
+		}
+		// Without RequireAck, the limits are checked in every cycle.
+		// Only the first cycle of a series of the exceeded cycles is counted as a trip.
+		if !c.tripped {
+			circuitBreakerTripsTotal.Inc()
+		}
+		c.tripped = true
+		if cb.RequireAck {
+			err := c.tripCircuitBreaker(ctx, reason)
+			if err == storage.ErrNoLeader {
+				return nil, err
+			}
+			if err != nil {
+				// The transitions are held in this cycle, and the circuit breaker
+				// is tripped again in the next cycle.
+				log.Warn("failed to save circuit breaker", map[string]interface{}{
+					log.FnError: err.Error(),
+				})
+			}
+		}
+		log.Warn("circuit breaker is tripped; holding state transitions", map[string]interface{}{
+			"reason":      reason,
+			"transitions": len(held),
+		})
+	}
+
+	circuitBreakerTrippedGauge.Set(1)
+	circuitBreakerHeldGauge.Set(float64(len(held)))
+	return applied, nil
+}
+
+// tripCircuitBreaker keeps the circuit breaker tripped until it is acknowledged.
+// The state is stored in etcd while this controller is the leader
+// so that it can be acknowledged and the next leader keeps holding the transitions.
+// If the state cannot be stored, the circuit breaker is not kept tripped.
+func (c *Controller) tripCircuitBreaker(ctx context.Context, reason string) error {
+	if c.leaderKey != "" {
+		err := c.storage.TripCircuitBreaker(ctx, c.leaderKey, reason)
+		if err != nil {
+			return err
+		}
+	}
+	c.breakerReason = reason
+	return nil
+}
+
+func (c *Controller) isCircuitBreakerAcked(ctx context.Context) bool {
+	if c.leaderKey == "" {
+		return false
+	}
+	acked, err := c.storage.IsCircuitBreakerAcked(ctx)
+	if err != nil {
+		// Keep holding the transitions until the acknowledgement is confirmed.
+		log.Warn("failed to get acknowledgement of circuit breaker", map[string]interface{}{
+			log.FnError: err.Error(),
+		})
+		return false
+	}
+	return acked
+}
+
+func (c *Controller) resetCircuitBreaker(ctx context.Context) error {
+	err := c.storage.ResetCircuitBreaker(ctx, c.leaderKey)
+	if err == storage.ErrNoLeader {
+		return err
+	}
+	if err != nil {
+		// Apply the acknowledged transitions anyway.  The stale record is
+		// removed when the circuit breaker is tripped next time.
+		log.Warn("failed to reset circuit breaker", map[string]interface{}{
+			log.FnError: err.Error(),
+		})
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"os"
@@ -11,20 +12,37 @@ import (
 	"github.com/cybozu-go/neco"
 	sss "github.com/cybozu-go/neco/pkg/sabakan-state-setter"
 	"github.com/cybozu-go/well"
+	clientv3 "go.etcd.io/etcd/client/v3"
 )
 
 var (
 	flagConfigFile      = flag.String("config-file", "", "path of config file")
 	flagEtcdSessionTTL  = flag.Duration("etcd-session-ttl", 1*time.Minute, "TTL of etcd session")
 	flagInterval        = flag.Duration("interval", 1*time.Minute, "interval of scraping metrics")
+	flagMetricsAddr     = flag.String("metrics-addr", ":10084", "listen address of the metrics server")
 	flagParallelSize    = flag.Int("parallel", 30, "The number of parallel execution of getting machines metrics")
 	flagSabakanURL      = flag.String("sabakan-url", "http://localhost:10080", "sabakan URL")
 	flagSabakanURLHTTPS = flag.String("sabakan-url-https", "https://localhost:10443", "sabakan TLS URL")
 	flagSerfAddress     = flag.String("serf-address", "127.0.0.1:7373", "serf address")
 )
 
+func usage() {
+	fmt.Fprintf(flag.CommandLine.Output(), `Usage:
+  sabakan-state-setter [OPTIONS]
+  sabakan-state-setter [OPTIONS] explain SERIAL
+
+Options:
+`)
+	flag.PrintDefaults()
+}
+
 func main() {
+	flag.Usage = usage
 	flag.Parse()
+	if flag.NArg() != 0 && (flag.NArg() != 2 || flag.Arg(0) != "explain") {
+		flag.Usage()
+		os.Exit(2)
+	}
 	err := well.LogConfig{}.Apply()
 	if err != nil {
 		log.ErrorExit(err)
@@ -41,6 +59,17 @@ func main() {
 	}
 	defer etcdClient.Close()
 
+	if flag.NArg() != 0 {
+		err := explain(etcdClient, hostname, flag.Arg(1))
+		if err != nil {
+			log.ErrorExit(err)
+		}
+		return
+	}
+
+	well.Go(func(ctx context.Context) error {
+		return neco.RunMetricsServer(ctx, *flagMetricsAddr, sss.GetMetricsHandler())
+	})
 	// Using well.Go for terminating this process when catche a signal.
 	well.Go(func(ctx context.Context) error {
 		ctr, err := sss.NewController(etcdClient, *flagSabakanURL, *flagSabakanURLHTTPS, *flagSerfAddress, *flagConfigFile, hostname, *flagInterval, *flagParallelSize, *flagEtcdSessionTTL)
@@ -59,3 +88,20 @@ func main() {
 	}
 	log.Info("exit", nil)
 }
+
+// explain prints how the machine state would be decided without updating sabakan.
+func explain(etcdClient *clientv3.Client, hostname, serial string) error {
+	ctr, err := sss.NewController(etcdClient, *flagSabakanURL, *flagSabakanURLHTTPS, *flagSerfAddress, *flagConfigFile, hostname, *flagInterval, *flagParallelSize, *flagEtcdSessionTTL)
+	if err != nil {
+		return fmt.Errorf("failed to create controller: %s", err.Error())
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	exp, err := ctr.Explain(ctx, serial)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(exp)
+}
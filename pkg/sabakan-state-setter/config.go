@@ -78,9 +78,19 @@ OUTER:
 }
 
 type machineType struct {
-	Name             string         `json:"name"`
-	MetricsCheckList []targetMetric `json:"metrics,omitempty"`
-	GracePeriod      duration       `json:"grace-period"`
+	Name                string         `json:"name"`
+	MetricsCheckList    []targetMetric `json:"metrics,omitempty"`
+	GracePeriod         duration       `json:"grace-period"`
+	RecoveryGracePeriod duration       `json:"recovery-grace-period"`
+	FlapDetection       *flapDetection `json:"flap-detection,omitempty"`
+}
+
+// flapDetection is the configuration to detect machines that bounce between
+// healthy and non-healthy states.  A machine is flapping if it has become
+// non-healthy from healthy Threshold times within Window.
+type flapDetection struct {
+	Threshold int      `json:"threshold"`
+	Window    duration `json:"window"`
 }
 
 type triggerAlert struct {
@@ -96,10 +106,32 @@ type alertMonitor struct {
 	TriggerAlerts        []triggerAlert `json:"trigger-alerts"`
 }
 
+// circuitBreaker is the configuration to stop mass transitions to unhealthy or unreachable
+// caused by failures of serf or metrics exporters seen from the leader.
+type circuitBreaker struct {
+	// MaxTransitions is the maximum number of machines that can become unhealthy or unreachable in one cycle.
+	MaxTransitions int `json:"max-transitions"`
+	// MaxTransitionsPercent is the maximum percentage of machines of a machine type
+	// that can become unhealthy or unreachable in one cycle.
+	MaxTransitionsPercent int `json:"max-transitions-percent"`
+	// RequireAck keeps the circuit breaker tripped until it is acknowledged through etcd.
+	RequireAck bool `json:"require-ack"`
+}
+
 type config struct {
-	ShutdownSchedule string         `json:"shutdown-schedule,omitempty"`
-	MachineTypes     []*machineType `json:"machine-types"`
-	AlertMonitor     *alertMonitor  `json:"alert-monitor"`
+	ShutdownSchedule string          `json:"shutdown-schedule,omitempty"`
+	MachineTypes     []*machineType  `json:"machine-types"`
+	AlertMonitor     *alertMonitor   `json:"alert-monitor"`
+	CircuitBreaker   *circuitBreaker `json:"circuit-breaker"`
+}
+
+// machineTypeMap returns the machine types indexed by their names.
+func (cfg *config) machineTypeMap() map[string]*machineType {
+	machineTypes := make(map[string]*machineType, len(cfg.MachineTypes))
+	for _, t := range cfg.MachineTypes {
+		machineTypes[t.Name] = t
+	}
+	return machineTypes
 }
 
 type duration struct {
@@ -133,56 +165,68 @@ func (d *duration) UnmarshalJSON(b []byte) error {
 	}
 }
 
-func readConfigFile(name string) (string, map[string]*machineType, *alertMonitor, error) {
+func readConfigFile(name string) (*config, error) {
 	cf, err := os.Open(name)
 	if err != nil {
-		return "", nil, nil, err
+		return nil, err
 	}
 	defer cf.Close()
 
-	shutdownSchedule, machineTypes, alertConfig, err := parseConfig(cf)
-	if err != nil {
-		return "", nil, nil, err
-	}
-	return shutdownSchedule, machineTypes, alertConfig, nil
+	return parseConfig(cf)
 }
 
-func parseConfig(reader io.Reader) (string, map[string]*machineType, *alertMonitor, error) {
+func parseConfig(reader io.Reader) (*config, error) {
 	data, err := io.ReadAll(reader)
 	if err != nil {
-		return "", nil, nil, err
+		return nil, err
 	}
 
 	cfg := &config{}
 	err = yaml.Unmarshal(data, cfg)
 	if err != nil {
-		return "", nil, nil, err
+		return nil, err
 	}
 
 	if len(cfg.MachineTypes) == 0 {
-		return "", nil, nil, errors.New("machine-types are not defined")
+		return nil, errors.New("machine-types are not defined")
 	}
-	machineTypes := make(map[string]*machineType)
 	for _, t := range cfg.MachineTypes {
 		if t.GracePeriod.Duration == 0 {
 			t.GracePeriod.Duration = time.Hour
 		}
-		machineTypes[t.Name] = t
+		if t.RecoveryGracePeriod.Duration < 0 {
+			return nil, fmt.Errorf("negative recovery-grace-period for %q", t.Name)
+		}
+		if t.FlapDetection != nil && (t.FlapDetection.Threshold <= 0 || t.FlapDetection.Window.Duration <= 0) {
+			return nil, fmt.Errorf("threshold and window of flap-detection must be positive for %q", t.Name)
+		}
 	}
 
 	if cfg.AlertMonitor != nil {
 		for _, triggerAlert := range cfg.AlertMonitor.TriggerAlerts {
 			if (len(triggerAlert.AddressLabel) == 0) == (len(triggerAlert.SerialLabel) == 0) {
-				return "", nil, nil, fmt.Errorf("exactly one of `address-label` and `serial-label` is required for %q", triggerAlert.Name)
+				return nil, fmt.Errorf("exactly one of `address-label` and `serial-label` is required for %q", triggerAlert.Name)
 			}
 			switch triggerAlert.State {
 			case sabakan.StateUnreachable:
 			case sabakan.StateUnhealthy:
 			default:
-				return "", nil, nil, fmt.Errorf("invalid state %q in %q", triggerAlert.State, triggerAlert.Name)
+				return nil, fmt.Errorf("invalid state %q in %q", triggerAlert.State, triggerAlert.Name)
 			}
 		}
 	}
 
-	return cfg.ShutdownSchedule, machineTypes, cfg.AlertMonitor, nil
+	if cb := cfg.CircuitBreaker; cb != nil {
+		if cb.MaxTransitions < 0 {
+			return nil, errors.New("max-transitions of circuit-breaker must not be negative")
+		}
+		if cb.MaxTransitionsPercent < 0 || cb.MaxTransitionsPercent > 100 {
+			return nil, errors.New("max-transitions-percent of circuit-breaker must be between 0 and 100")
+		}
+		if cb.MaxTransitions == 0 && cb.MaxTransitionsPercent == 0 {
+			return nil, errors.New("circuit-breaker requires max-transitions or max-transitions-percent")
+		}
+	}
+
+	return cfg, nil
 }
@@ -30,10 +30,11 @@ alert-monitor:
       address-label: address
       state: unreachable
 `
-	shutdownSchedule, machineTypes, alertMonitor, err := parseConfig(strings.NewReader(fileContent))
+	cfg, err := parseConfig(strings.NewReader(fileContent))
 	if err != nil {
 		t.Fatal(err)
 	}
+	shutdownSchedule, machineTypes, alertMonitor := cfg.ShutdownSchedule, cfg.machineTypeMap(), cfg.AlertMonitor
 	if shutdownSchedule != "0 11 * * *" {
 		t.Errorf("shutdownSchedule != \"0 11 * * *\", actual \"%s\"", shutdownSchedule)
 	}
@@ -79,10 +80,11 @@ alert-monitor:
 machine-types:
   - name: qemu
 `
-	shutdownSchedule, machineTypes, alertMonitor, err = parseConfig(strings.NewReader(fileContent2))
+	cfg, err = parseConfig(strings.NewReader(fileContent2))
 	if err != nil {
 		t.Fatal(err)
 	}
+	shutdownSchedule, machineTypes, alertMonitor = cfg.ShutdownSchedule, cfg.machineTypeMap(), cfg.AlertMonitor
 	if shutdownSchedule != "" {
 		t.Errorf("shutdownSchedule != \"\", actual \"%s\"", shutdownSchedule)
 	}
@@ -93,7 +95,7 @@ machine-types:
 		t.Error("alertMonitor != nil")
 	}
 
-	_, _, _, err = parseConfig(strings.NewReader("machine-types:"))
+	_, err = parseConfig(strings.NewReader("machine-types:"))
 	if err == nil {
 		t.Error("empty machine-types was not rejected")
 	}
@@ -107,7 +109,7 @@ alert-monitor:
     - name: Foo
       state: unhealthy
 `
-	_, _, _, err = parseConfig(strings.NewReader(fileContent3))
+	_, err = parseConfig(strings.NewReader(fileContent3))
 	if err == nil {
 		t.Error("exactly one of address-label and serial-label is required, but it was not checked")
 	}
@@ -123,7 +125,7 @@ alert-monitor:
       serial-label: serial
       state: unhealthy
 `
-	_, _, _, err = parseConfig(strings.NewReader(fileContent4))
+	_, err = parseConfig(strings.NewReader(fileContent4))
 	if err == nil {
 		t.Error("exactly one of address-label and serial-label is required, but it was not checked")
 	}
@@ -138,8 +140,67 @@ alert-monitor:
       address-label: address
       state: foobar
 `
-	_, _, _, err = parseConfig(strings.NewReader(fileContent5))
+	_, err = parseConfig(strings.NewReader(fileContent5))
 	if err == nil {
 		t.Error("invalid state was not rejected")
 	}
+
+	fileContent6 := `
+machine-types:
+  - name: qemu
+    recovery-grace-period: 30m
+    flap-detection:
+      threshold: 3
+      window: 24h
+`
+	cfg, err = parseConfig(strings.NewReader(fileContent6))
+	if err != nil {
+		t.Fatal(err)
+	}
+	machineTypes = cfg.machineTypeMap()
+	if machineTypes["qemu"].RecoveryGracePeriod.Duration != 30*time.Minute {
+		t.Error("RecoveryGracePeriod is not set")
+	}
+	if fd := machineTypes["qemu"].FlapDetection; fd == nil || fd.Threshold != 3 || fd.Window.Duration != 24*time.Hour {
+		t.Error("FlapDetection is not set", fd)
+	}
+
+	fileContent7 := `
+machine-types:
+  - name: qemu
+    flap-detection:
+      threshold: 3
+`
+	_, err = parseConfig(strings.NewReader(fileContent7))
+	if err == nil {
+		t.Error("flap-detection without window was not rejected")
+	}
+
+	fileContent8 := `
+machine-types:
+  - name: qemu
+circuit-breaker:
+  max-transitions: 10
+  max-transitions-percent: 30
+  require-ack: true
+`
+	cfg, err = parseConfig(strings.NewReader(fileContent8))
+	if err != nil {
+		t.Fatal(err)
+	}
+	circuitBreaker := cfg.CircuitBreaker
+	if circuitBreaker == nil || circuitBreaker.MaxTransitions != 10 || circuitBreaker.MaxTransitionsPercent != 30 || !circuitBreaker.RequireAck {
+		t.Error("CircuitBreaker is not set", circuitBreaker)
+	}
+
+	fileContent9 := `
+machine-types:
+  - name: qemu
+circuit-breaker:
+  require-ack: true
+`
+	_, err = parseConfig(strings.NewReader(fileContent9))
+	if err == nil {
+		t.Error("circuit-breaker without limits was not rejected")
+	}
 }
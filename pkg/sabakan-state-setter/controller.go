@@ -25,6 +25,9 @@ type Controller struct {
 	etcdClient    *clientv3.Client
 	electionValue string
 	sessionTTL    time.Duration
+	storage       StateStorage
+	// leaderKey is set while this controller is the leader.
+	leaderKey string
 
 	// Clients
 	necoExecutor       NecoCmdExecutor
@@ -39,6 +42,19 @@ type Controller struct {
 	shutdownSchedule  string
 	machineTypes      map[string]*machineType
 	unhealthyMachines map[string]time.Time
+	// persistedUnhealthyMachines is the unhealthy registry stored in etcd.
+	// This is used to write only the differences.
+	persistedUnhealthyMachines map[string]time.Time
+	// recoveringMachines is the time when the machines are first judged as healthy after non-healthy states.
+	recoveringMachines map[string]time.Time
+	// flaps is the times when the machines have become non-healthy from healthy.
+	flaps            map[string][]time.Time
+	flappingMachines map[string]bool
+	circuitBreaker   *circuitBreaker
+	// breakerReason is the reason why the circuit breaker has been tripped and waits for acknowledgement.
+	breakerReason string
+	// tripped is true while the circuit breaker holds the transitions.
+	tripped bool
 }
 
 // RegisterUnhealthy registers unhealthy machine and returns true
@@ -62,9 +78,77 @@ func (c *Controller) ClearUnhealthy(m *machine) {
 	delete(c.unhealthyMachines, m.Serial)
 }
 
+// RegisterRecovering registers machine judged as healthy while it is unhealthy or unreachable,
+// and returns true if the machine has been healthy longer than the RecoveryGracePeriod
+// specified in its machine type.
+func (c *Controller) RegisterRecovering(m *machine, now time.Time) bool {
+	machineType, ok := c.machineTypes[m.Type]
+	if !ok || machineType.RecoveryGracePeriod.Duration == 0 {
+		return true
+	}
+	startTime, ok := c.recoveringMachines[m.Serial]
+	if !ok {
+		c.recoveringMachines[m.Serial] = now
+		return false
+	}
+	return startTime.Add(machineType.RecoveryGracePeriod.Duration).Before(now)
+}
+
+// ClearRecovering removes machine from recovering registry.
+func (c *Controller) ClearRecovering(m *machine) {
+	delete(c.recoveringMachines, m.Serial)
+}
+
+// RecordFlap records that machine has become non-healthy from healthy.
+func (c *Controller) RecordFlap(m *machine, now time.Time) {
+	machineType, ok := c.machineTypes[m.Type]
+	if !ok || machineType.FlapDetection == nil {
+		return
+	}
+	c.flaps[m.Serial] = append(c.flaps[m.Serial], now)
+}
+
+// IsFlapping returns true if machine has become non-healthy from healthy
+// as many times as the threshold within the window of its machine type.
+// Flaps older than the window are forgotten.
+func (c *Controller) IsFlapping(m *machine, now time.Time) bool {
+	machineType, ok := c.machineTypes[m.Type]
+	if !ok || machineType.FlapDetection == nil {
+		return false
+	}
+	flaps := c.flaps[m.Serial]
+	for len(flaps) > 0 && !flaps[0].After(now.Add(-machineType.FlapDetection.Window.Duration)) {
+		flaps = flaps[1:]
+	}
+	if len(flaps) == 0 {
+		delete(c.flaps, m.Serial)
+	} else {
+		c.flaps[m.Serial] = flaps
+	}
+	flapping := len(flaps) >= machineType.FlapDetection.Threshold
+
+	switch {
+	case flapping && !c.flappingMachines[m.Serial]:
+		c.flappingMachines[m.Serial] = true
+		log.Warn("machine is flapping; pinned to unhealthy", map[string]interface{}{
+			"serial": m.Serial,
+			"ipv4":   m.IPv4Addr,
+			"flaps":  len(flaps),
+			"window": machineType.FlapDetection.Window.String(),
+		})
+	case !flapping && c.flappingMachines[m.Serial]:
+		delete(c.flappingMachines, m.Serial)
+		log.Info("machine stopped flapping", map[string]interface{}{
+			"serial": m.Serial,
+			"ipv4":   m.IPv4Addr,
+		})
+	}
+	return flapping
+}
+
 // NewController returns controller for sabakan-state-setter
 func NewController(etcdClient *clientv3.Client, sabakanAddress, sabakanAddressHTTPS, serfAddress, configFile, electionValue string, interval time.Duration, parallelSize int, sessionTTL time.Duration) (*Controller, error) {
-	shutdownSchedule, machineTypes, alertMonitor, err := readConfigFile(configFile)
+	cfg, err := readConfigFile(configFile)
 	if err != nil {
 		return nil, err
 	}
@@ -79,7 +163,7 @@ func NewController(etcdClient *clientv3.Client, sabakanAddress, sabakanAddressHT
 		return nil, err
 	}
 
-	alertmanagerClient, err := newAlertmanagerClient(alertMonitor)
+	alertmanagerClient, err := newAlertmanagerClient(cfg.AlertMonitor)
 	if err != nil {
 		return nil, err
 	}
@@ -91,6 +175,7 @@ func NewController(etcdClient *clientv3.Client, sabakanAddress, sabakanAddressHT
 		etcdClient:    etcdClient,
 		electionValue: electionValue,
 		sessionTTL:    sessionTTL,
+		storage:       storage.NewStorage(etcdClient),
 
 		necoExecutor:       necoExecutor,
 		promClient:         promClient,
@@ -100,9 +185,15 @@ func NewController(etcdClient *clientv3.Client, sabakanAddress, sabakanAddressHT
 
 		interval:          interval,
 		parallelSize:      parallelSize,
-		shutdownSchedule:  shutdownSchedule,
-		machineTypes:      machineTypes,
+		shutdownSchedule:  cfg.ShutdownSchedule,
+		machineTypes:      cfg.machineTypeMap(),
 		unhealthyMachines: make(map[string]time.Time),
+
+		persistedUnhealthyMachines: make(map[string]time.Time),
+		recoveringMachines:         make(map[string]time.Time),
+		flaps:                      make(map[string][]time.Time),
+		flappingMachines:           make(map[string]bool),
+		circuitBreaker:             cfg.CircuitBreaker,
 	}, nil
 }
 
@@ -143,6 +234,8 @@ func (c *Controller) Run(ctx context.Context) error {
 	log.Info("I am the leader", map[string]interface{}{
 		"session": session.Lease(),
 	})
+	leaderGauge.Set(1)
+	defer leaderGauge.Set(0)
 	leaderKey := election.Key()
 
 	// Release the leader before terminating.
@@ -163,6 +256,33 @@ func (c *Controller) Run(ctx context.Context) error {
 		}
 	}()
 
+	// Continue the grace periods from where the previous leader left off.
+	unhealthyMachines, err := c.storage.GetUnhealthyMachines(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load unhealthy machines: %s", err.Error())
+	}
+	c.leaderKey = leaderKey
+	c.unhealthyMachines = unhealthyMachines
+	c.persistedUnhealthyMachines = make(map[string]time.Time, len(unhealthyMachines))
+	for serial, t := range unhealthyMachines {
+		c.persistedUnhealthyMachines[serial] = t
+	}
+	log.Info("loaded unhealthy machines", map[string]interface{}{
+		"count": len(unhealthyMachines),
+	})
+	if c.circuitBreaker != nil && c.circuitBreaker.RequireAck {
+		reason, err := c.storage.GetCircuitBreaker(ctx)
+		if err != nil && err != storage.ErrNotFound {
+			return fmt.Errorf("failed to load circuit breaker: %s", err.Error())
+		}
+		if reason != "" {
+			log.Warn("circuit breaker has been tripped", map[string]interface{}{
+				"reason": reason,
+			})
+		}
+		c.breakerReason = reason
+	}
+
 	if c.shutdownSchedule == "" {
 		log.Info("skip to start shutdown cron job", nil)
 	} else {
@@ -285,54 +405,162 @@ func (c *Controller) runOnce(ctx context.Context) error {
 	}
 
 	now := time.Now()
+	var transitions []*transition
 	for _, m := range machines {
 		newState, ok := newStateMap[m.Serial]
+		if ok && c.IsFlapping(m, now) && newState == sabakan.StateHealthy {
+			// Keep flapping machines unhealthy until the flaps get out of the window.
+			newState = stateUnhealthyImmediate
+		}
 		switch {
 		case !ok || newState == m.State || (newState == stateUnhealthyImmediate && m.State == sabakan.StateUnhealthy):
 			c.ClearUnhealthy(m)
+			c.ClearRecovering(m)
 			continue
 		case newState == stateUnhealthyImmediate:
 			c.ClearUnhealthy(m)
+			c.ClearRecovering(m)
 			newState = sabakan.StateUnhealthy
 		case newState == sabakan.StateUnhealthy:
+			c.ClearRecovering(m)
 			// Wait for the GracePeriod before changing the machine state to unhealthy.
 			if ok := c.RegisterUnhealthy(m, now); !ok {
 				continue
 			}
+		case newState == sabakan.StateHealthy && (m.State == sabakan.StateUnhealthy || m.State == sabakan.StateUnreachable):
+			c.ClearUnhealthy(m)
+			// Wait for the RecoveryGracePeriod before changing the machine state to healthy.
+			if ok := c.RegisterRecovering(m, now); !ok {
+				continue
+			}
 		default:
 			c.ClearUnhealthy(m)
+			c.ClearRecovering(m)
 		}
 
-		err := c.sabakanClient.UpdateSabakanState(ctx, m.Serial, newState)
-		if err != nil {
-			switch e := err.(type) {
-			case *gqlerror.Error:
-				// In the case of an invalid state transition, the log may continue to be output.
-				// So the log is not output.
-				if eType, ok := e.Extensions["type"]; ok && eType == gqlsabakan.ErrInvalidStateTransition {
-					continue
-				}
-				log.Warn("gql error occurred when set state", map[string]interface{}{
-					log.FnError: err.Error(),
-					"serial":    m.Serial,
-					"ipv4":      m.IPv4Addr,
-				})
-			default:
-				log.Warn("error occurred when set state", map[string]interface{}{
-					log.FnError: err.Error(),
-					"serial":    m.Serial,
-					"ipv4":      m.IPv4Addr,
-				})
+		transitions = append(transitions, &transition{machine: m, state: newState})
+	}
+
+	transitions, err = c.applyCircuitBreaker(ctx, machines, transitions)
+	if err != nil {
+		return err
+	}
+	for _, t := range transitions {
+		c.updateState(ctx, t.machine, t.state, now)
+	}
+
+	c.updateGauges(machines)
+	return c.saveUnhealthyMachines(ctx, machines)
+}
+
+// updateState updates the state of the machine in sabakan.
+func (c *Controller) updateState(ctx context.Context, m *machine, newState sabakan.MachineState, now time.Time) {
+	oldState := m.State
+	err := c.sabakanClient.UpdateSabakanState(ctx, m.Serial, newState)
+	if err != nil {
+		switch e := err.(type) {
+		case *gqlerror.Error:
+			// In the case of an invalid state transition, the log may continue to be output.
+			// So the log is not output.
+			if eType, ok := e.Extensions["type"]; ok && eType == gqlsabakan.ErrInvalidStateTransition {
+				return
 			}
-		} else {
-			log.Info("change state", map[string]interface{}{
-				"serial": m.Serial,
-				"ipv4":   m.IPv4Addr,
-				"state":  newState,
+			log.Warn("gql error occurred when set state", map[string]interface{}{
+				log.FnError: err.Error(),
+				"serial":    m.Serial,
+				"ipv4":      m.IPv4Addr,
+			})
+		default:
+			log.Warn("error occurred when set state", map[string]interface{}{
+				log.FnError: err.Error(),
+				"serial":    m.Serial,
+				"ipv4":      m.IPv4Addr,
 			})
 		}
+		return
+	}
+
+	log.Info("change state", map[string]interface{}{
+		"serial": m.Serial,
+		"ipv4":   m.IPv4Addr,
+		"state":  newState,
+	})
+	stateChangesTotal.WithLabelValues(m.Type, newState.String()).Inc()
+	if oldState == sabakan.StateHealthy && (newState == sabakan.StateUnhealthy || newState == sabakan.StateUnreachable) {
+		c.RecordFlap(m, now)
 	}
+}
 
+// updateGauges updates the gauges of the machines in the grace periods and the flapping machines.
+func (c *Controller) updateGauges(machines []*machine) {
+	gracePeriodMachinesGauge.Reset()
+	flappingMachinesGauge.Reset()
+	for _, m := range machines {
+		if _, ok := c.unhealthyMachines[m.Serial]; ok {
+			gracePeriodMachinesGauge.WithLabelValues(m.Type, sabakan.StateUnhealthy.String()).Inc()
+		}
+		if _, ok := c.recoveringMachines[m.Serial]; ok {
+			gracePeriodMachinesGauge.WithLabelValues(m.Type, sabakan.StateHealthy.String()).Inc()
+		}
+		if c.flappingMachines[m.Serial] {
+			flappingMachinesGauge.WithLabelValues(m.Type).Inc()
+		}
+	}
+}
+
+// saveUnhealthyMachines writes the changes of the unhealthy registry to etcd
+// so that the next leader can continue the grace periods.
+// The records of the machines which no longer exist in sabakan are removed.
+// Writes are skipped unless this controller is the leader.
+func (c *Controller) saveUnhealthyMachines(ctx context.Context, machines []*machine) error {
+	exists := make(map[string]bool, len(machines))
+	for _, m := range machines {
+		exists[m.Serial] = true
+	}
+	for serial := range c.unhealthyMachines {
+		if !exists[serial] {
+			delete(c.unhealthyMachines, serial)
+		}
+	}
+	if c.leaderKey == "" {
+		return nil
+	}
+
+	for serial, t := range c.unhealthyMachines {
+		if p, ok := c.persistedUnhealthyMachines[serial]; ok && p.Equal(t) {
+			continue
+		}
+		err := c.storage.PutUnhealthyMachine(ctx, c.leaderKey, serial, t)
+		if err == storage.ErrNoLeader {
+			return err
+		}
+		if err != nil {
+			// The record will be written in the next run.
+			log.Warn("failed to save unhealthy machine", map[string]interface{}{
+				log.FnError: err.Error(),
+				"serial":    serial,
+			})
+			continue
+		}
+		c.persistedUnhealthyMachines[serial] = t
+	}
+	for serial := range c.persistedUnhealthyMachines {
+		if _, ok := c.unhealthyMachines[serial]; ok {
+			continue
+		}
+		err := c.storage.DeleteUnhealthyMachine(ctx, c.leaderKey, serial)
+		if err == storage.ErrNoLeader {
+			return err
+		}
+		if err != nil {
+			log.Warn("failed to delete unhealthy machine", map[string]interface{}{
+				log.FnError: err.Error(),
+				"serial":    serial,
+			})
+			continue
+		}
+		delete(c.persistedUnhealthyMachines, serial)
+	}
 	return nil
 }
 
@@ -396,6 +624,11 @@ func (c *Controller) machineHealthCheck(ctx context.Context, machines []*machine
 		if newState == doNotChangeState {
 			continue
 		}
+		target := newState
+		if target == stateUnhealthyImmediate {
+			target = sabakan.StateUnhealthy
+		}
+		decisionsTotal.WithLabelValues(mss.machineTypeName, mss.reason, target.String()).Inc()
 		newStateMap[mss.serial] = newState
 	}
 	return newStateMap
@@ -411,6 +644,7 @@ func (c *Controller) machineRetire(ctx context.Context, machines []*machine) map
 			// Skip any state expect for StateRetiring.
 			continue
 		}
+		retirementAttemptsTotal.Inc()
 
 		err := c.sabakanClient.CryptsDelete(ctx, m.Serial)
 		if err != nil {
@@ -419,6 +653,7 @@ func (c *Controller) machineRetire(ctx context.Context, machines []*machine) map
 				"serial":    m.Serial,
 				"ipv4":      m.IPv4Addr,
 			})
+			retirementFailuresTotal.Inc()
 			continue
 		}
 
@@ -430,6 +665,7 @@ func (c *Controller) machineRetire(ctx context.Context, machines []*machine) map
 				"ipv4":      m.IPv4Addr,
 				"cmdlog":    string(cmdOutput),
 			})
+			retirementFailuresTotal.Inc()
 			continue
 		}
 
@@ -459,6 +695,7 @@ func (c *Controller) machineShutdown(ctx context.Context) {
 
 	var errorMachines []string
 	for _, m := range machines {
+		shutdownAttemptsTotal.Inc()
 		cmdOutput, err := c.necoExecutor.PowerStatus(ctx, m.Serial)
 		if err != nil {
 			log.Warn("shutdown; failed to get power status", map[string]interface{}{
@@ -468,6 +705,7 @@ func (c *Controller) machineShutdown(ctx context.Context) {
 				"cmdlog":    string(cmdOutput),
 			})
 			errorMachines = append(errorMachines, m.Serial)
+			shutdownFailuresTotal.Inc()
 			continue
 		}
 
@@ -490,6 +728,7 @@ func (c *Controller) machineShutdown(ctx context.Context) {
 				"cmdlog":    string(cmdOutput),
 			})
 			errorMachines = append(errorMachines, m.Serial)
+			shutdownFailuresTotal.Inc()
 			continue
 		}
 
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	sabakan "github.com/cybozu-go/sabakan/v3"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 )
 
 func newMockController(saba *sabakanMockClient, prom *promMockClient, serf *serfMockClient, alertmanager *alertmanagerClient, neco *necoCmdMockExecutor, mt ...*machineType) *Controller {
@@ -25,6 +26,11 @@ func newMockController(saba *sabakanMockClient, prom *promMockClient, serf *serf
 		necoExecutor:       neco,
 		machineTypes:       machineTypes,
 		unhealthyMachines:  make(map[string]time.Time),
+		recoveringMachines: make(map[string]time.Time),
+
+		persistedUnhealthyMachines: make(map[string]time.Time),
+		flaps:                      make(map[string][]time.Time),
+		flappingMachines:           make(map[string]bool),
 	}
 }
 
@@ -443,6 +449,265 @@ func testControllerUnhealthy(t *testing.T) {
 	if exceeded {
 		t.Error("machine is misjudged as long-term unhealthy by confusion")
 	}
+
+	err := ctr.saveUnhealthyMachines(context.Background(), []*machine{m2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := ctr.unhealthyMachines[m1.Serial]; ok {
+		t.Error("machine not found in sabakan is not removed from registry")
+	}
+	if _, ok := ctr.unhealthyMachines[m2.Serial]; !ok {
+		t.Error("machine is removed from registry unexpectedly")
+	}
+}
+
+func testControllerRecovery(t *testing.T) {
+	t.Parallel()
+
+	mt := &machineType{
+		Name:                "type1",
+		GracePeriod:         duration{Duration: time.Millisecond},
+		RecoveryGracePeriod: duration{Duration: time.Hour},
+	}
+	machines := []*machine{
+		{
+			Serial:   "00000000",
+			Type:     "type1",
+			IPv4Addr: "10.0.0.100",
+			State:    sabakan.StateUnreachable,
+		},
+	}
+	sabaMock := newMockSabakanClient(machines)
+	promMock := newMockPromClient(map[string]string{})
+	serfMock, _ := newMockSerfClient(map[string]*serfStatus{
+		"10.0.0.100": {Status: "alive", SystemdUnitsFailed: strPtr("")},
+	})
+	necoMock := newMockNecoCmdExecutor()
+	ctr := newMockController(sabaMock, promMock, serfMock, nil, necoMock, mt)
+
+	for i := 0; i < 2; i++ {
+		err := ctr.runOnce(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if state := sabaMock.getState("00000000"); state != sabakan.StateUnreachable {
+			t.Fatal("machine recovered during recovery grace period", state)
+		}
+	}
+
+	ctr.recoveringMachines["00000000"] = time.Now().Add(-2 * time.Hour)
+	err := ctr.runOnce(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state := sabaMock.getState("00000000"); state != sabakan.StateHealthy {
+		t.Error("machine did not recover after recovery grace period", state)
+	}
+}
+
+func testControllerFlapping(t *testing.T) {
+	t.Parallel()
+
+	mt := &machineType{
+		Name:          "type1",
+		GracePeriod:   duration{Duration: time.Millisecond},
+		FlapDetection: &flapDetection{Threshold: 2, Window: duration{Duration: time.Hour}},
+	}
+	machines := []*machine{
+		{
+			Serial:   "00000000",
+			Type:     "type1",
+			IPv4Addr: "10.0.0.100",
+			State:    sabakan.StateHealthy,
+		},
+	}
+	sabaMock := newMockSabakanClient(machines)
+	promMock := newMockPromClient(map[string]string{})
+	serfMock, _ := newMockSerfClient(map[string]*serfStatus{})
+	necoMock := newMockNecoCmdExecutor()
+	ctr := newMockController(sabaMock, promMock, serfMock, nil, necoMock, mt)
+
+	steps := []struct {
+		serf     string
+		expected sabakan.MachineState
+	}{
+		{"failed", sabakan.StateUnreachable},
+		{"alive", sabakan.StateHealthy},
+		{"failed", sabakan.StateUnreachable},
+		// flapped twice within the window
+		{"alive", sabakan.StateUnhealthy},
+		{"alive", sabakan.StateUnhealthy},
+		{"failed", sabakan.StateUnreachable},
+		{"alive", sabakan.StateUnhealthy},
+	}
+	for i, step := range steps {
+		serfMock.status["10.0.0.100"] = &serfStatus{Status: step.serf, SystemdUnitsFailed: strPtr("")}
+		err := ctr.runOnce(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if state := sabaMock.getState("00000000"); state != step.expected {
+			t.Fatalf("step %d: expected %s, actual %s", i, step.expected, state)
+		}
+	}
+	if !ctr.flappingMachines["00000000"] {
+		t.Error("machine is not registered as flapping")
+	}
+
+	// the flaps get out of the window.
+	for i := range ctr.flaps["00000000"] {
+		ctr.flaps["00000000"][i] = ctr.flaps["00000000"][i].Add(-2 * time.Hour)
+	}
+	err := ctr.runOnce(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state := sabaMock.getState("00000000"); state != sabakan.StateHealthy {
+		t.Error("machine is still pinned after the window", state)
+	}
+	if ctr.flappingMachines["00000000"] {
+		t.Error("machine is still registered as flapping")
+	}
+}
+
+func testControllerCircuitBreaker(t *testing.T) {
+	t.Parallel()
+
+	mt := &machineType{
+		Name:        "type1",
+		GracePeriod: duration{Duration: time.Millisecond},
+	}
+	newMachines := func() []*machine {
+		return []*machine{
+			{Serial: "00000001", Type: "type1", IPv4Addr: "10.0.0.1", State: sabakan.StateHealthy},
+			{Serial: "00000002", Type: "type1", IPv4Addr: "10.0.0.2", State: sabakan.StateHealthy},
+			{Serial: "00000003", Type: "type1", IPv4Addr: "10.0.0.3", State: sabakan.StateHealthy},
+			{Serial: "00000004", Type: "type1", IPv4Addr: "10.0.0.4", State: sabakan.StateHealthy},
+			{Serial: "00000005", Type: "type1", IPv4Addr: "10.0.0.5", State: sabakan.StateUnreachable},
+		}
+	}
+	// 10.0.0.1, 10.0.0.2 and 10.0.0.3 are not serf members.
+	newSerfStatus := func() map[string]*serfStatus {
+		return map[string]*serfStatus{
+			"10.0.0.4": {Status: "alive", SystemdUnitsFailed: strPtr("")},
+			"10.0.0.5": {Status: "alive", SystemdUnitsFailed: strPtr("")},
+		}
+	}
+	expectStates := func(saba *sabakanMockClient, state sabakan.MachineState) {
+		t.Helper()
+		for _, serial := range []string{"00000001", "00000002", "00000003"} {
+			if actual := saba.getState(serial); actual != state {
+				t.Fatalf("expected %s for %s, actual %s", state, serial, actual)
+			}
+		}
+		// transitions to healthy are not held.
+		if actual := saba.getState("00000005"); actual != sabakan.StateHealthy {
+			t.Fatal("machine did not recover", actual)
+		}
+	}
+
+	sabaMock := newMockSabakanClient(newMachines())
+	serfMock, _ := newMockSerfClient(newSerfStatus())
+	ctr := newMockController(sabaMock, newMockPromClient(map[string]string{}), serfMock, nil, newMockNecoCmdExecutor(), mt)
+
+	trips := testutil.ToFloat64(circuitBreakerTripsTotal)
+	ctr.circuitBreaker = &circuitBreaker{MaxTransitions: 2}
+	err := ctr.runOnce(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectStates(sabaMock, sabakan.StateHealthy)
+
+	// 3 of 5 machines is 60%.
+	ctr.circuitBreaker = &circuitBreaker{MaxTransitionsPercent: 50}
+	err = ctr.runOnce(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectStates(sabaMock, sabakan.StateHealthy)
+	// the circuit breaker kept tripped is counted once.
+	if n := testutil.ToFloat64(circuitBreakerTripsTotal) - trips; n != 1 {
+		t.Error("unexpected number of trips", n)
+	}
+
+	ctr.circuitBreaker = &circuitBreaker{MaxTransitions: 3, MaxTransitionsPercent: 60}
+	err = ctr.runOnce(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectStates(sabaMock, sabakan.StateUnreachable)
+	if ctr.tripped {
+		t.Error("circuit breaker is not reset")
+	}
+
+	// with require-ack, the transitions are held until acknowledged.
+	sabaMock = newMockSabakanClient(newMachines())
+	serfMock, _ = newMockSerfClient(newSerfStatus())
+	ctr = newMockController(sabaMock, newMockPromClient(map[string]string{}), serfMock, nil, newMockNecoCmdExecutor(), mt)
+	ctr.circuitBreaker = &circuitBreaker{MaxTransitions: 2, RequireAck: true}
+	err = ctr.runOnce(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectStates(sabaMock, sabakan.StateHealthy)
+	if ctr.breakerReason == "" {
+		t.Fatal("circuit breaker is not tripped")
+	}
+
+	serfMock.status["10.0.0.2"] = &serfStatus{Status: "alive", SystemdUnitsFailed: strPtr("")}
+	serfMock.status["10.0.0.3"] = &serfStatus{Status: "alive", SystemdUnitsFailed: strPtr("")}
+	err = ctr.runOnce(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state := sabaMock.getState("00000001"); state != sabakan.StateHealthy {
+		t.Error("transition is applied without acknowledgement", state)
+	}
+
+	// the circuit breaker is kept tripped only after it is stored in etcd.
+	sabaMock = newMockSabakanClient(newMachines())
+	serfMock, _ = newMockSerfClient(newSerfStatus())
+	st := newMockStateStorage()
+	st.tripErr = errors.New("etcd is unavailable")
+	ctr = newMockController(sabaMock, newMockPromClient(map[string]string{}), serfMock, nil, newMockNecoCmdExecutor(), mt)
+	ctr.storage = st
+	ctr.leaderKey = "leader"
+	ctr.circuitBreaker = &circuitBreaker{MaxTransitions: 2, RequireAck: true}
+	err = ctr.runOnce(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectStates(sabaMock, sabakan.StateHealthy)
+	if ctr.breakerReason != "" {
+		t.Error("circuit breaker is tripped without being stored", ctr.breakerReason)
+	}
+	if err := st.AckCircuitBreaker(context.Background()); err == nil {
+		t.Error("circuit breaker is acknowledged without being stored")
+	}
+
+	st.tripErr = nil
+	err = ctr.runOnce(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectStates(sabaMock, sabakan.StateHealthy)
+	if ctr.breakerReason == "" || st.breakerReason != ctr.breakerReason {
+		t.Fatal("circuit breaker is not stored", ctr.breakerReason, st.breakerReason)
+	}
+
+	err = st.AckCircuitBreaker(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ctr.runOnce(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectStates(sabaMock, sabakan.StateUnreachable)
+	if ctr.breakerReason != "" || st.breakerReason != "" || st.breakerAcked {
+		t.Error("circuit breaker is not reset", ctr.breakerReason, st.breakerReason, st.breakerAcked)
+	}
 }
 
 func testControllerRetire(t *testing.T) {
@@ -582,6 +847,9 @@ func TestController(t *testing.T) {
 	t.Run("RunSerfError", testControllerRunSerfError)
 	t.Run("RunAlertmanagerError", testControllerRunAlertmanagerError)
 	t.Run("Unhealthy", testControllerUnhealthy)
+	t.Run("Recovery", testControllerRecovery)
+	t.Run("Flapping", testControllerFlapping)
+	t.Run("CircuitBreaker", testControllerCircuitBreaker)
 	t.Run("Retire", testControllerRetire)
 	t.Run("Shutdown", testControllerShutdown)
 }
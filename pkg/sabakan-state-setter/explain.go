@@ -0,0 +1,165 @@
+package sss
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/cybozu-go/sabakan/v3"
+)
+
+// Explanation describes how sabakan-state-setter decides the state of a machine.
+type Explanation struct {
+	Serial       string               `json:"serial"`
+	IPv4         string               `json:"ipv4"`
+	MachineType  string               `json:"machine_type"`
+	CurrentState sabakan.MachineState `json:"current_state"`
+
+	// Sources of the decision.
+	SerfStatus         string               `json:"serf_status,omitempty"`
+	SystemdUnitsFailed *string              `json:"systemd_units_failed,omitempty"`
+	Alert              string               `json:"alert,omitempty"`
+	AlertState         sabakan.MachineState `json:"alert_state,omitempty"`
+	Metrics            []*metricCheck       `json:"metrics,omitempty"`
+
+	// Candidate is the state decided by the health check.
+	// This is empty if the state is not changed by the health check.
+	Candidate sabakan.MachineState `json:"candidate,omitempty"`
+	// Reason is the reason of the candidate.
+	Reason string `json:"reason,omitempty"`
+	// UnhealthySince is the time when the leader first judged the machine as unhealthy.
+	UnhealthySince *time.Time `json:"unhealthy_since,omitempty"`
+	// NextState is the state which would be set to sabakan now.
+	// This is empty if the state would not be changed.
+	NextState sabakan.MachineState `json:"next_state,omitempty"`
+	Message   string               `json:"message"`
+}
+
+// Explain runs the health check of a machine in the same way as the controller,
+// and returns the explanation of the state to be set.  This does not update sabakan.
+func (c *Controller) Explain(ctx context.Context, serial string) (*Explanation, error) {
+	unhealthyMachines, err := c.storage.GetUnhealthyMachines(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load unhealthy machines: %w", err)
+	}
+	return c.explain(ctx, serial, unhealthyMachines, time.Now())
+}
+
+func (c *Controller) explain(ctx context.Context, serial string, unhealthyMachines map[string]time.Time, now time.Time) (*Explanation, error) {
+	machines, err := c.sabakanClient.GetAllMachines(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sabakan machines: %w", err)
+	}
+	var m *machine
+	for _, mm := range machines {
+		if mm.Serial == serial {
+			m = mm
+			break
+		}
+	}
+	if m == nil {
+		return nil, fmt.Errorf("machine %s is not found", serial)
+	}
+
+	exp := &Explanation{
+		Serial:       m.Serial,
+		IPv4:         m.IPv4Addr,
+		MachineType:  m.Type,
+		CurrentState: m.State,
+	}
+
+	switch m.State {
+	case sabakan.StateUninitialized, sabakan.StateHealthy, sabakan.StateUnhealthy, sabakan.StateUnreachable:
+	case sabakan.StateRetiring:
+		exp.NextState = sabakan.StateRetired
+		exp.Message = "health check is skipped; the encryption keys will be deleted and the machine will be retired"
+		return exp, nil
+	default:
+		exp.Message = fmt.Sprintf("health check is skipped in %s state", m.State)
+		return exp, nil
+	}
+
+	serfStatuses, err := c.serfClient.GetSerfStatus()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get serf members: %w", err)
+	}
+	alertStatuses, err := c.alertmanagerClient.GetAlertStatuses(machines)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get alert statuses: %w", err)
+	}
+
+	mss := newMachineStateSource(m, serfStatuses, alertStatuses, c.machineTypes)
+	if mss.machineType != nil && len(mss.machineType.MetricsCheckList) != 0 {
+		mfs, err := c.promClient.ConnectMetricsServer(ctx, mss.ipv4)
+		if err != nil {
+			// The controller regards the machine as unhealthy in this case.
+			exp.Message = fmt.Sprintf("failed to get metrics: %s; ", err)
+		} else {
+			mss.metrics = mfs
+		}
+	}
+	candidate := mss.decideMachineStateCandidate()
+
+	if mss.serfStatus != nil {
+		exp.SerfStatus = mss.serfStatus.Status
+		exp.SystemdUnitsFailed = mss.serfStatus.SystemdUnitsFailed
+	}
+	if mss.alertStatus != nil {
+		exp.Alert = mss.alertStatus.AlertName
+		exp.AlertState = mss.alertStatus.State
+	}
+	exp.Metrics = mss.checks
+	exp.Reason = mss.reason
+	if since, ok := unhealthyMachines[m.Serial]; ok {
+		exp.UnhealthySince = &since
+	}
+
+	// Keep this consistent with runOnce.
+	exp.Candidate = candidate
+	switch {
+	case candidate == doNotChangeState:
+		exp.Candidate = ""
+		exp.Message += "the state is not changed"
+	case candidate == stateUnhealthyImmediate:
+		exp.Candidate = sabakan.StateUnhealthy
+		if m.State == sabakan.StateUnhealthy {
+			exp.Message += "the machine is already unhealthy"
+			break
+		}
+		exp.NextState = sabakan.StateUnhealthy
+		exp.Message += "the machine will be unhealthy immediately"
+	case candidate == m.State:
+		exp.Message += fmt.Sprintf("the machine is already %s", m.State)
+	case candidate == sabakan.StateUnhealthy:
+		gracePeriod := time.Duration(0)
+		if mt, ok := c.machineTypes[m.Type]; ok {
+			gracePeriod = mt.GracePeriod.Duration
+		}
+		if exp.UnhealthySince == nil {
+			exp.Message += fmt.Sprintf("the grace period of %s will start", gracePeriod)
+			break
+		}
+		end := exp.UnhealthySince.Add(gracePeriod)
+		if !end.Before(now) {
+			exp.Message += fmt.Sprintf("the machine will be unhealthy after the grace period ends at %s", end.Format(time.RFC3339))
+			break
+		}
+		exp.NextState = sabakan.StateUnhealthy
+		exp.Message += "the grace period has passed; the machine will be unhealthy"
+	case candidate == sabakan.StateHealthy && (m.State == sabakan.StateUnhealthy || m.State == sabakan.StateUnreachable):
+		if mt, ok := c.machineTypes[m.Type]; ok && mt.RecoveryGracePeriod.Duration != 0 {
+			// The recovering machines are kept only in the memory of the leader.
+			exp.Message += fmt.Sprintf("the machine will be healthy after the recovery grace period of %s", mt.RecoveryGracePeriod.Duration)
+			break
+		}
+		exp.NextState = sabakan.StateHealthy
+		exp.Message += "the machine will be healthy"
+	default:
+		exp.NextState = candidate
+		exp.Message += fmt.Sprintf("the machine will be %s", candidate)
+	}
+	if mt, ok := c.machineTypes[m.Type]; ok && mt.FlapDetection != nil && candidate == sabakan.StateHealthy {
+		exp.Message += "; unless the machine is pinned to unhealthy by flap detection"
+	}
+	return exp, nil
+}
@@ -0,0 +1,139 @@
+package sss
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	sabakan "github.com/cybozu-go/sabakan/v3"
+)
+
+func TestExplain(t *testing.T) {
+	t.Parallel()
+
+	mt := &machineType{
+		Name:        "explain",
+		GracePeriod: duration{Duration: time.Hour},
+		MetricsCheckList: []targetMetric{
+			{
+				Name: "hw_processor_status_health",
+			},
+		},
+	}
+	machines := []*machine{
+		{
+			Serial:   "00000001",
+			Type:     "explain",
+			IPv4Addr: "10.0.0.1",
+			State:    sabakan.StateHealthy,
+		},
+		{
+			Serial:   "00000002",
+			Type:     "explain",
+			IPv4Addr: "10.0.0.2",
+			State:    sabakan.StateHealthy,
+		},
+		{
+			Serial:   "00000003",
+			Type:     "explain",
+			IPv4Addr: "10.0.0.3",
+			State:    sabakan.StateUnreachable,
+		},
+		{
+			Serial:   "00000004",
+			Type:     "explain",
+			IPv4Addr: "10.0.0.4",
+			State:    sabakan.StateRetired,
+		},
+	}
+	metrics := `
+# TYPE hw_processor_status_health gauge
+hw_processor_status_health{processor="CPU.Socket.1"} 0
+hw_processor_status_health{processor="CPU.Socket.2"} 2
+`
+	healthyMetrics := `
+# TYPE hw_processor_status_health gauge
+hw_processor_status_health{processor="CPU.Socket.1"} 0
+`
+	sabaMock := newMockSabakanClient(machines)
+	promMock := newMockPromClient(map[string]string{
+		"10.0.0.1": metrics,
+		"10.0.0.2": metrics,
+		"10.0.0.3": healthyMetrics,
+	})
+	serfMock, _ := newMockSerfClient(map[string]*serfStatus{
+		"10.0.0.1": {Status: "alive", SystemdUnitsFailed: strPtr("")},
+		"10.0.0.2": {Status: "alive", SystemdUnitsFailed: strPtr("")},
+		"10.0.0.3": {Status: "alive", SystemdUnitsFailed: strPtr("")},
+	})
+	necoMock := newMockNecoCmdExecutor()
+	ctr := newMockController(sabaMock, promMock, serfMock, nil, necoMock, mt)
+
+	now := time.Now()
+	unhealthyMachines := map[string]time.Time{
+		"00000002": now.Add(-2 * time.Hour),
+	}
+
+	exp, err := ctr.explain(context.Background(), "00000001", unhealthyMachines, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp.Candidate != sabakan.StateUnhealthy || exp.Reason != reasonMetricNotHealthy {
+		t.Error("unexpected candidate", exp.Candidate, exp.Reason)
+	}
+	if exp.NextState != "" || exp.UnhealthySince != nil || !strings.Contains(exp.Message, "grace period") {
+		t.Error("machine should wait for the grace period", exp.NextState, exp.Message)
+	}
+	if len(exp.Metrics) != 1 {
+		t.Fatal("unexpected metric checks", exp.Metrics)
+	}
+	check := exp.Metrics[0]
+	if !check.Found || check.Matched != 2 || check.HealthyCount != 1 || check.State != sabakan.StateUnhealthy {
+		t.Error("unexpected metric check", check)
+	}
+	if len(check.Unhealthy) != 1 || check.Unhealthy[0].Labels["processor"] != "CPU.Socket.2" || check.Unhealthy[0].Value != 2 {
+		t.Error("unexpected unhealthy metrics", check.Unhealthy)
+	}
+
+	exp, err = ctr.explain(context.Background(), "00000002", unhealthyMachines, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp.NextState != sabakan.StateUnhealthy || exp.UnhealthySince == nil {
+		t.Error("machine should be unhealthy after the grace period", exp.NextState, exp.Message)
+	}
+
+	exp, err = ctr.explain(context.Background(), "00000003", unhealthyMachines, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp.Candidate != sabakan.StateHealthy || exp.Reason != reasonHealthy || exp.NextState != sabakan.StateHealthy {
+		t.Error("machine should recover", exp.Candidate, exp.Reason, exp.NextState)
+	}
+
+	exp, err = ctr.explain(context.Background(), "00000004", unhealthyMachines, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp.Candidate != "" || exp.NextState != "" || !strings.Contains(exp.Message, "skipped") {
+		t.Error("health check should be skipped", exp)
+	}
+
+	_, err = ctr.explain(context.Background(), "99999999", unhealthyMachines, now)
+	if err == nil {
+		t.Error("unknown serial should be an error")
+	}
+
+	expected := map[string]sabakan.MachineState{
+		"00000001": sabakan.StateHealthy,
+		"00000002": sabakan.StateHealthy,
+		"00000003": sabakan.StateUnreachable,
+		"00000004": sabakan.StateRetired,
+	}
+	for serial, state := range expected {
+		if actual := sabaMock.getState(serial); actual != state {
+			t.Error("explain should not update the state", serial, actual)
+		}
+	}
+}
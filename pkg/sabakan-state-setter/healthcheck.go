@@ -18,10 +18,32 @@ const (
 const doNotChangeState = sabakan.MachineState("")
 const stateUnhealthyImmediate = sabakan.MachineState("unhealthy_immediate")
 
+// Reasons of the machine state candidates.
+// These are used as the label values of the metrics.
+const (
+	reasonSerfStatusNil       = "serf_status_nil"
+	reasonSerfNotAlive        = "serf_not_alive"
+	reasonAlertFiring         = "alert_firing"
+	reasonSystemdUnitsFailed  = "systemd_units_failed"
+	reasonStartingUp          = "starting_up"
+	reasonUnknownMachineType  = "unknown_machine_type"
+	reasonMetricsUnavailable  = "metrics_unavailable"
+	reasonMetricNotFound      = "metric_not_found"
+	reasonMetricNotHealthy    = "metric_not_healthy"
+	reasonMinimumHealthyCount = "minimum_healthy_count"
+	reasonHealthy             = "healthy"
+)
+
 // machineStateSource is a struct of machine state collection
 type machineStateSource struct {
 	serial string
 	ipv4   string
+	// machineTypeName is the name of the machine type even if it is unknown.
+	machineTypeName string
+	// reason is the reason of the last decided state candidate.
+	reason string
+	// checks is the results of checking the target metrics.
+	checks []*metricCheck
 
 	serfStatus  *serfStatus
 	alertStatus *alertStatus
@@ -29,13 +51,31 @@ type machineStateSource struct {
 	metrics     map[string]*dto.MetricFamily
 }
 
+// metricCheck is the result of checking a target metric of a machine.
+type metricCheck struct {
+	Name                string               `json:"name"`
+	Selector            *selector            `json:"selector,omitempty"`
+	MinimumHealthyCount *int                 `json:"minimum_healthy_count,omitempty"`
+	Found               bool                 `json:"found"`
+	Matched             int                  `json:"matched"`
+	HealthyCount        int                  `json:"healthy_count"`
+	Unhealthy           []unhealthyMetric    `json:"unhealthy,omitempty"`
+	State               sabakan.MachineState `json:"state"`
+}
+
+type unhealthyMetric struct {
+	Labels map[string]string `json:"labels"`
+	Value  float64           `json:"value"`
+}
+
 func newMachineStateSource(m *machine, serfStatuses map[string]*serfStatus, alertStatuses map[string]*alertStatus, machineTypes map[string]*machineType) *machineStateSource {
 	return &machineStateSource{
-		serial:      m.Serial,
-		ipv4:        m.IPv4Addr,
-		serfStatus:  serfStatuses[m.IPv4Addr],
-		alertStatus: alertStatuses[m.IPv4Addr],
-		machineType: machineTypes[m.Type],
+		serial:          m.Serial,
+		ipv4:            m.IPv4Addr,
+		machineTypeName: m.Type,
+		serfStatus:      serfStatuses[m.IPv4Addr],
+		alertStatus:     alertStatuses[m.IPv4Addr],
+		machineType:     machineTypes[m.Type],
 	}
 }
 
@@ -47,6 +87,7 @@ func (mss *machineStateSource) decideMachineStateCandidate() sabakan.MachineStat
 			"serial": mss.serial,
 			"ipv4":   mss.ipv4,
 		})
+		mss.reason = reasonSerfStatusNil
 		return sabakan.StateUnreachable
 	}
 
@@ -56,6 +97,7 @@ func (mss *machineStateSource) decideMachineStateCandidate() sabakan.MachineStat
 			"ipv4":   mss.ipv4,
 			"status": mss.serfStatus.Status,
 		})
+		mss.reason = reasonSerfNotAlive
 		return sabakan.StateUnreachable
 	}
 
@@ -67,6 +109,7 @@ func (mss *machineStateSource) decideMachineStateCandidate() sabakan.MachineStat
 			"ipv4":   mss.ipv4,
 			"alert":  mss.alertStatus.AlertName,
 		})
+		mss.reason = reasonAlertFiring
 		if mss.alertStatus.State == sabakan.StateUnhealthy {
 			return stateUnhealthyImmediate
 		}
@@ -79,6 +122,7 @@ func (mss *machineStateSource) decideMachineStateCandidate() sabakan.MachineStat
 			"ipv4":   mss.ipv4,
 			"failed": *mss.serfStatus.SystemdUnitsFailed,
 		})
+		mss.reason = reasonSystemdUnitsFailed
 		return sabakan.StateUnhealthy
 	}
 
@@ -90,6 +134,7 @@ func (mss *machineStateSource) decideMachineStateCandidate() sabakan.MachineStat
 	if mss.serfStatus.SystemdUnitsFailed == nil {
 		// Do nothing if there is no systemd-units-failed tag and no hardware failure.
 		// In this case, the machine is starting up.
+		mss.reason = reasonStartingUp
 		return doNotChangeState
 	}
 
@@ -102,10 +147,12 @@ func (mss *machineStateSource) decideByMonitorHW() sabakan.MachineState {
 			"serial": mss.serial,
 			"ipv4":   mss.ipv4,
 		})
+		mss.reason = reasonUnknownMachineType
 		return sabakan.StateUnhealthy
 	}
 
 	if len(mss.machineType.MetricsCheckList) == 0 {
+		mss.reason = reasonHealthy
 		return sabakan.StateHealthy
 	}
 
@@ -114,6 +161,7 @@ func (mss *machineStateSource) decideByMonitorHW() sabakan.MachineState {
 			"serial": mss.serial,
 			"ipv4":   mss.ipv4,
 		})
+		mss.reason = reasonMetricsUnavailable
 		return sabakan.StateUnhealthy
 	}
 
@@ -125,10 +173,18 @@ func (mss *machineStateSource) decideByMonitorHW() sabakan.MachineState {
 				"ipv4":   mss.ipv4,
 				"target": checkTarget.Name,
 			})
+			mss.reason = reasonMetricNotFound
+			mss.checks = append(mss.checks, &metricCheck{
+				Name:                checkTarget.Name,
+				Selector:            checkTarget.Selector,
+				MinimumHealthyCount: checkTarget.MinimumHealthyCount,
+				State:               sabakan.StateUnhealthy,
+			})
 			return sabakan.StateUnhealthy
 		}
 
 		res := mss.checkTarget(checkTarget)
+		mss.checks[len(mss.checks)-1].State = res
 		if res != sabakan.StateHealthy {
 			return res
 		}
@@ -140,6 +196,14 @@ func (mss *machineStateSource) decideByMonitorHW() sabakan.MachineState {
 func (mss *machineStateSource) checkTarget(target targetMetric) sabakan.MachineState {
 	mf := mss.metrics[target.Name]
 	matched := target.Selector.Match(mf)
+	check := &metricCheck{
+		Name:                target.Name,
+		Selector:            target.Selector,
+		MinimumHealthyCount: target.MinimumHealthyCount,
+		Found:               true,
+		Matched:             len(matched),
+	}
+	mss.checks = append(mss.checks, check)
 	if len(matched) == 0 {
 		log.Info("unhealthy; metric with specified labels does not exist", map[string]interface{}{
 			"serial":   mss.serial,
@@ -147,6 +211,7 @@ func (mss *machineStateSource) checkTarget(target targetMetric) sabakan.MachineS
 			"name":     target.Name,
 			"selector": target.Selector,
 		})
+		mss.reason = reasonMetricNotFound
 		return sabakan.StateUnhealthy
 	}
 
@@ -160,6 +225,11 @@ func (mss *machineStateSource) checkTarget(target targetMetric) sabakan.MachineS
 			healthyCount++
 			continue
 		}
+		labels := make(map[string]string, len(m.Label))
+		for _, l := range m.Label {
+			labels[l.GetName()] = l.GetValue()
+		}
+		check.Unhealthy = append(check.Unhealthy, unhealthyMetric{Labels: labels, Value: gauge.GetValue()})
 		log.Info("unhealthy; metric is not healthy", map[string]interface{}{
 			"serial": mss.serial,
 			"ipv4":   mss.ipv4,
@@ -169,6 +239,8 @@ func (mss *machineStateSource) checkTarget(target targetMetric) sabakan.MachineS
 		})
 	}
 
+	check.HealthyCount = healthyCount
+
 	if target.MinimumHealthyCount == nil {
 		if healthyCount != len(matched) {
 			log.Info("unhealthy; one or more metric is not healthy", map[string]interface{}{
@@ -179,9 +251,11 @@ func (mss *machineStateSource) checkTarget(target targetMetric) sabakan.MachineS
 				"num_metrics":   len(matched),
 				"healthy_count": healthyCount,
 			})
+			mss.reason = reasonMetricNotHealthy
 			return sabakan.StateUnhealthy
 		}
 
+		mss.reason = reasonHealthy
 		log.Info("healthy;", map[string]interface{}{
 			"serial":   mss.serial,
 			"ipv4":     mss.ipv4,
@@ -201,9 +275,11 @@ func (mss *machineStateSource) checkTarget(target targetMetric) sabakan.MachineS
 			"minimum_healthy_count": minCount,
 			"healthy_count":         healthyCount,
 		})
+		mss.reason = reasonMinimumHealthyCount
 		return sabakan.StateUnhealthy
 	}
 
+	mss.reason = reasonHealthy
 	log.Info("healthy;", map[string]interface{}{
 		"serial":   mss.serial,
 		"ipv4":     mss.ipv4,
@@ -0,0 +1,135 @@
+package sss
+
+import (
+	"net/http"
+
+	"github.com/cybozu-go/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+type logger struct{}
+
+func (l logger) Println(v ...interface{}) {
+	log.Error("metrics error", map[string]interface{}{
+		"message": v,
+	})
+}
+
+const metricsNamespace = "sabakan_state_setter"
+
+// Metrics updated by Controller.
+var (
+	leaderGauge = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "leader",
+			Help:      "1 if this process is the leader of sabakan-state-setter.",
+		},
+	)
+	decisionsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "decisions_total",
+			Help:      "The number of machine state candidates decided by health checks.",
+		},
+		[]string{"machine_type", "reason", "state"},
+	)
+	stateChangesTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "state_changes_total",
+			Help:      "The number of machine state changes made by sabakan-state-setter.",
+		},
+		[]string{"machine_type", "state"},
+	)
+	gracePeriodMachinesGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "grace_period_machines",
+			Help:      "The number of machines waiting for the grace period before being set to the state.",
+		},
+		[]string{"machine_type", "state"},
+	)
+	flappingMachinesGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "flapping_machines",
+			Help:      "The number of machines pinned to unhealthy due to flapping.",
+		},
+		[]string{"machine_type"},
+	)
+	circuitBreakerTrippedGauge = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "circuit_breaker_tripped",
+			Help:      "1 if the circuit breaker is tripped and holding state transitions.",
+		},
+	)
+	circuitBreakerHeldGauge = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "circuit_breaker_held_transitions",
+			Help:      "The number of state transitions held by the circuit breaker in the last cycle.",
+		},
+	)
+	circuitBreakerTripsTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "circuit_breaker_trips_total",
+			Help:      "The number of times the circuit breaker has been tripped.",
+		},
+	)
+	retirementAttemptsTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "retirement_attempts_total",
+			Help:      "The number of attempts to retire machines.",
+		},
+	)
+	retirementFailuresTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "retirement_failures_total",
+			Help:      "The number of failed attempts to retire machines.",
+		},
+	)
+	shutdownAttemptsTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "shutdown_attempts_total",
+			Help:      "The number of attempts to shut down retired machines.",
+		},
+	)
+	shutdownFailuresTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "shutdown_failures_total",
+			Help:      "The number of failed attempts to shut down retired machines.",
+		},
+	)
+)
+
+// GetMetricsHandler returns a http.Handler to serve metrics of sabakan-state-setter.
+func GetMetricsHandler() http.Handler {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(
+		leaderGauge,
+		decisionsTotal,
+		stateChangesTotal,
+		gracePeriodMachinesGauge,
+		flappingMachinesGauge,
+		circuitBreakerTrippedGauge,
+		circuitBreakerHeldGauge,
+		circuitBreakerTripsTotal,
+		retirementAttemptsTotal,
+		retirementFailuresTotal,
+		shutdownAttemptsTotal,
+		shutdownFailuresTotal,
+	)
+	return promhttp.HandlerFor(registry,
+		promhttp.HandlerOpts{
+			ErrorLog:      logger{},
+			ErrorHandling: promhttp.ContinueOnError,
+		})
+}
@@ -0,0 +1,66 @@
+package sss
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	sabakan "github.com/cybozu-go/sabakan/v3"
+)
+
+func TestMetrics(t *testing.T) {
+	mt := &machineType{
+		Name:        "metrics-test",
+		GracePeriod: duration{Duration: time.Hour},
+	}
+	machines := []*machine{
+		{
+			Serial:   "00000001",
+			Type:     "metrics-test",
+			IPv4Addr: "10.0.0.1",
+			State:    sabakan.StateHealthy,
+		},
+		{
+			Serial:   "00000002",
+			Type:     "metrics-test",
+			IPv4Addr: "10.0.0.2",
+			State:    sabakan.StateHealthy,
+		},
+	}
+	sabaMock := newMockSabakanClient(machines)
+	promMock := newMockPromClient(map[string]string{})
+	serfMock, _ := newMockSerfClient(map[string]*serfStatus{
+		"10.0.0.2": {Status: "alive", SystemdUnitsFailed: strPtr("a.service")},
+	})
+	necoMock := newMockNecoCmdExecutor()
+	ctr := newMockController(sabaMock, promMock, serfMock, nil, necoMock, mt)
+
+	err := ctr.runOnce(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handler := GetMetricsHandler()
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	metrics := rec.Body.String()
+
+	expects := []string{
+		`sabakan_state_setter_decisions_total{machine_type="metrics-test",reason="serf_status_nil",state="unreachable"} 1`,
+		`sabakan_state_setter_decisions_total{machine_type="metrics-test",reason="systemd_units_failed",state="unhealthy"} 1`,
+		`sabakan_state_setter_state_changes_total{machine_type="metrics-test",state="unreachable"} 1`,
+		`sabakan_state_setter_grace_period_machines{machine_type="metrics-test",state="unhealthy"} 1`,
+		`sabakan_state_setter_leader 0`,
+	}
+	for _, expect := range expects {
+		if !strings.Contains(metrics, expect) {
+			t.Errorf("expected %s, but got %s", expect, metrics)
+		}
+	}
+	if strings.Contains(metrics, `sabakan_state_setter_state_changes_total{machine_type="metrics-test",state="unhealthy"}`) {
+		t.Errorf("unexpected state change during grace period: %s", metrics)
+	}
+}
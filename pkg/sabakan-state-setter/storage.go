@@ -0,0 +1,21 @@
+package sss
+
+import (
+	"context"
+	"time"
+
+	"github.com/cybozu-go/neco/storage"
+)
+
+// StateStorage is interface for the states of sabakan-state-setter stored in etcd
+type StateStorage interface {
+	GetUnhealthyMachines(ctx context.Context) (map[string]time.Time, error)
+	PutUnhealthyMachine(ctx context.Context, leaderKey, serial string, since time.Time) error
+	DeleteUnhealthyMachine(ctx context.Context, leaderKey, serial string) error
+	GetCircuitBreaker(ctx context.Context) (string, error)
+	TripCircuitBreaker(ctx context.Context, leaderKey, reason string) error
+	IsCircuitBreakerAcked(ctx context.Context) (bool, error)
+	ResetCircuitBreaker(ctx context.Context, leaderKey string) error
+}
+
+var _ StateStorage = storage.Storage{}
@@ -0,0 +1,74 @@
+package sss
+
+import (
+	"context"
+	"time"
+
+	"github.com/cybozu-go/neco/storage"
+)
+
+type stateStorageMock struct {
+	unhealthyMachines map[string]time.Time
+	breakerReason     string
+	breakerAcked      bool
+	tripErr           error
+}
+
+func newMockStateStorage() *stateStorageMock {
+	return &stateStorageMock{
+		unhealthyMachines: map[string]time.Time{},
+	}
+}
+
+func (s *stateStorageMock) GetUnhealthyMachines(ctx context.Context) (map[string]time.Time, error) {
+	ret := make(map[string]time.Time, len(s.unhealthyMachines))
+	for serial, t := range s.unhealthyMachines {
+		ret[serial] = t
+	}
+	return ret, nil
+}
+
+func (s *stateStorageMock) PutUnhealthyMachine(ctx context.Context, leaderKey, serial string, since time.Time) error {
+	s.unhealthyMachines[serial] = since
+	return nil
+}
+
+func (s *stateStorageMock) DeleteUnhealthyMachine(ctx context.Context, leaderKey, serial string) error {
+	delete(s.unhealthyMachines, serial)
+	return nil
+}
+
+func (s *stateStorageMock) GetCircuitBreaker(ctx context.Context) (string, error) {
+	if s.breakerReason == "" {
+		return "", storage.ErrNotFound
+	}
+	return s.breakerReason, nil
+}
+
+func (s *stateStorageMock) TripCircuitBreaker(ctx context.Context, leaderKey, reason string) error {
+	if s.tripErr != nil {
+		return s.tripErr
+	}
+	s.breakerReason = reason
+	s.breakerAcked = false
+	return nil
+}
+
+// AckCircuitBreaker acknowledges the circuit breaker as `neco sabakan-state-setter ack` does.
+func (s *stateStorageMock) AckCircuitBreaker(ctx context.Context) error {
+	if s.breakerReason == "" {
+		return storage.ErrNotFound
+	}
+	s.breakerAcked = true
+	return nil
+}
+
+func (s *stateStorageMock) IsCircuitBreakerAcked(ctx context.Context) (bool, error) {
+	return s.breakerAcked, nil
+}
+
+func (s *stateStorageMock) ResetCircuitBreaker(ctx context.Context, leaderKey string) error {
+	s.breakerReason = ""
+	s.breakerAcked = false
+	return nil
+}
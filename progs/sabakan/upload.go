@@ -61,7 +61,51 @@ func UploadContents(ctx context.Context, sabakanHTTP *http.Client, proxyHTTP *ht
 	return uploadIgnitions(ctx, client, version, st)
 }
 
+// UploadedOSImage returns the version of the latest OS image uploaded to sabakan.
+// If no image has been uploaded, this returns an empty string.
+func UploadedOSImage(ctx context.Context, sabakanHTTP *http.Client) (string, error) {
+	client, err := sabac.NewClient(neco.SabakanLocalEndpoint, sabakanHTTP)
+	if err != nil {
+		return "", err
+	}
+
+	index, err := client.ImagesIndex(ctx, imageOS)
+	if err != nil {
+		return "", err
+	}
+	if len(index) == 0 {
+		return "", nil
+	}
+	return index[len(index)-1].ID, nil
+}
+
 // uploadOSImages uploads OS images
+// MissingImageAssets returns the container images in images which have not
+// been uploaded to sabakan as assets.
+func MissingImageAssets(ctx context.Context, sabakanHTTP *http.Client, images []neco.ContainerImage) ([]neco.ContainerImage, error) {
+	client, err := sabac.NewClient(neco.SabakanLocalEndpoint, sabakanHTTP)
+	if err != nil {
+		return nil, err
+	}
+
+	index, err := client.AssetsIndex(ctx)
+	if err != nil {
+		return nil, err
+	}
+	uploaded := make(map[string]bool)
+	for _, name := range index {
+		uploaded[name] = true
+	}
+
+	var missing []neco.ContainerImage
+	for _, img := range images {
+		if !uploaded[imageAssetName(img)] {
+			missing = append(missing, img)
+		}
+	}
+	return missing, nil
+}
+
 func uploadOSImages(ctx context.Context, c *sabac.Client, p *http.Client) error {
 	index, err := c.ImagesIndex(ctx, imageOS)
 	if err != nil {
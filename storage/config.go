@@ -2,7 +2,9 @@ package storage
 
 import (
 	"context"
+	"encoding/json"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/cybozu-go/neco"
@@ -12,6 +14,9 @@ import (
 const (
 	DefaultCheckUpdateInterval = 1 * time.Minute
 	DefaultWorkerTimeout       = 60 * time.Minute
+	DefaultCanarySoakPeriod    = 30 * time.Minute
+	DefaultHistoryRetention    = 90 * 24 * time.Hour
+	DefaultReleaseTimeZone     = "Asia/Tokyo"
 )
 
 // PutEnvConfig stores proxy config to storage.
@@ -39,6 +44,75 @@ func (s Storage) GetSlackNotification(ctx context.Context) (string, error) {
 	return s.get(ctx, KeyNotificationSlack)
 }
 
+// DeleteSlackNotification removes SlackNotification from storage.
+func (s Storage) DeleteSlackNotification(ctx context.Context) error {
+	return s.del(ctx, KeyNotificationSlack)
+}
+
+// PutWebhookNotification stores the URL of the generic webhook to storage.
+func (s Storage) PutWebhookNotification(ctx context.Context, url string) error {
+	return s.put(ctx, KeyNotificationWebhook, url)
+}
+
+// GetWebhookNotification returns the URL of the generic webhook from storage.
+// If not found, this returns ErrNotFound.
+func (s Storage) GetWebhookNotification(ctx context.Context) (string, error) {
+	return s.get(ctx, KeyNotificationWebhook)
+}
+
+// DeleteWebhookNotification removes the URL of the generic webhook from storage.
+func (s Storage) DeleteWebhookNotification(ctx context.Context) error {
+	return s.del(ctx, KeyNotificationWebhook)
+}
+
+// PutWebhookTemplate stores the payload template of the generic webhook to storage.
+func (s Storage) PutWebhookTemplate(ctx context.Context, tmpl string) error {
+	return s.put(ctx, KeyNotificationWebhookTemplate, tmpl)
+}
+
+// GetWebhookTemplate returns the payload template of the generic webhook from storage.
+// If not found, this returns ErrNotFound.
+func (s Storage) GetWebhookTemplate(ctx context.Context) (string, error) {
+	return s.get(ctx, KeyNotificationWebhookTemplate)
+}
+
+// DeleteWebhookTemplate removes the payload template of the generic webhook from storage.
+func (s Storage) DeleteWebhookTemplate(ctx context.Context) error {
+	return s.del(ctx, KeyNotificationWebhookTemplate)
+}
+
+// PutTeamsNotification stores the URL of Microsoft Teams webhook to storage.
+func (s Storage) PutTeamsNotification(ctx context.Context, url string) error {
+	return s.put(ctx, KeyNotificationTeams, url)
+}
+
+// GetTeamsNotification returns the URL of Microsoft Teams webhook from storage.
+// If not found, this returns ErrNotFound.
+func (s Storage) GetTeamsNotification(ctx context.Context) (string, error) {
+	return s.get(ctx, KeyNotificationTeams)
+}
+
+// DeleteTeamsNotification removes the URL of Microsoft Teams webhook from storage.
+func (s Storage) DeleteTeamsNotification(ctx context.Context) error {
+	return s.del(ctx, KeyNotificationTeams)
+}
+
+// PutAlertmanagerNotification stores the URL of Alertmanager to storage.
+func (s Storage) PutAlertmanagerNotification(ctx context.Context, url string) error {
+	return s.put(ctx, KeyNotificationAlertmanager, url)
+}
+
+// GetAlertmanagerNotification returns the URL of Alertmanager from storage.
+// If not found, this returns ErrNotFound.
+func (s Storage) GetAlertmanagerNotification(ctx context.Context) (string, error) {
+	return s.get(ctx, KeyNotificationAlertmanager)
+}
+
+// DeleteAlertmanagerNotification removes the URL of Alertmanager from storage.
+func (s Storage) DeleteAlertmanagerNotification(ctx context.Context) error {
+	return s.del(ctx, KeyNotificationAlertmanager)
+}
+
 // PutProxyConfig stores proxy config to storage.
 func (s Storage) PutProxyConfig(ctx context.Context, proxy string) error {
 	return s.put(ctx, KeyProxy, proxy)
@@ -180,3 +254,154 @@ func (s Storage) PutReleaseTimeZone(ctx context.Context, tz string) error {
 func (s Storage) GetReleaseTimeZone(ctx context.Context) (string, error) {
 	return s.get(ctx, KeyReleaseTimeZone)
 }
+
+// PutCanaryServers stores LRNs of canary boot servers to storage.
+func (s Storage) PutCanaryServers(ctx context.Context, lrns []int) error {
+	data, err := json.Marshal(lrns)
+	if err != nil {
+		return err
+	}
+	return s.put(ctx, KeyCanaryServers, string(data))
+}
+
+// GetCanaryServers returns LRNs of canary boot servers from storage.
+// It returns nil if the key does not exist.
+func (s Storage) GetCanaryServers(ctx context.Context) ([]int, error) {
+	data, err := s.get(ctx, KeyCanaryServers)
+	if err == ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return parseCanaryServers([]byte(data))
+}
+
+// DeleteCanaryServers removes canary-servers config from storage.
+func (s Storage) DeleteCanaryServers(ctx context.Context) error {
+	return s.del(ctx, KeyCanaryServers)
+}
+
+func parseCanaryServers(data []byte) ([]int, error) {
+	var lrns []int
+	err := json.Unmarshal(data, &lrns)
+	if err != nil {
+		return nil, err
+	}
+	return lrns, nil
+}
+
+// PutCanarySoakPeriod stores canary-soak-period config to storage.
+func (s Storage) PutCanarySoakPeriod(ctx context.Context, d time.Duration) error {
+	data := strconv.FormatInt(int64(d), 10)
+	return s.put(ctx, KeyCanarySoakPeriod, data)
+}
+
+// GetCanarySoakPeriod returns canary-soak-period config from storage. It
+// returns default value if the key does not exist.
+func (s Storage) GetCanarySoakPeriod(ctx context.Context) (time.Duration, error) {
+	data, err := s.get(ctx, KeyCanarySoakPeriod)
+	if err == ErrNotFound {
+		return DefaultCanarySoakPeriod, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	i, err := strconv.ParseInt(data, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(i), nil
+}
+
+// PutCanaryProbeURL stores canary-probe-url config to storage.
+func (s Storage) PutCanaryProbeURL(ctx context.Context, u string) error {
+	return s.put(ctx, KeyCanaryProbeURL, u)
+}
+
+// GetCanaryProbeURL returns canary-probe-url config from storage.
+// If not found, this returns ErrNotFound.
+func (s Storage) GetCanaryProbeURL(ctx context.Context) (string, error) {
+	return s.get(ctx, KeyCanaryProbeURL)
+}
+
+// DeleteCanaryProbeURL removes canary-probe-url config from storage.
+func (s Storage) DeleteCanaryProbeURL(ctx context.Context) error {
+	return s.del(ctx, KeyCanaryProbeURL)
+}
+
+// PutAutoRollback stores auto-rollback config to storage.
+func (s Storage) PutAutoRollback(ctx context.Context, enabled bool) error {
+	return s.put(ctx, KeyAutoRollback, strconv.FormatBool(enabled))
+}
+
+// GetAutoRollback returns auto-rollback config from storage. It returns
+// false if the key does not exist.
+func (s Storage) GetAutoRollback(ctx context.Context) (bool, error) {
+	data, err := s.get(ctx, KeyAutoRollback)
+	if err == ErrNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return strconv.ParseBool(data)
+}
+
+// PutHistoryRetention stores history-retention config to storage.
+func (s Storage) PutHistoryRetention(ctx context.Context, d time.Duration) error {
+	data := strconv.FormatInt(int64(d), 10)
+	return s.put(ctx, KeyHistoryRetention, data)
+}
+
+// GetHistoryRetention returns history-retention config from storage. It
+// returns default value if the key does not exist.
+func (s Storage) GetHistoryRetention(ctx context.Context) (time.Duration, error) {
+	data, err := s.get(ctx, KeyHistoryRetention)
+	if err == ErrNotFound {
+		return DefaultHistoryRetention, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	i, err := strconv.ParseInt(data, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(i), nil
+}
+
+// PutDenyWindows stores deny-windows config to storage.
+func (s Storage) PutDenyWindows(ctx context.Context, windows []string) error {
+	return s.put(ctx, KeyDenyWindows, strings.Join(windows, "\n"))
+}
+
+// GetDenyWindows returns deny-windows config from storage.
+// It returns nil if the key does not exist.
+func (s Storage) GetDenyWindows(ctx context.Context) ([]string, error) {
+	data, err := s.get(ctx, KeyDenyWindows)
+	if err == ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return strings.Split(data, "\n"), nil
+}
+
+// DeleteDenyWindows removes deny-windows config from storage.
+func (s Storage) DeleteDenyWindows(ctx context.Context) error {
+	return s.del(ctx, KeyDenyWindows)
+}
+
+// GetReleaseTimeLocation returns the location of release-timezone config.
+// It returns the location of DefaultReleaseTimeZone if the key does not exist.
+func (s Storage) GetReleaseTimeLocation(ctx context.Context) (*time.Location, error) {
+	tz, err := s.GetReleaseTimeZone(ctx)
+	if err == ErrNotFound {
+		tz = DefaultReleaseTimeZone
+	} else if err != nil {
+		return nil, err
+	}
+	return time.LoadLocation(tz)
+}
@@ -67,6 +67,57 @@ func testSlackNotification(t *testing.T) {
 	}
 }
 
+func testNotifications(t *testing.T) {
+	t.Parallel()
+
+	etcd := test.NewEtcdClient(t)
+	defer etcd.Close()
+	ctx := context.Background()
+	st := NewStorage(etcd)
+
+	testCases := []struct {
+		name  string
+		put   func(context.Context, string) error
+		get   func(context.Context) (string, error)
+		del   func(context.Context) error
+		value string
+	}{
+		{"slack", st.PutSlackNotification, st.GetSlackNotification, st.DeleteSlackNotification, "http://slack.com/aaa"},
+		{"webhook", st.PutWebhookNotification, st.GetWebhookNotification, st.DeleteWebhookNotification, "http://example.com/hook"},
+		{"webhook-template", st.PutWebhookTemplate, st.GetWebhookTemplate, st.DeleteWebhookTemplate, `{"text": {{ json .Message }}}`},
+		{"teams", st.PutTeamsNotification, st.GetTeamsNotification, st.DeleteTeamsNotification, "http://teams.example.com/hook"},
+		{"alertmanager", st.PutAlertmanagerNotification, st.GetAlertmanagerNotification, st.DeleteAlertmanagerNotification, "http://alertmanager:9093"},
+	}
+
+	for _, tc := range testCases {
+		_, err := tc.get(ctx)
+		if err != ErrNotFound {
+			t.Error(tc.name, "should not be found", err)
+		}
+
+		err = tc.put(ctx, tc.value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		value, err := tc.get(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if value != tc.value {
+			t.Error("unexpected value for", tc.name, value)
+		}
+
+		err = tc.del(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = tc.get(ctx)
+		if err != ErrNotFound {
+			t.Error(tc.name, "should be deleted", err)
+		}
+	}
+}
+
 func testProxyConfig(t *testing.T) {
 	t.Parallel()
 
@@ -154,10 +205,247 @@ func testWorkerTimeout(t *testing.T) {
 	}
 }
 
+func testCanaryServers(t *testing.T) {
+	t.Parallel()
+
+	etcd := test.NewEtcdClient(t)
+	defer etcd.Close()
+	ctx := context.Background()
+	st := NewStorage(etcd)
+
+	lrns, err := st.GetCanaryServers(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lrns != nil {
+		t.Error(`lrns != nil`, lrns)
+	}
+
+	err = st.PutCanaryServers(ctx, []int{0, 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lrns, err = st.GetCanaryServers(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lrns) != 2 || lrns[0] != 0 || lrns[1] != 2 {
+		t.Error(`lrns != []int{0, 2}`, lrns)
+	}
+
+	ss, err := st.NewSnapshot(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ss.Canary) != 2 {
+		t.Error(`len(ss.Canary) != 2`, ss.Canary)
+	}
+
+	err = st.DeleteCanaryServers(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lrns, err = st.GetCanaryServers(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lrns != nil {
+		t.Error(`lrns != nil`, lrns)
+	}
+}
+
+func testCanarySoakPeriod(t *testing.T) {
+	t.Parallel()
+
+	etcd := test.NewEtcdClient(t)
+	defer etcd.Close()
+	ctx := context.Background()
+	st := NewStorage(etcd)
+
+	d, err := st.GetCanarySoakPeriod(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d != DefaultCanarySoakPeriod {
+		t.Error(`d != DefaultCanarySoakPeriod`, d)
+	}
+
+	err = st.PutCanarySoakPeriod(ctx, 2*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d, err = st.GetCanarySoakPeriod(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d != 2*time.Hour {
+		t.Error(`d != 2*time.Hour`, d)
+	}
+}
+
+func testCanaryProbeURL(t *testing.T) {
+	t.Parallel()
+
+	etcd := test.NewEtcdClient(t)
+	defer etcd.Close()
+	ctx := context.Background()
+	st := NewStorage(etcd)
+
+	_, err := st.GetCanaryProbeURL(ctx)
+	if err != ErrNotFound {
+		t.Error(`err != ErrNotFound`, err)
+	}
+
+	u := "http://{ip}:10080/health"
+	err = st.PutCanaryProbeURL(ctx, u)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := st.GetCanaryProbeURL(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != u {
+		t.Error(`got != u`, got)
+	}
+
+	ss, err := st.NewSnapshot(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ss.CanaryProbeURL != u {
+		t.Error(`ss.CanaryProbeURL != u`, ss.CanaryProbeURL)
+	}
+
+	err = st.DeleteCanaryProbeURL(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = st.GetCanaryProbeURL(ctx)
+	if err != ErrNotFound {
+		t.Error(`err != ErrNotFound`, err)
+	}
+}
+
+func testAutoRollback(t *testing.T) {
+	t.Parallel()
+
+	etcd := test.NewEtcdClient(t)
+	defer etcd.Close()
+	ctx := context.Background()
+	st := NewStorage(etcd)
+
+	enabled, err := st.GetAutoRollback(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if enabled {
+		t.Error(`auto-rollback should be disabled by default`)
+	}
+
+	err = st.PutAutoRollback(ctx, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	enabled, err = st.GetAutoRollback(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !enabled {
+		t.Error(`auto-rollback should be enabled`)
+	}
+}
+
+func testDenyWindows(t *testing.T) {
+	t.Parallel()
+
+	etcd := test.NewEtcdClient(t)
+	defer etcd.Close()
+	ctx := context.Background()
+	st := NewStorage(etcd)
+
+	windows, err := st.GetDenyWindows(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if windows != nil {
+		t.Error(`windows != nil`, windows)
+	}
+
+	err = st.PutReleaseTimeZone(ctx, "UTC")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = st.PutDenyWindows(ctx, []string{"12-28/01-04 year-end", "2024-05-01/2024-05-05"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	windows, err = st.GetDenyWindows(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(windows) != 2 || windows[0] != "12-28/01-04 year-end" {
+		t.Error(`unexpected windows`, windows)
+	}
+
+	ss, err := st.NewSnapshot(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ss.DenyWindows) != 2 || ss.DenyWindows[0].Description != "year-end" {
+		t.Error(`unexpected ss.DenyWindows`, ss.DenyWindows)
+	}
+	active, _ := ss.DenyWindows[1].Active(time.Date(2024, 5, 5, 23, 0, 0, 0, time.UTC))
+	if !active {
+		t.Error(`deny window should be in release-timezone`)
+	}
+
+	// WaitInfo should wake up on changes of deny windows.
+	ch := make(chan error, 1)
+	go func() {
+		ch <- st.WaitInfo(ctx, ss.Revision)
+	}()
+
+	err = st.DeleteDenyWindows(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case err := <-ch:
+		if err != nil {
+			t.Error(err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Error(`WaitInfo did not return`)
+	}
+
+	windows, err = st.GetDenyWindows(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if windows != nil {
+		t.Error(`windows != nil`, windows)
+	}
+}
+
 func TestConfig(t *testing.T) {
 	t.Run("EnvConfig", testEnvConfig)
 	t.Run("SlackNotification", testSlackNotification)
+	t.Run("Notifications", testNotifications)
 	t.Run("ProxyConfig", testProxyConfig)
 	t.Run("CheckUpdateIntervalConfig", testCheckUpdateIntervalConfig)
 	t.Run("WorkerTimeout", testWorkerTimeout)
+	t.Run("CanaryServers", testCanaryServers)
+	t.Run("CanarySoakPeriod", testCanarySoakPeriod)
+	t.Run("CanaryProbeURL", testCanaryProbeURL)
+	t.Run("AutoRollback", testAutoRollback)
+	t.Run("DenyWindows", testDenyWindows)
 }
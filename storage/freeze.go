@@ -0,0 +1,41 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/cybozu-go/neco"
+)
+
+// PutUpdateFreeze stores UpdateFreeze to freeze updates.
+func (s Storage) PutUpdateFreeze(ctx context.Context, f neco.UpdateFreeze) error {
+	data, err := json.Marshal(f)
+	if err != nil {
+		return err
+	}
+	return s.put(ctx, KeyUpdateFreeze, string(data))
+}
+
+// GetUpdateFreeze returns UpdateFreeze.
+// If updates are not frozen, this returns ErrNotFound.
+func (s Storage) GetUpdateFreeze(ctx context.Context) (*neco.UpdateFreeze, error) {
+	data, err := s.get(ctx, KeyUpdateFreeze)
+	if err != nil {
+		return nil, err
+	}
+	return parseUpdateFreeze([]byte(data))
+}
+
+// DeleteUpdateFreeze removes UpdateFreeze to unfreeze updates.
+func (s Storage) DeleteUpdateFreeze(ctx context.Context) error {
+	return s.del(ctx, KeyUpdateFreeze)
+}
+
+func parseUpdateFreeze(data []byte) (*neco.UpdateFreeze, error) {
+	f := new(neco.UpdateFreeze)
+	err := json.Unmarshal(data, f)
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
@@ -0,0 +1,59 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cybozu-go/neco"
+	"github.com/cybozu-go/neco/storage/test"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestUpdateFreeze(t *testing.T) {
+	t.Parallel()
+
+	etcd := test.NewEtcdClient(t)
+	defer etcd.Close()
+	ctx := context.Background()
+	st := NewStorage(etcd)
+
+	_, err := st.GetUpdateFreeze(ctx)
+	if err != ErrNotFound {
+		t.Error("unexpected error", err)
+	}
+
+	f := neco.UpdateFreeze{
+		Reason:   "maintenance",
+		FrozenAt: time.Date(2024, 12, 28, 9, 0, 0, 0, time.UTC),
+	}
+	err = st.PutUpdateFreeze(ctx, f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := st.GetUpdateFreeze(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cmp.Equal(*got, f) {
+		t.Error("unexpected freeze", cmp.Diff(*got, f))
+	}
+
+	ss, err := st.NewSnapshot(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ss.Freeze == nil || ss.Freeze.Reason != "maintenance" {
+		t.Error("unexpected ss.Freeze", ss.Freeze)
+	}
+
+	err = st.DeleteUpdateFreeze(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = st.GetUpdateFreeze(ctx)
+	if err != ErrNotFound {
+		t.Error("unexpected error", err)
+	}
+}
@@ -0,0 +1,169 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cybozu-go/neco"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/client/v3/clientv3util"
+)
+
+// maxPruneHistories is the maximum number of history records deleted at once.
+const maxPruneHistories = 50
+
+// HistoryID returns the ID of the history record for an update request.
+// IDs are sortable in the order of the start time of requests.
+func HistoryID(req neco.UpdateRequest) string {
+	return fmt.Sprintf("%019d", req.StartedAt.UnixNano())
+}
+
+// PutHistory creates or updates a history record.
+// leaderKey is the current leader key.
+// If the caller has lost the leadership, this returns ErrNoLeader.
+func (s Storage) PutHistory(ctx context.Context, h neco.UpdateHistory, leaderKey string) error {
+	h.Steps = nil
+	data, err := json.Marshal(h)
+	if err != nil {
+		return err
+	}
+
+	resp, err := s.etcd.Txn(ctx).
+		If(clientv3util.KeyExists(leaderKey)).
+		Then(clientv3.OpPut(keyHistoryRecord(h.ID), string(data))).
+		Commit()
+	if err != nil {
+		return err
+	}
+	if !resp.Succeeded {
+		return ErrNoLeader
+	}
+	return nil
+}
+
+// RecordHistoryStep records the result of an update step run by a boot server.
+func (s Storage) RecordHistoryStep(ctx context.Context, id string, lrn int, st neco.HistoryStep) error {
+	data, err := json.Marshal(st)
+	if err != nil {
+		return err
+	}
+	return s.put(ctx, keyHistoryStep(id, lrn, st.Step), string(data))
+}
+
+// GetHistory returns a history record including the step records.
+// If the record does not exist, this returns ErrNotFound.
+func (s Storage) GetHistory(ctx context.Context, id string) (*neco.UpdateHistory, error) {
+	resp, err := s.etcd.Txn(ctx).
+		Then(
+			clientv3.OpGet(keyHistoryRecord(id)),
+			clientv3.OpGet(keyHistorySteps(id), clientv3.WithPrefix()),
+		).
+		Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	recResp := resp.Responses[0].GetResponseRange()
+	if recResp.Count == 0 {
+		return nil, ErrNotFound
+	}
+	h := new(neco.UpdateHistory)
+	err = json.Unmarshal(recResp.Kvs[0].Value, h)
+	if err != nil {
+		return nil, err
+	}
+
+	stepsResp := resp.Responses[1].GetResponseRange()
+	if stepsResp.Count == 0 {
+		return h, nil
+	}
+	prefix := keyHistorySteps(id)
+	h.Steps = make(map[int][]neco.HistoryStep)
+	for _, kv := range stepsResp.Kvs {
+		lrnStr, _, _ := strings.Cut(string(kv.Key[len(prefix):]), "/")
+		lrn, err := strconv.Atoi(lrnStr)
+		if err != nil {
+			return nil, err
+		}
+		var st neco.HistoryStep
+		err = json.Unmarshal(kv.Value, &st)
+		if err != nil {
+			return nil, err
+		}
+		h.Steps[lrn] = append(h.Steps[lrn], st)
+	}
+	for _, steps := range h.Steps {
+		sort.Slice(steps, func(i, j int) bool {
+			return steps[i].Step < steps[j].Step
+		})
+	}
+	return h, nil
+}
+
+// ListHistories returns history records without the step records
+// in the order of the start time.
+func (s Storage) ListHistories(ctx context.Context) ([]*neco.UpdateHistory, error) {
+	resp, err := s.etcd.Get(ctx, KeyHistoryRecordsPrefix,
+		clientv3.WithPrefix(),
+		clientv3.WithSort(clientv3.SortByKey, clientv3.SortAscend))
+	if err != nil {
+		return nil, err
+	}
+
+	hs := make([]*neco.UpdateHistory, 0, resp.Count)
+	for _, kv := range resp.Kvs {
+		h := new(neco.UpdateHistory)
+		err = json.Unmarshal(kv.Value, h)
+		if err != nil {
+			return nil, err
+		}
+		hs = append(hs, h)
+	}
+	return hs, nil
+}
+
+// PruneHistories deletes history records started before the given time.
+// This returns the number of deleted records.  Not all of the old records
+// may be deleted at once.
+// leaderKey is the current leader key.
+// If the caller has lost the leadership, this returns ErrNoLeader.
+func (s Storage) PruneHistories(ctx context.Context, before time.Time, leaderKey string) (int, error) {
+	end := keyHistoryRecord(HistoryID(neco.UpdateRequest{StartedAt: before}))
+	// limit the number of records to keep the transaction small enough.
+	resp, err := s.etcd.Get(ctx, KeyHistoryRecordsPrefix,
+		clientv3.WithRange(end),
+		clientv3.WithKeysOnly(),
+		clientv3.WithLimit(maxPruneHistories))
+	if err != nil {
+		return 0, err
+	}
+	if resp.Count == 0 {
+		return 0, nil
+	}
+
+	ops := make([]clientv3.Op, 0, resp.Count*2)
+	for _, kv := range resp.Kvs {
+		id := string(kv.Key[len(KeyHistoryRecordsPrefix):])
+		ops = append(ops,
+			clientv3.OpDelete(keyHistoryRecord(id)),
+			clientv3.OpDelete(keyHistorySteps(id), clientv3.WithPrefix()),
+		)
+	}
+
+	txnResp, err := s.etcd.Txn(ctx).
+		If(clientv3util.KeyExists(leaderKey)).
+		Then(ops...).
+		Commit()
+	if err != nil {
+		return 0, err
+	}
+	if !txnResp.Succeeded {
+		return 0, ErrNoLeader
+	}
+	return len(resp.Kvs), nil
+}
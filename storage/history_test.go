@@ -0,0 +1,138 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cybozu-go/neco"
+	"github.com/cybozu-go/neco/storage/test"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/client/v3/concurrency"
+)
+
+func TestHistory(t *testing.T) {
+	t.Parallel()
+
+	etcd := test.NewEtcdClient(t)
+	defer etcd.Close()
+	ctx := context.Background()
+	st := NewStorage(etcd)
+
+	sess, err := concurrency.NewSession(etcd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sess.Close()
+	e := concurrency.NewElection(sess, KeyUpdaterLeader)
+	err = e.Campaign(ctx, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaderKey := e.Key()
+
+	hs, err := st.ListHistories(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hs) != 0 {
+		t.Error("histories should be empty", hs)
+	}
+
+	now := time.Now().UTC()
+	old := neco.UpdateRequest{Version: "0.9.0", Servers: []int{0, 1}, StartedAt: now.Add(-48 * time.Hour)}
+	cur := neco.UpdateRequest{Version: "1.0.0", Servers: []int{0, 1}, StartedAt: now}
+	for _, req := range []neco.UpdateRequest{cur, old} {
+		h := neco.UpdateHistory{
+			ID:        HistoryID(req),
+			Version:   req.Version,
+			Servers:   req.Servers,
+			StartedAt: req.StartedAt,
+			Cond:      neco.CondRunning,
+		}
+		err = st.PutHistory(ctx, h, leaderKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	hs, err = st.ListHistories(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hs) != 2 {
+		t.Fatal("unexpected histories", hs)
+	}
+	if hs[0].Version != "0.9.0" || hs[1].Version != "1.0.0" {
+		t.Error("histories should be sorted by start time", hs[0].Version, hs[1].Version)
+	}
+
+	id := HistoryID(cur)
+	for _, lrn := range []int{1, 0} {
+		for _, step := range []int{10, 2, 1} {
+			err = st.RecordHistoryStep(ctx, id, lrn, neco.HistoryStep{
+				Step:      step,
+				StartedAt: now,
+				EndedAt:   now.Add(time.Second),
+				Cond:      neco.CondComplete,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	err = st.RecordHistoryStep(ctx, HistoryID(old), 0, neco.HistoryStep{Step: 1, Cond: neco.CondAbort, Message: "failed"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h, err := st.GetHistory(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(h.Steps) != 2 {
+		t.Fatal("unexpected steps", h.Steps)
+	}
+	for lrn, steps := range h.Steps {
+		if len(steps) != 3 || steps[0].Step != 1 || steps[1].Step != 2 || steps[2].Step != 10 {
+			t.Error("unexpected steps for", lrn, steps)
+		}
+	}
+
+	_, err = st.GetHistory(ctx, "no-such-id")
+	if err != ErrNotFound {
+		t.Error("history should not be found", err)
+	}
+
+	n, err := st.PruneHistories(ctx, now.Add(-24*time.Hour), leaderKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Error("one history should be pruned", n)
+	}
+	_, err = st.GetHistory(ctx, HistoryID(old))
+	if err != ErrNotFound {
+		t.Error("old history should be pruned", err)
+	}
+	resp, err := etcd.Get(ctx, keyHistorySteps(HistoryID(old)), clientv3.WithPrefix())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Count != 0 {
+		t.Error("steps of old history should be pruned")
+	}
+	_, err = st.GetHistory(ctx, id)
+	if err != nil {
+		t.Error("current history should be kept", err)
+	}
+
+	err = e.Resign(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = st.PutHistory(ctx, *h, leaderKey)
+	if err != ErrNoLeader {
+		t.Error("should lost leadership")
+	}
+}
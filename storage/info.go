@@ -58,14 +58,24 @@ func (s Storage) GetNecoRelease(ctx context.Context) (string, error) {
 	return string(resp.Kvs[0].Value), nil
 }
 
-// WaitInfo waits for update of keys under `info/`
+// WaitInfo waits for update of keys under `info/` or `updater/`, or
+// `config/deny-windows`.
 func (s Storage) WaitInfo(ctx context.Context, rev int64) error {
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
 	ch := s.etcd.Watch(ctx, KeyInfoPrefix,
 		clientv3.WithPrefix(), clientv3.WithKeysOnly(), clientv3.WithRev(rev+1))
+	uch := s.etcd.Watch(ctx, KeyUpdaterPrefix,
+		clientv3.WithPrefix(), clientv3.WithKeysOnly(), clientv3.WithRev(rev+1))
+	dch := s.etcd.Watch(ctx, KeyDenyWindows,
+		clientv3.WithKeysOnly(), clientv3.WithRev(rev+1))
 
-	resp := <-ch
+	var resp clientv3.WatchResponse
+	select {
+	case resp = <-ch:
+	case resp = <-uch:
+	case resp = <-dch:
+	}
 	return resp.Err()
 }
@@ -7,55 +7,80 @@ import (
 
 // etcd keys
 const (
-	KeySabakanStateSetterLeader    = "leader/sabakan-state-setter/"
-	KeyUpdaterLeader               = "leader/updater/"
-	KeyWorkerLeader                = "leader/worker/"
-	KeyNecoRebooterLeader          = "leader/neco-rebooter/"
-	KeyInfoPrefix                  = "info/"
-	KeyBootserversPrefix           = "info/bootservers/"
-	KeyNecoRelease                 = "info/neco-release"
-	KeySSHPubkey                   = "info/ssh-pubkey"
-	KeyStatusPrefix                = "status/"
-	KeyCurrent                     = "status/current"
-	KeyWorkerStatusPrefix          = "status/bootservers/"
-	KeyContentsPrefix              = "contents/"
-	KeySabakanContents             = "contents/sabakan"
-	KeyCKEContents                 = "contents/cke"
-	KeyDHCPJSONContents            = "contents/dhcp.json"
-	KeyCKETemplateContents         = "contents/cke-template"
-	KeyUserResourcesContents       = "contents/user-resources"
-	KeyConfigPrefix                = "config/"
-	KeyNotificationSlack           = "config/notification/slack"
-	KeyProxy                       = "config/proxy"
-	KeyEnv                         = "config/env"
-	KeyCheckUpdateInterval         = "config/check-update-interval"
-	KeyWorkerTimeout               = "config/worker-timeout"
-	KeyGitHubToken                 = "config/github-token"
-	KeyNodeProxy                   = "config/node-proxy"
-	KeyExternalIPAddressBlock      = "config/external-ip-address-block"
-	KeyLBAddressBlockDefault       = "config/lb-address-block-default"
-	KeyLBAddressBlockBastion       = "config/lb-address-block-bastion"
-	KeyLBAddressBlockInternet      = "config/lb-address-block-internet"
-	KeyLBAddressBlockInternetCN    = "config/lb-address-block-internet-cn"
-	KeyReleaseTime                 = "config/release-time"
-	KeyReleaseTimeZone             = "config/release-timezone"
-	KeyVaultUnsealKey              = "vault-unseal-key"
-	KeyVaultRootToken              = "vault-root-token"
-	KeyFinishPrefix                = "finish/"
-	KeyContainersFormat            = "install/%d/containers/%s"
-	KeyDebsFormat                  = "install/%d/debs/%s"
-	KeyInstallPrefix               = "install/"
-	KeyBMCBMCUser                  = "bmc/bmc-user"
-	KeyBMCIPMIUser                 = "bmc/ipmi-user"
-	KeyBMCIPMIPassword             = "bmc/ipmi-password"
-	KeyBMCRepairUser               = "bmc/repair-user"
-	KeyBMCRepairPassword           = "bmc/repair-password"
-	KeyTeleportAuthToken           = "teleport/auth-token"
-	KeyCKEWeight                   = "cke/weight"
-	KeyNecoRebooterRebootList      = "neco-rebooter/reboot-list/"
-	KeyNecoRebooterWriteIndex      = "neco-rebooter/write-index"
-	KeyNecoRebooterProcessingGroup = "neco-rebooter/processing-group"
-	KeyNecoRebooterIsEnabled       = "neco-rebooter/is-enabled"
+	KeySabakanStateSetterLeader     = "leader/sabakan-state-setter/"
+	KeyUpdaterLeader                = "leader/updater/"
+	KeyWorkerLeader                 = "leader/worker/"
+	KeyNecoRebooterLeader           = "leader/neco-rebooter/"
+	KeyInfoPrefix                   = "info/"
+	KeyBootserversPrefix            = "info/bootservers/"
+	KeyNecoRelease                  = "info/neco-release"
+	KeySSHPubkey                    = "info/ssh-pubkey"
+	KeyStatusPrefix                 = "status/"
+	KeyCurrent                      = "status/current"
+	KeyWorkerStatusPrefix           = "status/bootservers/"
+	KeyContentsPrefix               = "contents/"
+	KeySabakanContents              = "contents/sabakan"
+	KeyCKEContents                  = "contents/cke"
+	KeyDHCPJSONContents             = "contents/dhcp.json"
+	KeyCKETemplateContents          = "contents/cke-template"
+	KeyUserResourcesContents        = "contents/user-resources"
+	KeyConfigPrefix                 = "config/"
+	KeyNotificationSlack            = "config/notification/slack"
+	KeyNotificationWebhook          = "config/notification/webhook"
+	KeyNotificationWebhookTemplate  = "config/notification/webhook-template"
+	KeyNotificationTeams            = "config/notification/teams"
+	KeyNotificationAlertmanager     = "config/notification/alertmanager"
+	KeyProxy                        = "config/proxy"
+	KeyEnv                          = "config/env"
+	KeyCheckUpdateInterval          = "config/check-update-interval"
+	KeyWorkerTimeout                = "config/worker-timeout"
+	KeyGitHubToken                  = "config/github-token"
+	KeyNodeProxy                    = "config/node-proxy"
+	KeyExternalIPAddressBlock       = "config/external-ip-address-block"
+	KeyLBAddressBlockDefault        = "config/lb-address-block-default"
+	KeyLBAddressBlockBastion        = "config/lb-address-block-bastion"
+	KeyLBAddressBlockInternet       = "config/lb-address-block-internet"
+	KeyLBAddressBlockInternetCN     = "config/lb-address-block-internet-cn"
+	KeyReleaseTime                  = "config/release-time"
+	KeyReleaseTimeZone              = "config/release-timezone"
+	KeyCanaryServers                = "config/canary-servers"
+	KeyCanarySoakPeriod             = "config/canary-soak-period"
+	KeyCanaryProbeURL               = "config/canary-probe-url"
+	KeyAutoRollback                 = "config/auto-rollback"
+	KeyHistoryRetention             = "config/history-retention"
+	KeyDenyWindows                  = "config/deny-windows"
+	KeyLastCompletedRelease         = "rollback/last-completed"
+	KeyRollbackRecord               = "rollback/record"
+	KeyHistoryPrefix                = "history/"
+	KeyHistoryRecordsPrefix         = "history/records/"
+	KeyHistoryStepsPrefix           = "history/steps/"
+	KeyUpdaterPrefix                = "updater/"
+	KeyUpdateFreeze                 = "updater/freeze"
+	KeyUpdatePin                    = "updater/pin"
+	KeyVaultUnsealKey               = "vault-unseal-key"
+	KeyVaultRootToken               = "vault-root-token"
+	KeyFinishPrefix                 = "finish/"
+	KeyContainersFormat             = "install/%d/containers/%s"
+	KeyDebsFormat                   = "install/%d/debs/%s"
+	KeyInstallPrefix                = "install/"
+	KeyBMCBMCUser                   = "bmc/bmc-user"
+	KeyBMCIPMIUser                  = "bmc/ipmi-user"
+	KeyBMCIPMIPassword              = "bmc/ipmi-password"
+	KeyBMCRepairUser                = "bmc/repair-user"
+	KeyBMCRepairPassword            = "bmc/repair-password"
+	KeyTeleportAuthToken            = "teleport/auth-token"
+	KeyCKEWeight                    = "cke/weight"
+	KeyNecoRebooterPrefix           = "neco-rebooter/"
+	KeyNecoRebooterRebootList       = "neco-rebooter/reboot-list/"
+	KeyNecoRebooterWriteIndex       = "neco-rebooter/write-index"
+	KeyNecoRebooterProcessingGroup  = "neco-rebooter/processing-group"
+	KeyNecoRebooterIsEnabled        = "neco-rebooter/is-enabled"
+	KeyNecoRebooterGroupOrder       = "neco-rebooter/group-order"
+	KeyNecoRebooterPauseReason      = "neco-rebooter/pause-reason"
+	KeyNecoRebooterHistoryPrefix    = "neco-rebooter/history/"
+	KeySabakanStateSetterUnhealthy  = "sabakan-state-setter/unhealthy/"
+	KeySabakanStateSetterBreaker    = "sabakan-state-setter/circuit-breaker"
+	KeySabakanStateSetterBreakerAck = "sabakan-state-setter/circuit-breaker-ack"
 )
 
 func keyBootServer(lrn int) string {
@@ -81,3 +106,15 @@ func keyContainer(lrn int, name string) string {
 func keyDeb(lrn int, name string) string {
 	return fmt.Sprintf(KeyDebsFormat, lrn, name)
 }
+
+func keyHistoryRecord(id string) string {
+	return KeyHistoryRecordsPrefix + id
+}
+
+func keyHistorySteps(id string) string {
+	return KeyHistoryStepsPrefix + id + "/"
+}
+
+func keyHistoryStep(id string, lrn, step int) string {
+	return fmt.Sprintf("%s%d/%d", keyHistorySteps(id), lrn, step)
+}
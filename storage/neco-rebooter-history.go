@@ -0,0 +1,100 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/cybozu-go/neco"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/client/v3/clientv3util"
+)
+
+// keyRebootListEvent returns the key of the event.
+// Keys are sortable in the order of the time of events.
+func keyRebootListEvent(ev neco.RebootListEvent) string {
+	return fmt.Sprintf("%s%019d-%016x", KeyNecoRebooterHistoryPrefix, ev.Time.UnixNano(), ev.Index)
+}
+
+// RecordRebootListEvents records audit events of the reboot list.
+func (s Storage) RecordRebootListEvents(ctx context.Context, events ...neco.RebootListEvent) error {
+	ops := make([]clientv3.Op, 0, len(events))
+	for _, ev := range events {
+		data, err := json.Marshal(ev)
+		if err != nil {
+			return err
+		}
+		ops = append(ops, clientv3.OpPut(keyRebootListEvent(ev), string(data)))
+	}
+	for len(ops) > 0 {
+		n := min(len(ops), rebootListTxnBatchSize)
+		_, err := s.etcd.Txn(ctx).Then(ops[:n]...).Commit()
+		if err != nil {
+			return err
+		}
+		ops = ops[n:]
+	}
+	return nil
+}
+
+// GetRebootListEvents returns the audit events of the reboot list in the order of time.
+// If node is not empty, only the events of the node are returned.
+func (s Storage) GetRebootListEvents(ctx context.Context, node string) ([]*neco.RebootListEvent, error) {
+	resp, err := s.etcd.Get(ctx, KeyNecoRebooterHistoryPrefix,
+		clientv3.WithPrefix(),
+		clientv3.WithSort(clientv3.SortByKey, clientv3.SortAscend))
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]*neco.RebootListEvent, 0, resp.Count)
+	for _, kv := range resp.Kvs {
+		ev := new(neco.RebootListEvent)
+		err = json.Unmarshal(kv.Value, ev)
+		if err != nil {
+			return nil, err
+		}
+		if node != "" && ev.Node != node {
+			continue
+		}
+		events = append(events, ev)
+	}
+	return events, nil
+}
+
+// PruneRebootListEvents deletes the audit events recorded before the given time.
+// This returns the number of deleted events.  Not all of the old events
+// may be deleted at once.
+// leaderKey is the current leader key.
+// If the caller has lost the leadership, this returns ErrNoLeader.
+func (s Storage) PruneRebootListEvents(ctx context.Context, before time.Time, leaderKey string) (int, error) {
+	end := fmt.Sprintf("%s%019d", KeyNecoRebooterHistoryPrefix, before.UnixNano())
+	// limit the number of events to keep the transaction small enough.
+	resp, err := s.etcd.Get(ctx, KeyNecoRebooterHistoryPrefix,
+		clientv3.WithRange(end),
+		clientv3.WithKeysOnly(),
+		clientv3.WithLimit(rebootListTxnBatchSize))
+	if err != nil {
+		return 0, err
+	}
+	if len(resp.Kvs) == 0 {
+		return 0, nil
+	}
+
+	ops := make([]clientv3.Op, 0, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		ops = append(ops, clientv3.OpDelete(string(kv.Key)))
+	}
+	txnResp, err := s.etcd.Txn(ctx).
+		If(clientv3util.KeyExists(leaderKey)).
+		Then(ops...).
+		Commit()
+	if err != nil {
+		return 0, err
+	}
+	if !txnResp.Succeeded {
+		return 0, ErrNoLeader
+	}
+	return len(resp.Kvs), nil
+}
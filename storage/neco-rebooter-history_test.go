@@ -0,0 +1,95 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cybozu-go/neco"
+	"github.com/cybozu-go/neco/storage/test"
+	"go.etcd.io/etcd/client/v3/concurrency"
+)
+
+func TestRebootListEvents(t *testing.T) {
+	t.Parallel()
+
+	etcd := test.NewEtcdClient(t)
+	defer etcd.Close()
+	ctx := context.Background()
+	st := NewStorage(etcd)
+
+	sess, err := concurrency.NewSession(etcd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sess.Close()
+	e := concurrency.NewElection(sess, KeyNecoRebooterLeader)
+	err = e.Campaign(ctx, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaderKey := e.Key()
+
+	now := time.Now().UTC()
+	entry1 := &neco.RebootListEntry{Index: 1, Node: "10.69.0.4", Group: "rack0", RebootTime: "cs"}
+	entry2 := &neco.RebootListEntry{Index: 2, Node: "10.69.0.5", Group: "rack0", RebootTime: "cs"}
+	old := neco.NewRebootListEvent(entry1, neco.RebootListEventAdded, "alice", "")
+	old.Time = now.Add(-48 * time.Hour)
+	queued := neco.NewRebootListEvent(entry1, neco.RebootListEventQueued, "neco-rebooter@boot-0", "")
+	queued.Time = now.Add(-time.Hour)
+	added := neco.NewRebootListEvent(entry2, neco.RebootListEventAdded, "bob", "")
+	added.Time = now.Add(-2 * time.Hour)
+	completed := neco.NewRebootListEvent(entry1, neco.RebootListEventCompleted, "neco-rebooter@boot-0", "")
+	completed.Time = now
+
+	err = st.RecordRebootListEvents(ctx, completed, old, queued)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = st.RecordRebootListEvents(ctx, added)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events, err := st.GetRebootListEvents(ctx, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 4 {
+		t.Fatal("unexpected events", events)
+	}
+	expected := []string{neco.RebootListEventAdded, neco.RebootListEventAdded, neco.RebootListEventQueued, neco.RebootListEventCompleted}
+	for i, ev := range events {
+		if ev.Type != expected[i] {
+			t.Errorf("events[%d] is not expected: %v", i, ev)
+		}
+	}
+
+	events, err = st.GetRebootListEvents(ctx, "10.69.0.4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 3 || events[0].Actor != "alice" || !events[2].Time.Equal(now) {
+		t.Error("unexpected events of the node", events)
+	}
+
+	n, err := st.PruneRebootListEvents(ctx, now.Add(-24*time.Hour), leaderKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Error("one event should be pruned", n)
+	}
+	events, err = st.GetRebootListEvents(ctx, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 3 || events[0].Node != "10.69.0.5" {
+		t.Error("old event should be pruned", events)
+	}
+
+	_, err = st.PruneRebootListEvents(ctx, now, "no-leader")
+	if err != ErrNoLeader {
+		t.Error("pruning without leadership should fail", err)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"strconv"
+	"time"
 
 	"github.com/cybozu-go/neco"
 	clientv3 "go.etcd.io/etcd/client/v3"
@@ -33,10 +34,47 @@ func (s Storage) EnableNecoRebooter(ctx context.Context, flag bool) error {
 	} else {
 		val = "false"
 	}
-	_, err := s.etcd.Put(ctx, KeyNecoRebooterIsEnabled, val)
+	_, err := s.etcd.Txn(ctx).
+		Then(
+			clientv3.OpPut(KeyNecoRebooterIsEnabled, val),
+			clientv3.OpDelete(KeyNecoRebooterPauseReason),
+		).
+		Commit()
 	return err
 }
 
+// PauseNecoRebooter disables reboot list processing and records the reason.
+// This is used by the leader of neco-rebooter to stop processing by itself.
+func (s Storage) PauseNecoRebooter(ctx context.Context, leaderKey, reason string) error {
+	resp, err := s.etcd.Txn(ctx).
+		If(clientv3util.KeyExists(leaderKey)).
+		Then(
+			clientv3.OpPut(KeyNecoRebooterIsEnabled, "false"),
+			clientv3.OpPut(KeyNecoRebooterPauseReason, reason),
+		).
+		Commit()
+	if err != nil {
+		return err
+	}
+	if !resp.Succeeded {
+		return ErrNoLeader
+	}
+	return nil
+}
+
+// GetNecoRebooterPauseReason returns the reason why neco-rebooter paused itself.
+// An empty string is returned if neco-rebooter has not been paused by itself.
+func (s Storage) GetNecoRebooterPauseReason(ctx context.Context) (string, error) {
+	resp, err := s.etcd.Get(ctx, KeyNecoRebooterPauseReason)
+	if err != nil {
+		return "", err
+	}
+	if resp.Count == 0 {
+		return "", nil
+	}
+	return string(resp.Kvs[0].Value), nil
+}
+
 func (s Storage) GetRebootListEntry(ctx context.Context, index int64) (*neco.RebootListEntry, error) {
 	resp, err := s.etcd.Get(ctx, rebootListEntryKey(index))
 	if err != nil {
@@ -220,6 +258,44 @@ RETRY:
 	return nil
 }
 
+// GetGroupOrder returns the expected order of groups recorded by neco-rebooter.
+// The first group is the processing group.
+func (s Storage) GetGroupOrder(ctx context.Context) ([]string, error) {
+	resp, err := s.etcd.Get(ctx, KeyNecoRebooterGroupOrder)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Count == 0 {
+		return nil, nil
+	}
+
+	var groups []string
+	err = json.Unmarshal(resp.Kvs[0].Value, &groups)
+	if err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
+// UpdateGroupOrder records the expected order of groups.
+func (s Storage) UpdateGroupOrder(ctx context.Context, leaderKey string, groups []string) error {
+	data, err := json.Marshal(groups)
+	if err != nil {
+		return err
+	}
+	resp, err := s.etcd.Txn(ctx).
+		If(clientv3util.KeyExists(leaderKey)).
+		Then(clientv3.OpPut(KeyNecoRebooterGroupOrder, string(data))).
+		Commit()
+	if err != nil {
+		return err
+	}
+	if !resp.Succeeded {
+		return ErrNoLeader
+	}
+	return nil
+}
+
 func (s Storage) GetNecoRebooterLeader(ctx context.Context) (string, error) {
 	session, err := concurrency.NewSession(s.etcd)
 	if err != nil {
@@ -244,3 +320,92 @@ func (s Storage) GetNecoRebooterLeader(ctx context.Context) (string, error) {
 	}
 	return string(leader.Kvs[0].Value), nil
 }
+
+// rebootListTxnBatchSize is the maximum number of entries updated in a transaction.
+// etcd limits the number of operations in a transaction to 128 by default.
+const rebootListTxnBatchSize = 100
+
+// updateRebootListEntries applies update to the reboot list entries selected by sel.
+// update returns false if the entry needs not to be updated.
+// Entries are updated in batches, each of which is done in a transaction
+// that fails if any of the entries in the batch has been modified or removed.
+// The selection is evaluated again when a transaction fails.
+func (s Storage) updateRebootListEntries(ctx context.Context, sel *neco.RebootListEntrySelector, update func(*neco.RebootListEntry) bool) ([]*neco.RebootListEntry, error) {
+	updated := []*neco.RebootListEntry{}
+
+RETRY:
+	resp, err := s.etcd.Get(ctx, KeyNecoRebooterRebootList,
+		clientv3.WithPrefix(),
+		clientv3.WithSort(clientv3.SortByKey, clientv3.SortAscend))
+	if err != nil {
+		return nil, err
+	}
+
+	var cmps []clientv3.Cmp
+	var ops []clientv3.Op
+	var batch []*neco.RebootListEntry
+	for _, kv := range resp.Kvs {
+		r := new(neco.RebootListEntry)
+		err = json.Unmarshal(kv.Value, r)
+		if err != nil {
+			return nil, err
+		}
+		if !sel.Match(r) || !update(r) {
+			continue
+		}
+		data, err := json.Marshal(r)
+		if err != nil {
+			return nil, err
+		}
+		key := string(kv.Key)
+		cmps = append(cmps, clientv3.Compare(clientv3.ModRevision(key), "=", kv.ModRevision))
+		ops = append(ops, clientv3.OpPut(key, string(data)))
+		batch = append(batch, r)
+		if len(batch) == rebootListTxnBatchSize {
+			break
+		}
+	}
+	if len(batch) == 0 {
+		return updated, nil
+	}
+
+	txnResp, err := s.etcd.Txn(ctx).If(cmps...).Then(ops...).Commit()
+	if err != nil {
+		return nil, err
+	}
+	if txnResp.Succeeded {
+		updated = append(updated, batch...)
+	}
+	// Retry to update the rest of entries or the entries modified concurrently.
+	goto RETRY
+}
+
+// CancelRebootListEntries cancels the reboot list entries selected by sel.
+// This returns the cancelled entries.
+func (s Storage) CancelRebootListEntries(ctx context.Context, sel *neco.RebootListEntrySelector) ([]*neco.RebootListEntry, error) {
+	return s.updateRebootListEntries(ctx, sel, func(r *neco.RebootListEntry) bool {
+		if r.Status == neco.RebootListEntryStatusCancelled {
+			return false
+		}
+		r.Status = neco.RebootListEntryStatusCancelled
+		return true
+	})
+}
+
+// PostponeRebootListEntries moves the reboot list entries selected by sel back to Pending,
+// and prevents them from being processed until the given time.
+// Cancelled entries are not postponed.  This returns the postponed entries.
+func (s Storage) PostponeRebootListEntries(ctx context.Context, sel *neco.RebootListEntrySelector, until time.Time) ([]*neco.RebootListEntry, error) {
+	until = until.UTC()
+	return s.updateRebootListEntries(ctx, sel, func(r *neco.RebootListEntry) bool {
+		if r.Status == neco.RebootListEntryStatusCancelled {
+			return false
+		}
+		if r.Status == neco.RebootListEntryStatusPending && r.PostponedUntil != nil && r.PostponedUntil.Equal(until) {
+			return false
+		}
+		r.Status = neco.RebootListEntryStatusPending
+		r.PostponedUntil = &until
+		return true
+	})
+}
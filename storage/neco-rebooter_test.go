@@ -0,0 +1,76 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cybozu-go/neco"
+	"github.com/cybozu-go/neco/storage/test"
+)
+
+func TestRebootListEntriesBySelector(t *testing.T) {
+	t.Parallel()
+
+	etcd := test.NewEtcdClient(t)
+	defer etcd.Close()
+	ctx := context.Background()
+	st := NewStorage(etcd)
+
+	entries := []neco.RebootListEntry{
+		{Node: "10.69.0.4", Group: "rack0", RebootTime: "cs", Status: neco.RebootListEntryStatusPending},
+		{Node: "10.69.0.5", Group: "rack0", RebootTime: "ss", Status: neco.RebootListEntryStatusQueued},
+		{Node: "10.69.1.4", Group: "rack1", RebootTime: "cs", Status: neco.RebootListEntryStatusQueued},
+		{Node: "10.69.1.5", Group: "rack1", RebootTime: "cs", Status: neco.RebootListEntryStatusPending},
+	}
+	for i := range entries {
+		err := st.RegisterRebootListEntry(ctx, &entries[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	until := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	postponed, err := st.PostponeRebootListEntries(ctx, &neco.RebootListEntrySelector{NodePattern: "10.69.1.*"}, until)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(postponed) != 2 {
+		t.Fatal("unexpected postponed entries", postponed)
+	}
+	postponed, err = st.PostponeRebootListEntries(ctx, &neco.RebootListEntrySelector{NodePattern: "10.69.1.*"}, until)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(postponed) != 0 {
+		t.Error("entries should not be postponed twice", postponed)
+	}
+
+	cancelled, err := st.CancelRebootListEntries(ctx, &neco.RebootListEntrySelector{Group: "rack0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cancelled) != 2 {
+		t.Fatal("unexpected cancelled entries", cancelled)
+	}
+	cancelled, err = st.CancelRebootListEntries(ctx, &neco.RebootListEntrySelector{RebootTime: "cs"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cancelled) != 2 {
+		t.Fatal("already cancelled entries should be skipped", cancelled)
+	}
+
+	actual, err := st.GetRebootListEntries(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range actual {
+		if entry.Status != neco.RebootListEntryStatusCancelled {
+			t.Error("entry is not cancelled", entry)
+		}
+		if entry.Group == "rack1" && (entry.PostponedUntil == nil || !entry.PostponedUntil.Equal(until)) {
+			t.Error("entry is not postponed", entry)
+		}
+	}
+}
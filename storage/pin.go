@@ -0,0 +1,21 @@
+package storage
+
+import (
+	"context"
+)
+
+// PutUpdatePin pins the version of neco to be installed.
+func (s Storage) PutUpdatePin(ctx context.Context, version string) error {
+	return s.put(ctx, KeyUpdatePin, version)
+}
+
+// GetUpdatePin returns the pinned version.
+// If no version is pinned, this returns ErrNotFound.
+func (s Storage) GetUpdatePin(ctx context.Context) (string, error) {
+	return s.get(ctx, KeyUpdatePin)
+}
+
+// DeleteUpdatePin removes the pinned version.
+func (s Storage) DeleteUpdatePin(ctx context.Context) error {
+	return s.del(ctx, KeyUpdatePin)
+}
@@ -0,0 +1,52 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cybozu-go/neco/storage/test"
+)
+
+func TestUpdatePin(t *testing.T) {
+	t.Parallel()
+
+	etcd := test.NewEtcdClient(t)
+	defer etcd.Close()
+	ctx := context.Background()
+	st := NewStorage(etcd)
+
+	_, err := st.GetUpdatePin(ctx)
+	if err != ErrNotFound {
+		t.Error("unexpected error", err)
+	}
+
+	err = st.PutUpdatePin(ctx, "2024.12.01-12345")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pinned, err := st.GetUpdatePin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pinned != "2024.12.01-12345" {
+		t.Error("unexpected pinned version", pinned)
+	}
+
+	ss, err := st.NewSnapshot(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ss.Pinned != "2024.12.01-12345" {
+		t.Error("unexpected ss.Pinned", ss.Pinned)
+	}
+
+	err = st.DeleteUpdatePin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = st.GetUpdatePin(ctx)
+	if err != ErrNotFound {
+		t.Error("unexpected error", err)
+	}
+}
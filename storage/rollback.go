@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/cybozu-go/neco"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/client/v3/clientv3util"
+)
+
+// PutLastCompletedRelease records the version of the release which has been
+// installed on all boot servers.
+// leaderKey is the current leader key.
+// If the caller has lost the leadership, this returns ErrNoLeader.
+func (s Storage) PutLastCompletedRelease(ctx context.Context, version, leaderKey string) error {
+	resp, err := s.etcd.Txn(ctx).
+		If(clientv3util.KeyExists(leaderKey)).
+		Then(clientv3.OpPut(KeyLastCompletedRelease, version)).
+		Commit()
+	if err != nil {
+		return err
+	}
+	if !resp.Succeeded {
+		return ErrNoLeader
+	}
+	return nil
+}
+
+// GetLastCompletedRelease returns the version of the last completed release.
+// If no release has been completed, this returns ErrNotFound.
+func (s Storage) GetLastCompletedRelease(ctx context.Context) (string, error) {
+	return s.get(ctx, KeyLastCompletedRelease)
+}
+
+// PutRollbackRequest stores UpdateRequest to roll back a failed update
+// together with RollbackRecord, and deletes worker statuses in a single
+// transaction.
+// leaderKey is the current leader key.
+// If the caller has lost the leadership, this returns ErrNoLeader.
+func (s Storage) PutRollbackRequest(ctx context.Context, req neco.UpdateRequest, rec neco.RollbackRecord, leaderKey string) error {
+	reqData, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	recData, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+
+	resp, err := s.etcd.Txn(ctx).
+		If(clientv3util.KeyExists(leaderKey)).
+		Then(
+			clientv3.OpPut(KeyCurrent, string(reqData)),
+			clientv3.OpPut(KeyRollbackRecord, string(recData)),
+			clientv3.OpDelete(KeyWorkerStatusPrefix, clientv3.WithPrefix()),
+		).
+		Commit()
+	if err != nil {
+		return err
+	}
+
+	if !resp.Succeeded {
+		return ErrNoLeader
+	}
+
+	return nil
+}
+
+// GetRollbackRecord returns the record of the last automatic rollback.
+// If no rollback has been done, this returns ErrNotFound.
+func (s Storage) GetRollbackRecord(ctx context.Context) (*neco.RollbackRecord, error) {
+	data, err := s.get(ctx, KeyRollbackRecord)
+	if err != nil {
+		return nil, err
+	}
+
+	rec := new(neco.RollbackRecord)
+	err = json.Unmarshal([]byte(data), rec)
+	if err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
@@ -0,0 +1,109 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cybozu-go/neco"
+	"github.com/cybozu-go/neco/storage/test"
+	"github.com/google/go-cmp/cmp"
+	"go.etcd.io/etcd/client/v3/concurrency"
+)
+
+func TestRollback(t *testing.T) {
+	t.Parallel()
+
+	etcd := test.NewEtcdClient(t)
+	defer etcd.Close()
+	ctx := context.Background()
+	st := NewStorage(etcd)
+
+	_, err := st.GetLastCompletedRelease(ctx)
+	if err != ErrNotFound {
+		t.Error("last completed release should not exist")
+	}
+	_, err = st.GetRollbackRecord(ctx)
+	if err != ErrNotFound {
+		t.Error("rollback record should not exist")
+	}
+
+	sess, err := concurrency.NewSession(etcd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sess.Close()
+	e := concurrency.NewElection(sess, KeyUpdaterLeader)
+	err = e.Campaign(ctx, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaderKey := e.Key()
+
+	err = st.PutLastCompletedRelease(ctx, "0.9.0", leaderKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ver, err := st.GetLastCompletedRelease(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ver != "0.9.0" {
+		t.Error("unexpected last completed release", ver)
+	}
+
+	err = st.PutStatus(ctx, 0, neco.UpdateStatus{Version: "1.0.0", Step: 2, Cond: neco.CondAbort, Message: "failed"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	req := neco.UpdateRequest{Version: "0.9.0", Servers: []int{0, 1}, RollbackFrom: "1.0.0", StartedAt: now}
+	rec := neco.RollbackRecord{
+		FailedVersion: "1.0.0",
+		Version:       "0.9.0",
+		Servers:       []int{0, 1},
+		Reasons:       map[int]string{0: "failed"},
+		StartedAt:     now,
+	}
+	err = st.PutRollbackRequest(ctx, req, rec, leaderKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req2, err := st.GetRequest(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cmp.Equal(req, *req2) {
+		t.Errorf("unexpected request. expected=%#v, actual=%#v", req, *req2)
+	}
+	rec2, err := st.GetRollbackRecord(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cmp.Equal(rec, *rec2) {
+		t.Errorf("unexpected record. expected=%#v, actual=%#v", rec, *rec2)
+	}
+	statuses, err := st.GetStatuses(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(statuses) != 0 {
+		t.Error("statuses should be deleted", statuses)
+	}
+
+	err = e.Resign(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = st.PutLastCompletedRelease(ctx, "1.0.0", leaderKey)
+	if err != ErrNoLeader {
+		t.Error("should lost leadership")
+	}
+	err = st.PutRollbackRequest(ctx, req, rec, leaderKey)
+	if err != ErrNoLeader {
+		t.Error("should lost leadership")
+	}
+}
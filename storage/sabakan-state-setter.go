@@ -0,0 +1,129 @@
+package storage
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/client/v3/clientv3util"
+)
+
+// GetUnhealthyMachines returns the times when the machines were first judged
+// as unhealthy by sabakan-state-setter.  The keys of the returned map are serials.
+func (s Storage) GetUnhealthyMachines(ctx context.Context) (map[string]time.Time, error) {
+	resp, err := s.etcd.Get(ctx, KeySabakanStateSetterUnhealthy, clientv3.WithPrefix())
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make(map[string]time.Time, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		serial := strings.TrimPrefix(string(kv.Key), KeySabakanStateSetterUnhealthy)
+		t, err := time.Parse(time.RFC3339Nano, string(kv.Value))
+		if err != nil {
+			return nil, err
+		}
+		ret[serial] = t
+	}
+	return ret, nil
+}
+
+// PutUnhealthyMachine records the time when the machine was first judged as unhealthy.
+// leaderKey is the current leader key of sabakan-state-setter.
+// If the caller has lost the leadership, this returns ErrNoLeader.
+func (s Storage) PutUnhealthyMachine(ctx context.Context, leaderKey, serial string, since time.Time) error {
+	resp, err := s.etcd.Txn(ctx).
+		If(clientv3util.KeyExists(leaderKey)).
+		Then(clientv3.OpPut(KeySabakanStateSetterUnhealthy+serial, since.UTC().Format(time.RFC3339Nano))).
+		Commit()
+	if err != nil {
+		return err
+	}
+	if !resp.Succeeded {
+		return ErrNoLeader
+	}
+	return nil
+}
+
+// DeleteUnhealthyMachine removes the record of the machine.
+// leaderKey is the current leader key of sabakan-state-setter.
+// If the caller has lost the leadership, this returns ErrNoLeader.
+func (s Storage) DeleteUnhealthyMachine(ctx context.Context, leaderKey, serial string) error {
+	resp, err := s.etcd.Txn(ctx).
+		If(clientv3util.KeyExists(leaderKey)).
+		Then(clientv3.OpDelete(KeySabakanStateSetterUnhealthy + serial)).
+		Commit()
+	if err != nil {
+		return err
+	}
+	if !resp.Succeeded {
+		return ErrNoLeader
+	}
+	return nil
+}
+
+// GetCircuitBreaker returns the reason why the circuit breaker of sabakan-state-setter has been tripped.
+// If the circuit breaker is not tripped, this returns ErrNotFound.
+func (s Storage) GetCircuitBreaker(ctx context.Context) (string, error) {
+	return s.get(ctx, KeySabakanStateSetterBreaker)
+}
+
+// TripCircuitBreaker records that the circuit breaker of sabakan-state-setter has been tripped,
+// and removes the previous acknowledgement.
+// leaderKey is the current leader key of sabakan-state-setter.
+// If the caller has lost the leadership, this returns ErrNoLeader.
+func (s Storage) TripCircuitBreaker(ctx context.Context, leaderKey, reason string) error {
+	resp, err := s.etcd.Txn(ctx).
+		If(clientv3util.KeyExists(leaderKey)).
+		Then(
+			clientv3.OpPut(KeySabakanStateSetterBreaker, reason),
+			clientv3.OpDelete(KeySabakanStateSetterBreakerAck),
+		).
+		Commit()
+	if err != nil {
+		return err
+	}
+	if !resp.Succeeded {
+		return ErrNoLeader
+	}
+	return nil
+}
+
+// AckCircuitBreaker acknowledges the tripped circuit breaker of sabakan-state-setter
+// to apply the pending state transitions.
+func (s Storage) AckCircuitBreaker(ctx context.Context) error {
+	return s.put(ctx, KeySabakanStateSetterBreakerAck, "true")
+}
+
+// IsCircuitBreakerAcked returns true if the circuit breaker has been acknowledged.
+func (s Storage) IsCircuitBreakerAcked(ctx context.Context) (bool, error) {
+	_, err := s.get(ctx, KeySabakanStateSetterBreakerAck)
+	if err == ErrNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ResetCircuitBreaker removes the tripped circuit breaker and its acknowledgement.
+// leaderKey is the current leader key of sabakan-state-setter.
+// If the caller has lost the leadership, this returns ErrNoLeader.
+func (s Storage) ResetCircuitBreaker(ctx context.Context, leaderKey string) error {
+	resp, err := s.etcd.Txn(ctx).
+		If(clientv3util.KeyExists(leaderKey)).
+		Then(
+			clientv3.OpDelete(KeySabakanStateSetterBreaker),
+			clientv3.OpDelete(KeySabakanStateSetterBreakerAck),
+		).
+		Commit()
+	if err != nil {
+		return err
+	}
+	if !resp.Succeeded {
+		return ErrNoLeader
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cybozu-go/neco/storage/test"
+	"go.etcd.io/etcd/client/v3/concurrency"
+)
+
+func TestUnhealthyMachines(t *testing.T) {
+	t.Parallel()
+
+	etcd := test.NewEtcdClient(t)
+	defer etcd.Close()
+	ctx := context.Background()
+	st := NewStorage(etcd)
+
+	sess, err := concurrency.NewSession(etcd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sess.Close()
+	e := concurrency.NewElection(sess, KeySabakanStateSetterLeader)
+	err = e.Campaign(ctx, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaderKey := e.Key()
+
+	machines, err := st.GetUnhealthyMachines(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(machines) != 0 {
+		t.Error("unhealthy machines should be empty", machines)
+	}
+
+	since := time.Date(2024, 1, 1, 0, 0, 0, 123, time.UTC)
+	err = st.PutUnhealthyMachine(ctx, leaderKey, "serial1", since)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = st.PutUnhealthyMachine(ctx, leaderKey, "serial2", since.Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	machines, err = st.GetUnhealthyMachines(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(machines) != 2 || !machines["serial1"].Equal(since) || !machines["serial2"].Equal(since.Add(time.Minute)) {
+		t.Error("unexpected unhealthy machines", machines)
+	}
+
+	err = st.DeleteUnhealthyMachine(ctx, leaderKey, "serial1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	machines, err = st.GetUnhealthyMachines(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(machines) != 1 || !machines["serial2"].Equal(since.Add(time.Minute)) {
+		t.Error("unexpected unhealthy machines after delete", machines)
+	}
+
+	err = e.Resign(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = st.PutUnhealthyMachine(ctx, leaderKey, "serial3", since)
+	if err != ErrNoLeader {
+		t.Error("should lost leadership")
+	}
+	err = st.DeleteUnhealthyMachine(ctx, leaderKey, "serial2")
+	if err != ErrNoLeader {
+		t.Error("should lost leadership")
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	t.Parallel()
+
+	etcd := test.NewEtcdClient(t)
+	defer etcd.Close()
+	ctx := context.Background()
+	st := NewStorage(etcd)
+
+	sess, err := concurrency.NewSession(etcd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sess.Close()
+	e := concurrency.NewElection(sess, KeySabakanStateSetterLeader)
+	err = e.Campaign(ctx, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaderKey := e.Key()
+
+	_, err = st.GetCircuitBreaker(ctx)
+	if err != ErrNotFound {
+		t.Error("circuit breaker should not be tripped", err)
+	}
+
+	err = st.AckCircuitBreaker(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = st.TripCircuitBreaker(ctx, leaderKey, "too many transitions")
+	if err != nil {
+		t.Fatal(err)
+	}
+	reason, err := st.GetCircuitBreaker(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reason != "too many transitions" {
+		t.Error("unexpected reason", reason)
+	}
+	acked, err := st.IsCircuitBreakerAcked(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if acked {
+		t.Error("stale acknowledgement should be removed by tripping")
+	}
+
+	err = st.AckCircuitBreaker(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	acked, err = st.IsCircuitBreakerAcked(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !acked {
+		t.Error("circuit breaker should be acknowledged")
+	}
+
+	err = st.ResetCircuitBreaker(ctx, leaderKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = st.GetCircuitBreaker(ctx)
+	if err != ErrNotFound {
+		t.Error("circuit breaker should be reset", err)
+	}
+	acked, err = st.IsCircuitBreakerAcked(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if acked {
+		t.Error("acknowledgement should be reset")
+	}
+
+	err = e.Resign(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = st.TripCircuitBreaker(ctx, leaderKey, "too many transitions")
+	if err != ErrNoLeader {
+		t.Error("should lost leadership")
+	}
+}
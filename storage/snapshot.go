@@ -4,6 +4,8 @@ import (
 	"context"
 	"encoding/json"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/cybozu-go/neco"
 	clientv3 "go.etcd.io/etcd/client/v3"
@@ -16,6 +18,36 @@ type Snapshot struct {
 	Statuses map[int]*neco.UpdateStatus
 	Latest   string
 	Servers  []int
+
+	// Canary is the list of canary boot servers configured by the user.
+	// This may contain boot servers that are not registered.
+	Canary []int
+
+	// SoakPeriod is the duration to watch the canary boot servers
+	// before rolling out the update to the rest.
+	SoakPeriod time.Duration
+
+	// CanaryProbeURL is the URL to probe the canary boot servers
+	// during the soak period.
+	CanaryProbeURL string
+
+	// AutoRollback is true if a failed update should be rolled back
+	// to LastCompleted automatically.
+	AutoRollback bool
+
+	// LastCompleted is the version of the last release which has been
+	// installed on all boot servers successfully.
+	LastCompleted string
+
+	// DenyWindows is the list of periods during which updates are not started.
+	DenyWindows []neco.DenyWindow
+
+	// Freeze is set while updates are frozen by the user.
+	Freeze *neco.UpdateFreeze
+
+	// Pinned is the version pinned by the user.  If not empty,
+	// this overrides Latest.
+	Pinned string
 }
 
 // NewSnapshot takes the up-to-date snapshot.
@@ -67,6 +99,95 @@ func (s Storage) NewSnapshot(ctx context.Context) (*Snapshot, error) {
 		snap.Latest = string(resp.Kvs[0].Value)
 	}
 
+	resp, err = s.etcd.Get(ctx, KeyCanaryServers, clientv3.WithRev(rev))
+	if err != nil {
+		return nil, err
+	}
+	if resp.Count > 0 {
+		canary, err := parseCanaryServers(resp.Kvs[0].Value)
+		if err != nil {
+			return nil, err
+		}
+		snap.Canary = canary
+	}
+
+	snap.SoakPeriod = DefaultCanarySoakPeriod
+	resp, err = s.etcd.Get(ctx, KeyCanarySoakPeriod, clientv3.WithRev(rev))
+	if err != nil {
+		return nil, err
+	}
+	if resp.Count > 0 {
+		i, err := strconv.ParseInt(string(resp.Kvs[0].Value), 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		snap.SoakPeriod = time.Duration(i)
+	}
+
+	resp, err = s.etcd.Get(ctx, KeyCanaryProbeURL, clientv3.WithRev(rev))
+	if err != nil {
+		return nil, err
+	}
+	if resp.Count > 0 {
+		snap.CanaryProbeURL = string(resp.Kvs[0].Value)
+	}
+
+	resp, err = s.etcd.Get(ctx, KeyAutoRollback, clientv3.WithRev(rev))
+	if err != nil {
+		return nil, err
+	}
+	if resp.Count > 0 {
+		enabled, err := strconv.ParseBool(string(resp.Kvs[0].Value))
+		if err != nil {
+			return nil, err
+		}
+		snap.AutoRollback = enabled
+	}
+
+	resp, err = s.etcd.Get(ctx, KeyLastCompletedRelease, clientv3.WithRev(rev))
+	if err != nil {
+		return nil, err
+	}
+	if resp.Count > 0 {
+		snap.LastCompleted = string(resp.Kvs[0].Value)
+	}
+
+	resp, err = s.etcd.Get(ctx, KeyDenyWindows, clientv3.WithRev(rev))
+	if err != nil {
+		return nil, err
+	}
+	if resp.Count > 0 {
+		loc, err := s.GetReleaseTimeLocation(ctx)
+		if err != nil {
+			return nil, err
+		}
+		windows, err := neco.ParseDenyWindows(strings.Split(string(resp.Kvs[0].Value), "\n"), loc)
+		if err != nil {
+			return nil, err
+		}
+		snap.DenyWindows = windows
+	}
+
+	resp, err = s.etcd.Get(ctx, KeyUpdateFreeze, clientv3.WithRev(rev))
+	if err != nil {
+		return nil, err
+	}
+	if resp.Count > 0 {
+		f, err := parseUpdateFreeze(resp.Kvs[0].Value)
+		if err != nil {
+			return nil, err
+		}
+		snap.Freeze = f
+	}
+
+	resp, err = s.etcd.Get(ctx, KeyUpdatePin, clientv3.WithRev(rev))
+	if err != nil {
+		return nil, err
+	}
+	if resp.Count > 0 {
+		snap.Pinned = string(resp.Kvs[0].Value)
+	}
+
 	statuses, err := s.getStatusesAt(ctx, rev)
 	if err != nil {
 		return nil, err
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"strconv"
+	"time"
 
 	"github.com/cybozu-go/neco"
 	clientv3 "go.etcd.io/etcd/client/v3"
@@ -250,3 +251,62 @@ RETRY:
 
 	return nil
 }
+
+// ResumeRequest resumes the stopped UpdateRequest from the step where
+// the update was aborted.
+//
+// It first checks that "stop" field in KeyCurrent is true.  If not,
+// ErrNotStopped will be returned.
+//
+// Then it puts the request with "stop" cleared and "resume_step" set,
+// and removes worker statuses in a single transaction.  Other status
+// and contents keys are kept as they are.
+func (s Storage) ResumeRequest(ctx context.Context) (*neco.UpdateRequest, error) {
+RETRY:
+	req, modRev, rev, err := s.GetRequestWithRev(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if !req.Stop {
+		return nil, ErrNotStopped
+	}
+
+	statuses, err := s.getStatusesAt(ctx, rev)
+	if err != nil {
+		return nil, err
+	}
+	step, err := neco.ResumeStep(req.Version, req.Members(), statuses)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Stop = false
+	req.ResumeStep = step
+	req.StartedAt = time.Now().UTC()
+	req.SoakStartedAt = nil
+	data, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.etcd.Txn(ctx).
+		If(
+			clientv3.Compare(clientv3.ModRevision(KeyCurrent), "=", modRev),
+			clientv3.Compare(clientv3.ModRevision(KeyWorkerStatusPrefix).WithPrefix(), "<", rev+1),
+		).
+		Then(
+			clientv3.OpPut(KeyCurrent, string(data)),
+			clientv3.OpDelete(KeyWorkerStatusPrefix, clientv3.WithPrefix()),
+		).
+		Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	if !resp.Succeeded {
+		goto RETRY
+	}
+
+	return req, nil
+}
@@ -231,6 +231,97 @@ func testClearStatusAndContents(t *testing.T) {
 	}
 }
 
+func testResumeRequest(t *testing.T) {
+	t.Parallel()
+
+	etcd := test.NewEtcdClient(t)
+	defer etcd.Close()
+	ctx := context.Background()
+	st := NewStorage(etcd)
+
+	_, err := st.ResumeRequest(ctx)
+	if err != ErrNotFound {
+		t.Error("unexpected error", err)
+	}
+
+	sess, err := concurrency.NewSession(etcd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sess.Close()
+	e := concurrency.NewElection(sess, KeyUpdaterLeader)
+	err = e.Campaign(ctx, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaderKey := e.Key()
+
+	req := neco.UpdateRequest{Version: "1.0.0", Servers: []int{0, 1}}
+	err = st.PutRequest(ctx, req, leaderKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = st.ResumeRequest(ctx)
+	if err != ErrNotStopped {
+		t.Error("unexpected error", err)
+	}
+
+	req.Stop = true
+	err = st.PutRequest(ctx, req, leaderKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = st.PutStatus(ctx, 0, neco.UpdateStatus{Version: "1.0.0", Step: 15, Cond: neco.CondAbort})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = st.ResumeRequest(ctx)
+	if err == nil {
+		t.Error("resume should fail when some boot servers have not started the update")
+	}
+
+	err = st.PutStatus(ctx, 1, neco.UpdateStatus{Version: "1.0.0", Step: 16, Cond: neco.CondAbort})
+	if err != nil {
+		t.Fatal(err)
+	}
+	reqContents := neco.ContentsUpdateStatus{Version: "1.0.0", Success: true}
+	err = st.PutSabakanContentsStatus(ctx, &reqContents, leaderKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resumed, err := st.ResumeRequest(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resumed.Stop || resumed.ResumeStep != 15 {
+		t.Error("unexpected resumed request", resumed)
+	}
+
+	current, err := st.GetRequest(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cmp.Equal(current, resumed) {
+		t.Error("unexpected current request", cmp.Diff(current, resumed))
+	}
+
+	statuses, err := st.GetStatuses(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(statuses) != 0 {
+		t.Error("worker statuses should have been cleared", statuses)
+	}
+
+	_, err = st.GetSabakanContentsStatus(ctx)
+	if err != nil {
+		t.Error("contents status should be kept", err)
+	}
+}
+
 func testFinish(t *testing.T) {
 	t.Parallel()
 
@@ -284,6 +375,7 @@ func TestStorage(t *testing.T) {
 	t.Run("Request", testRequest)
 	t.Run("Status", testStatus)
 	t.Run("ClearStatus", testClearStatusAndContents)
+	t.Run("ResumeRequest", testResumeRequest)
 	t.Run("Finish", testFinish)
 	t.Run("SabakanContentsStatus", testSabakanContentsStatus)
 }
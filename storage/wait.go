@@ -40,12 +40,12 @@ func (s Storage) WaitRequest(ctx context.Context, rev int64) (*neco.UpdateReques
 	return nil, 0, errors.New("waitRequest was interrupted")
 }
 
-// WaitRequestDeletion waits for a UpdateRequest to be deleted.
-func (s Storage) WaitRequestDeletion(ctx context.Context, rev int64) error {
+// WaitRequestChange waits for a UpdateRequest to be deleted or updated.
+func (s Storage) WaitRequestChange(ctx context.Context, rev int64) error {
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	ch := s.etcd.Watch(ctx, KeyCurrent, clientv3.WithRev(rev+1), clientv3.WithFilterPut())
+	ch := s.etcd.Watch(ctx, KeyCurrent, clientv3.WithRev(rev+1))
 	resp := <-ch
 	if err := resp.Err(); err != nil {
 		return err
@@ -1,6 +1,10 @@
 package neco
 
-import "time"
+import (
+	"errors"
+	"fmt"
+	"time"
+)
 
 // Environments to use release or pre-release neco
 const (
@@ -11,17 +15,74 @@ const (
 	ProdEnv    = "prod"
 )
 
+// UpdateWave is the wave of a staged rollout.
+type UpdateWave string
+
+// Possible update waves.
+const (
+	// WaveAll updates all boot servers at once.
+	WaveAll UpdateWave = ""
+
+	// WaveCanary updates only the canary boot servers.
+	WaveCanary UpdateWave = "canary"
+
+	// WaveRollout updates the rest of boot servers after the canary wave succeeded.
+	WaveRollout UpdateWave = "rollout"
+)
+
+// String implements io.Stringer
+func (w UpdateWave) String() string {
+	if w == WaveAll {
+		return "all"
+	}
+	return string(w)
+}
+
 // UpdateRequest represents request from neco-updater
 type UpdateRequest struct {
-	Version   string    `json:"version"`
-	Servers   []int     `json:"servers"`
-	Stop      bool      `json:"stop"`
-	StartedAt time.Time `json:"started_at"`
+	Version string `json:"version"`
+
+	// Servers is the list of all boot servers in the cluster.
+	// This is used to generate configuration files of etcd, vault, and so on.
+	Servers []int `json:"servers"`
+
+	// Targets is the list of boot servers to be updated by this request.
+	// If empty, all Servers are updated.
+	Targets []int `json:"targets,omitempty"`
+
+	Wave          UpdateWave `json:"wave,omitempty"`
+	Stop          bool       `json:"stop"`
+	StartedAt     time.Time  `json:"started_at"`
+	SoakStartedAt *time.Time `json:"soak_started_at,omitempty"`
+
+	// RollbackFrom is the version of the failed update if this request
+	// rolls back boot servers to the last completed release.
+	RollbackFrom string `json:"rollback_from,omitempty"`
+
+	// ResumeStep is the step from which workers resume an aborted update.
+	// If zero, workers start the update from step 1.
+	ResumeStep int `json:"resume_step,omitempty"`
+}
+
+// StartStep returns the step from which workers start this request.
+func (r UpdateRequest) StartStep() int {
+	if r.ResumeStep > 0 {
+		return r.ResumeStep
+	}
+	return 1
+}
+
+// Members returns the list of boot servers updated by this request.
+func (r UpdateRequest) Members() []int {
+	if len(r.Targets) > 0 {
+		return r.Targets
+	}
+	return r.Servers
 }
 
 // IsMember returns true if a boot server is the member of this update request.
 func (r UpdateRequest) IsMember(lrn int) bool {
-	for _, n := range r.Servers {
+	for _, n := range r.Members() {
 		if n == lrn {
 			return true
 		}
@@ -59,10 +120,48 @@ func (c UpdateCondition) String() string {
 
 // UpdateStatus represents status report from neco-worker
 type UpdateStatus struct {
-	Version string          `json:"version"`
-	Step    int             `json:"step"`
-	Cond    UpdateCondition `json:"cond"`
-	Message string          `json:"message"`
+	Version  string          `json:"version"`
+	Step     int             `json:"step"`
+	StepName string          `json:"step_name,omitempty"`
+	Cond     UpdateCondition `json:"cond"`
+	Message  string          `json:"message"`
+}
+
+// RollbackRecord represents an automatic rollback done by neco-updater.
+type RollbackRecord struct {
+	FailedVersion string         `json:"failed_version"`
+	Version       string         `json:"version"`
+	Servers       []int          `json:"servers"`
+	Reasons       map[int]string `json:"reasons"`
+	StartedAt     time.Time      `json:"started_at"`
+}
+
+// UpdateHistory represents a record of an update process.
+type UpdateHistory struct {
+	ID           string          `json:"id"`
+	Version      string          `json:"version"`
+	Servers      []int           `json:"servers"`
+	Wave         UpdateWave      `json:"wave,omitempty"`
+	RollbackFrom string          `json:"rollback_from,omitempty"`
+	ResumeStep   int             `json:"resume_step,omitempty"`
+	StartedAt    time.Time       `json:"started_at"`
+	EndedAt      *time.Time      `json:"ended_at,omitempty"`
+	Cond         UpdateCondition `json:"cond"`
+	Message      string          `json:"message,omitempty"`
+
+	// Steps is the list of step records for each boot server.
+	// This is filled only by storage.GetHistory.
+	Steps map[int][]HistoryStep `json:"steps,omitempty"`
+}
+
+// HistoryStep represents a record of an update step run by a boot server.
+type HistoryStep struct {
+	Step      int             `json:"step"`
+	Name      string          `json:"name,omitempty"`
+	StartedAt time.Time       `json:"started_at"`
+	EndedAt   time.Time       `json:"ended_at"`
+	Cond      UpdateCondition `json:"cond"`
+	Message   string          `json:"message,omitempty"`
 }
 
 // UpdateCompleted returns true if the current update process has
@@ -86,6 +185,33 @@ func UpdateCompleted(version string, lrns []int, statuses map[int]*UpdateStatus)
 	return true
 }
 
+// ResumeStep returns the step from which an aborted update can be resumed.
+//
+// Every boot server in lrns must have a status of the version.
+// Boot servers have completed the steps before their current steps,
+// so the update can be resumed from the smallest current step among
+// boot servers which have not completed the update.
+func ResumeStep(version string, lrns []int, statuses map[int]*UpdateStatus) (int, error) {
+	step := 0
+	for _, lrn := range lrns {
+		st, ok := statuses[lrn]
+		if !ok || st.Version != version {
+			return 0, fmt.Errorf("boot server %d has not started the update to %s", lrn, version)
+		}
+		if st.Cond == CondComplete {
+			continue
+		}
+		if step == 0 || st.Step < step {
+			step = st.Step
+		}
+	}
+
+	if step == 0 {
+		return 0, errors.New("all boot servers have completed the update")
+	}
+	return step, nil
+}
+
 // ContentsUpdateStatus represents update status of uploaded assets.
 type ContentsUpdateStatus struct {
 	Version string `json:"version"`
@@ -30,3 +30,98 @@ func TestUpdateStatus(t *testing.T) {
 		t.Errorf("st != st2, %+v", st2)
 	}
 }
+
+func TestUpdateRequestMembers(t *testing.T) {
+	req := UpdateRequest{
+		Version: "1.2.3",
+		Servers: []int{0, 1, 2},
+	}
+	if !cmp.Equal(req.Members(), []int{0, 1, 2}) {
+		t.Error("unexpected members", req.Members())
+	}
+	if !req.IsMember(1) {
+		t.Error("1 should be a member")
+	}
+
+	req.Targets = []int{2}
+	req.Wave = WaveCanary
+	if !cmp.Equal(req.Members(), []int{2}) {
+		t.Error("unexpected members", req.Members())
+	}
+	if req.IsMember(1) {
+		t.Error("1 should not be a member")
+	}
+	if !req.IsMember(2) {
+		t.Error("2 should be a member")
+	}
+}
+
+func TestResumeStep(t *testing.T) {
+	testCases := []struct {
+		name     string
+		statuses map[int]*UpdateStatus
+		expected int
+		valid    bool
+	}{
+		{
+			name: "aborted",
+			statuses: map[int]*UpdateStatus{
+				0: {Version: "1.2.3", Step: 15, Cond: CondAbort},
+				1: {Version: "1.2.3", Step: 16, Cond: CondAbort},
+				2: {Version: "1.2.3", Step: 15, Cond: CondRunning},
+			},
+			expected: 15,
+			valid:    true,
+		},
+		{
+			name: "partially-completed",
+			statuses: map[int]*UpdateStatus{
+				0: {Version: "1.2.3", Step: 18, Cond: CondComplete},
+				1: {Version: "1.2.3", Step: 18, Cond: CondAbort},
+				2: {Version: "1.2.3", Step: 18, Cond: CondAbort},
+			},
+			expected: 18,
+			valid:    true,
+		},
+		{
+			name: "not-started",
+			statuses: map[int]*UpdateStatus{
+				0: {Version: "1.2.3", Step: 3, Cond: CondAbort},
+				1: {Version: "1.2.3", Step: 3, Cond: CondAbort},
+			},
+		},
+		{
+			name: "old-version",
+			statuses: map[int]*UpdateStatus{
+				0: {Version: "1.2.3", Step: 3, Cond: CondAbort},
+				1: {Version: "1.2.3", Step: 3, Cond: CondAbort},
+				2: {Version: "1.2.2", Step: 18, Cond: CondComplete},
+			},
+		},
+		{
+			name: "completed",
+			statuses: map[int]*UpdateStatus{
+				0: {Version: "1.2.3", Step: 18, Cond: CondComplete},
+				1: {Version: "1.2.3", Step: 18, Cond: CondComplete},
+				2: {Version: "1.2.3", Step: 18, Cond: CondComplete},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		step, err := ResumeStep("1.2.3", []int{0, 1, 2}, tc.statuses)
+		if !tc.valid {
+			if err == nil {
+				t.Errorf("%s: error is expected", tc.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+			continue
+		}
+		if step != tc.expected {
+			t.Errorf("%s: expected %d, actual %d", tc.name, tc.expected, step)
+		}
+	}
+}
@@ -21,6 +21,10 @@ const (
 	ActionWaitWorkers
 	ActionStop
 	ActionWaitClear
+	ActionSoak
+	ActionRollout
+	ActionRollback
+	ActionWaitFreeze
 )
 
 func (a Action) String() string {
@@ -39,6 +43,14 @@ func (a Action) String() string {
 		return "request-stop"
 	case ActionWaitClear:
 		return "wait-for-user-recovery"
+	case ActionSoak:
+		return "wait-for-canary-soak"
+	case ActionRollout:
+		return "request-rollout"
+	case ActionRollback:
+		return "request-rollback"
+	case ActionWaitFreeze:
+		return "wait-for-freeze-end"
 	default:
 		panic("no such action")
 	}
@@ -46,15 +58,19 @@ func (a Action) String() string {
 
 // NextAction decides the next action to do for neco-updater.
 func NextAction(ss *storage.Snapshot, timeout time.Duration) (Action, error) {
-	if ss.Latest == "" {
+	target := targetVersion(ss)
+	if target == "" {
 		return ActionWaitInfo, nil
 	}
-	latestVer, err := version.NewVersion(ss.Latest)
+	latestVer, err := version.NewVersion(target)
 	if err != nil {
 		return ActionError, err
 	}
 
 	if ss.Request == nil {
+		if reason, _ := Frozen(ss, time.Now()); reason != "" {
+			return ActionWaitFreeze, nil
+		}
 		return ActionNewVersion, nil
 	}
 
@@ -69,29 +85,66 @@ func NextAction(ss *storage.Snapshot, timeout time.Duration) (Action, error) {
 			continue
 		}
 		if status.Cond == neco.CondAbort {
+			if canRollback(ss) {
+				return ActionRollback, nil
+			}
 			return ActionStop, nil
 		}
 	}
 
-	if !neco.UpdateCompleted(ss.Request.Version, ss.Request.Servers, ss.Statuses) {
+	if !neco.UpdateCompleted(ss.Request.Version, ss.Request.Members(), ss.Statuses) {
 		if time.Since(ss.Request.StartedAt) > timeout {
 			return ActionStop, nil
 		}
 		return ActionWaitWorkers, nil
 	}
 
+	// canary boot servers have been updated.  roll out the update to
+	// the rest after the soak period if they are still healthy.
+	if ss.Request.Wave == neco.WaveCanary {
+		if checkCanary(ss) != nil {
+			return ActionStop, nil
+		}
+		soak := ss.Request.SoakStartedAt
+		if soak == nil || time.Since(*soak) < ss.SoakPeriod {
+			return ActionSoak, nil
+		}
+		if reason, _ := Frozen(ss, time.Now()); reason != "" {
+			return ActionWaitFreeze, nil
+		}
+		return ActionRollout, nil
+	}
+
 	// reconfigure the new set of boot servers with unchanged neco package version.
 	if !reflect.DeepEqual(ss.Request.Servers, ss.Servers) {
 		return ActionReconfigure, nil
 	}
 
+	// do not retry the release which has been rolled back until
+	// a newer release is found.
+	if ss.Request.RollbackFrom == target {
+		return ActionWaitInfo, nil
+	}
+
 	requestVer, err := version.NewVersion(ss.Request.Version)
 	if err != nil {
 		return ActionError, err
 	}
 	if !latestVer.Equal(requestVer) {
+		if reason, _ := Frozen(ss, time.Now()); reason != "" {
+			return ActionWaitFreeze, nil
+		}
 		return ActionNewVersion, nil
 	}
 
 	return ActionWaitInfo, nil
 }
+
+// targetVersion returns the version of neco to be installed.
+// The version pinned by "neco update pin" overrides the latest release.
+func targetVersion(ss *storage.Snapshot) string {
+	if ss.Pinned != "" {
+		return ss.Pinned
+	}
+	return ss.Latest
+}
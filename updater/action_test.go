@@ -1,6 +1,7 @@
 package updater
 
 import (
+	"reflect"
 	"testing"
 	"time"
 
@@ -17,6 +18,32 @@ func TestNextAction(t *testing.T) {
 	}
 	oldReq := *req
 	oldReq.StartedAt = time.Now().Add(-2 * timeout)
+
+	canaryReq := &neco.UpdateRequest{
+		Version:   "1.0.0",
+		Servers:   []int{0, 1},
+		Targets:   []int{0},
+		Wave:      neco.WaveCanary,
+		StartedAt: time.Now(),
+	}
+	soakStarted := time.Now()
+	soakingReq := *canaryReq
+	soakingReq.SoakStartedAt = &soakStarted
+	soakEnded := time.Now().Add(-2 * timeout)
+	soakedReq := *canaryReq
+	soakedReq.SoakStartedAt = &soakEnded
+	canaryStatuses := map[int]*neco.UpdateStatus{
+		0: {
+			Version: "1.0.0",
+			Step:    3,
+			Cond:    neco.CondComplete,
+		},
+		1: {
+			Version: "0.9.0",
+			Step:    3,
+			Cond:    neco.CondComplete,
+		},
+	}
 	statuses := map[int]*neco.UpdateStatus{
 		0: {
 			Version: "1.0.0",
@@ -30,6 +57,45 @@ func TestNextAction(t *testing.T) {
 		},
 	}
 
+	abortedStatuses := map[int]*neco.UpdateStatus{
+		0: {
+			Version: "1.0.0",
+			Step:    2,
+			Cond:    neco.CondAbort,
+			Message: "failed",
+		},
+	}
+	rollbackReq := &neco.UpdateRequest{
+		Version:      "0.9.0",
+		Servers:      []int{0, 1},
+		StartedAt:    time.Now(),
+		RollbackFrom: "1.0.0",
+	}
+	rollbackStatuses := map[int]*neco.UpdateStatus{
+		0: {
+			Version: "0.9.0",
+			Step:    3,
+			Cond:    neco.CondComplete,
+		},
+		1: {
+			Version: "0.9.0",
+			Step:    3,
+			Cond:    neco.CondComplete,
+		},
+	}
+
+	freeze := &neco.UpdateFreeze{Reason: "maintenance", FrozenAt: time.Now()}
+	windows, err := neco.ParseDenyWindows([]string{
+		time.Now().Add(-24*time.Hour).Format("2006-01-02") + "/" + time.Now().Add(24*time.Hour).Format("2006-01-02"),
+	}, time.Local)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pastWindows, err := neco.ParseDenyWindows([]string{"2000-01-01/2000-01-04"}, time.Local)
+	if err != nil {
+		t.Fatal(err)
+	}
+
 	tests := []struct {
 		name string
 		ss   *storage.Snapshot
@@ -113,6 +179,237 @@ func TestNextAction(t *testing.T) {
 			},
 			want: ActionWaitInfo,
 		},
+		{
+			name: "canary-not-completed",
+			ss: &storage.Snapshot{
+				Latest:  "1.0.0",
+				Request: canaryReq,
+				Servers: []int{0, 1},
+				Statuses: map[int]*neco.UpdateStatus{
+					0: {
+						Version: "1.0.0",
+						Step:    2,
+						Cond:    neco.CondRunning,
+					},
+				},
+				SoakPeriod: timeout,
+			},
+			want: ActionWaitWorkers,
+		},
+		{
+			name: "canary-completed",
+			ss: &storage.Snapshot{
+				Latest:     "1.0.0",
+				Request:    canaryReq,
+				Statuses:   canaryStatuses,
+				Servers:    []int{0, 1},
+				SoakPeriod: timeout,
+			},
+			want: ActionSoak,
+		},
+		{
+			name: "canary-soaking",
+			ss: &storage.Snapshot{
+				Latest:     "1.0.0",
+				Request:    &soakingReq,
+				Statuses:   canaryStatuses,
+				Servers:    []int{0, 1},
+				SoakPeriod: timeout,
+			},
+			want: ActionSoak,
+		},
+		{
+			name: "canary-soaked",
+			ss: &storage.Snapshot{
+				Latest:     "1.0.0",
+				Request:    &soakedReq,
+				Statuses:   canaryStatuses,
+				Servers:    []int{0, 1},
+				SoakPeriod: timeout,
+			},
+			want: ActionRollout,
+		},
+		{
+			name: "canary-unregistered",
+			ss: &storage.Snapshot{
+				Latest:     "1.0.0",
+				Request:    &soakingReq,
+				Statuses:   canaryStatuses,
+				Servers:    []int{1},
+				SoakPeriod: timeout,
+			},
+			want: ActionStop,
+		},
+		{
+			name: "rollback",
+			ss: &storage.Snapshot{
+				Latest:        "1.0.0",
+				Request:       req,
+				Statuses:      abortedStatuses,
+				AutoRollback:  true,
+				LastCompleted: "0.9.0",
+			},
+			want: ActionRollback,
+		},
+		{
+			name: "rollback-disabled",
+			ss: &storage.Snapshot{
+				Latest:        "1.0.0",
+				Request:       req,
+				Statuses:      abortedStatuses,
+				LastCompleted: "0.9.0",
+			},
+			want: ActionStop,
+		},
+		{
+			name: "rollback-no-completed",
+			ss: &storage.Snapshot{
+				Latest:       "1.0.0",
+				Request:      req,
+				Statuses:     abortedStatuses,
+				AutoRollback: true,
+			},
+			want: ActionStop,
+		},
+		{
+			name: "rollback-aborted",
+			ss: &storage.Snapshot{
+				Latest:  "1.0.0",
+				Request: rollbackReq,
+				Statuses: map[int]*neco.UpdateStatus{
+					0: {
+						Version: "0.9.0",
+						Cond:    neco.CondAbort,
+					},
+				},
+				AutoRollback:  true,
+				LastCompleted: "0.9.0",
+			},
+			want: ActionStop,
+		},
+		{
+			name: "rollback-completed",
+			ss: &storage.Snapshot{
+				Latest:        "1.0.0",
+				Request:       rollbackReq,
+				Statuses:      rollbackStatuses,
+				Servers:       []int{0, 1},
+				AutoRollback:  true,
+				LastCompleted: "0.9.0",
+			},
+			want: ActionWaitInfo,
+		},
+		{
+			name: "rollback-newer",
+			ss: &storage.Snapshot{
+				Latest:        "1.1.0",
+				Request:       rollbackReq,
+				Statuses:      rollbackStatuses,
+				Servers:       []int{0, 1},
+				AutoRollback:  true,
+				LastCompleted: "0.9.0",
+			},
+			want: ActionNewVersion,
+		},
+		{
+			name: "frozen-recover",
+			ss:   &storage.Snapshot{Latest: "1.0.0", Freeze: freeze},
+			want: ActionWaitFreeze,
+		},
+		{
+			name: "frozen-update",
+			ss: &storage.Snapshot{
+				Latest:   "1.1.0",
+				Request:  req,
+				Statuses: statuses,
+				Servers:  []int{0, 1},
+				Freeze:   freeze,
+			},
+			want: ActionWaitFreeze,
+		},
+		{
+			name: "frozen-reconfigure",
+			ss: &storage.Snapshot{
+				Latest:   "1.0.0",
+				Request:  req,
+				Statuses: statuses,
+				Servers:  []int{0, 1, 2},
+				Freeze:   freeze,
+			},
+			want: ActionReconfigure,
+		},
+		{
+			name: "frozen-canary-soaked",
+			ss: &storage.Snapshot{
+				Latest:     "1.0.0",
+				Request:    &soakedReq,
+				Statuses:   canaryStatuses,
+				Servers:    []int{0, 1},
+				SoakPeriod: timeout,
+				Freeze:     freeze,
+			},
+			want: ActionWaitFreeze,
+		},
+		{
+			name: "deny-window",
+			ss: &storage.Snapshot{
+				Latest:      "1.1.0",
+				Request:     req,
+				Statuses:    statuses,
+				Servers:     []int{0, 1},
+				DenyWindows: windows,
+			},
+			want: ActionWaitFreeze,
+		},
+		{
+			name: "deny-window-past",
+			ss: &storage.Snapshot{
+				Latest:      "1.1.0",
+				Request:     req,
+				Statuses:    statuses,
+				Servers:     []int{0, 1},
+				DenyWindows: pastWindows,
+			},
+			want: ActionNewVersion,
+		},
+		{
+			name: "pinned-no-latest",
+			ss:   &storage.Snapshot{Latest: "", Pinned: "1.0.0"},
+			want: ActionNewVersion,
+		},
+		{
+			name: "pinned",
+			ss: &storage.Snapshot{
+				Latest:   "1.0.0",
+				Pinned:   "0.9.0",
+				Request:  req,
+				Statuses: statuses,
+				Servers:  []int{0, 1},
+			},
+			want: ActionNewVersion,
+		},
+		{
+			name: "pinned-hold",
+			ss: &storage.Snapshot{
+				Latest:   "1.1.0",
+				Pinned:   "1.0.0",
+				Request:  req,
+				Statuses: statuses,
+				Servers:  []int{0, 1},
+			},
+			want: ActionWaitInfo,
+		},
+		{
+			name: "pinned-reconfigure",
+			ss: &storage.Snapshot{
+				Latest:   "1.1.0",
+				Pinned:   "1.0.0",
+				Request:  req,
+				Statuses: statuses,
+				Servers:  []int{0, 1, 2},
+			},
+			want: ActionReconfigure,
+		},
 	}
 
 	for _, tt := range tests {
@@ -129,3 +426,152 @@ func TestNextAction(t *testing.T) {
 		})
 	}
 }
+
+func TestNewUpdateRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		ss      *storage.Snapshot
+		targets []int
+		wave    neco.UpdateWave
+	}{
+		{
+			name: "no-canary",
+			ss:   &storage.Snapshot{Servers: []int{0, 1, 2}},
+		},
+		{
+			name:    "canary",
+			ss:      &storage.Snapshot{Servers: []int{0, 1, 2}, Canary: []int{2, 5}},
+			targets: []int{2},
+			wave:    neco.WaveCanary,
+		},
+		{
+			name: "canary-unregistered",
+			ss:   &storage.Snapshot{Servers: []int{0, 1, 2}, Canary: []int{5}},
+		},
+		{
+			name: "canary-all",
+			ss:   &storage.Snapshot{Servers: []int{0, 1}, Canary: []int{0, 1}},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			req := newUpdateRequest(tt.ss, "1.0.0")
+			if !reflect.DeepEqual(req.Servers, tt.ss.Servers) {
+				t.Errorf("unexpected servers: %v", req.Servers)
+			}
+			if !reflect.DeepEqual(req.Targets, tt.targets) {
+				t.Errorf("unexpected targets: %v, want %v", req.Targets, tt.targets)
+			}
+			if req.Wave != tt.wave {
+				t.Errorf("unexpected wave: %v, want %v", req.Wave, tt.wave)
+			}
+		})
+	}
+}
+
+func TestNewRolloutRequest(t *testing.T) {
+	canaryReq := &neco.UpdateRequest{
+		Version: "1.0.0",
+		Servers: []int{0, 1, 2},
+		Targets: []int{1},
+		Wave:    neco.WaveCanary,
+	}
+
+	tests := []struct {
+		name    string
+		servers []int
+		targets []int
+	}{
+		{
+			name:    "rest",
+			servers: []int{0, 1, 2},
+			targets: []int{0, 2},
+		},
+		{
+			name:    "added",
+			servers: []int{0, 1, 2, 3},
+			targets: []int{0, 2, 3},
+		},
+		{
+			name:    "rest-unregistered",
+			servers: []int{1},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			ss := &storage.Snapshot{Servers: tt.servers, Request: canaryReq}
+			req := newRolloutRequest(ss)
+			if req.Version != "1.0.0" || req.Wave != neco.WaveRollout {
+				t.Errorf("unexpected request: %+v", req)
+			}
+			if !reflect.DeepEqual(req.Servers, tt.servers) {
+				t.Errorf("unexpected servers: %v", req.Servers)
+			}
+			if !reflect.DeepEqual(req.Targets, tt.targets) {
+				t.Errorf("unexpected targets: %v, want %v", req.Targets, tt.targets)
+			}
+		})
+	}
+}
+
+func TestCompletedRelease(t *testing.T) {
+	statuses := map[int]*neco.UpdateStatus{
+		0: {
+			Version: "1.0.0",
+			Cond:    neco.CondComplete,
+		},
+		1: {
+			Version: "0.9.0",
+			Cond:    neco.CondComplete,
+		},
+	}
+
+	tests := []struct {
+		name string
+		req  *neco.UpdateRequest
+		want string
+	}{
+		{
+			name: "no-request",
+			want: "",
+		},
+		{
+			name: "completed",
+			req:  &neco.UpdateRequest{Version: "1.0.0", Servers: []int{0}},
+			want: "1.0.0",
+		},
+		{
+			name: "not-completed",
+			req:  &neco.UpdateRequest{Version: "1.0.0", Servers: []int{0, 1}},
+			want: "",
+		},
+		{
+			name: "stopped",
+			req:  &neco.UpdateRequest{Version: "1.0.0", Servers: []int{0}, Stop: true},
+			want: "",
+		},
+		{
+			name: "canary",
+			req:  &neco.UpdateRequest{Version: "1.0.0", Servers: []int{0, 1}, Targets: []int{0}, Wave: neco.WaveCanary},
+			want: "",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			ss := &storage.Snapshot{Request: tt.req, Statuses: statuses}
+			got := completedRelease(ss)
+			if got != tt.want {
+				t.Errorf("completedRelease() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,189 @@
+package updater
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/cybozu-go/log"
+	"github.com/cybozu-go/neco"
+	"github.com/cybozu-go/neco/storage"
+	"go.etcd.io/etcd/api/v3/etcdserverpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+const (
+	canaryProbeInterval = 1 * time.Minute
+	canaryProbeTimeout  = 10 * time.Second
+
+	// canaryProbeFailures is the number of consecutive probe failures
+	// to stop the rollout.
+	canaryProbeFailures = 3
+)
+
+// canaryServers returns the registered boot servers to be updated in the
+// canary wave.  It returns nil if the staged rollout is not configured
+// or if the canary boot servers cover all the boot servers.
+func canaryServers(ss *storage.Snapshot) []int {
+	var canary []int
+	for _, lrn := range ss.Servers {
+		if slices.Contains(ss.Canary, lrn) {
+			canary = append(canary, lrn)
+		}
+	}
+
+	if len(canary) == len(ss.Servers) {
+		return nil
+	}
+	return canary
+}
+
+// checkCanary checks the canary boot servers in the snapshot after the
+// canary wave has completed.
+//
+// This returns an error if any of them has been unregistered or no longer
+// reports the completion of the update.  The health of the services on
+// them is checked by probeCanary during the soak period.
+func checkCanary(ss *storage.Snapshot) error {
+	req := ss.Request
+	for _, lrn := range req.Members() {
+		if !slices.Contains(ss.Servers, lrn) {
+			return fmt.Errorf("canary boot server %d has been unregistered", lrn)
+		}
+
+		st, ok := ss.Statuses[lrn]
+		if !ok || st.Version != req.Version || st.Cond != neco.CondComplete {
+			return fmt.Errorf("canary boot server %d no longer reports completion", lrn)
+		}
+	}
+
+	return nil
+}
+
+// newUpdateRequest creates a new UpdateRequest for the given version.
+// If canary boot servers are configured, the request updates only them.
+func newUpdateRequest(ss *storage.Snapshot, version string) neco.UpdateRequest {
+	req := neco.UpdateRequest{
+		Version: version,
+		Servers: ss.Servers,
+	}
+
+	canary := canaryServers(ss)
+	if len(canary) > 0 {
+		req.Targets = canary
+		req.Wave = neco.WaveCanary
+	}
+
+	return req
+}
+
+// newRolloutRequest creates an UpdateRequest to roll out the release of
+// the canary wave to the rest of the boot servers.  The canary boot
+// servers are not updated again.
+func newRolloutRequest(ss *storage.Snapshot) neco.UpdateRequest {
+	req := neco.UpdateRequest{
+		Version:   ss.Request.Version,
+		Servers:   ss.Servers,
+		Wave:      neco.WaveRollout,
+		StartedAt: time.Now().UTC(),
+	}
+
+	for _, lrn := range ss.Servers {
+		if !ss.Request.IsMember(lrn) {
+			req.Targets = append(req.Targets, lrn)
+		}
+	}
+	// Targets cannot be empty because it means all the boot servers.
+	// This happens only when the rest have been unregistered during
+	// the canary wave, and then all boot servers are updated again.
+	return req
+}
+
+// probeCanary checks the health of the services on the canary boot servers.
+//
+// A canary boot server is healthy if its etcd member responds without
+// errors, and if probeURL is not empty, the URL returns 2xx status.
+// "{ip}" in probeURL is replaced with the IP address of the boot server.
+func probeCanary(ctx context.Context, ec *clientv3.Client, hc *http.Client, lrns []int, probeURL string) error {
+	ctx, cancel := context.WithTimeout(ctx, canaryProbeTimeout)
+	defer cancel()
+
+	mlr, err := ec.MemberList(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list etcd members: %w", err)
+	}
+
+	for _, lrn := range lrns {
+		name := fmt.Sprintf("boot-%d", lrn)
+		idx := slices.IndexFunc(mlr.Members, func(m *etcdserverpb.Member) bool {
+			return m.Name == name
+		})
+		if idx < 0 || len(mlr.Members[idx].ClientURLs) == 0 {
+			return fmt.Errorf("etcd on canary boot server %d is not a member of the cluster", lrn)
+		}
+		resp, err := ec.Status(ctx, mlr.Members[idx].ClientURLs[0])
+		if err != nil {
+			return fmt.Errorf("etcd on canary boot server %d does not respond: %w", lrn, err)
+		}
+		if len(resp.Errors) > 0 {
+			return fmt.Errorf("etcd on canary boot server %d has errors: %s", lrn, strings.Join(resp.Errors, ", "))
+		}
+
+		if probeURL == "" {
+			continue
+		}
+		err = probeHTTP(ctx, hc, canaryProbeURL(probeURL, lrn))
+		if err != nil {
+			return fmt.Errorf("probe for canary boot server %d failed: %w", lrn, err)
+		}
+	}
+
+	return nil
+}
+
+func canaryProbeURL(probeURL string, lrn int) string {
+	return strings.ReplaceAll(probeURL, "{ip}", neco.BootNode0IP(lrn).String())
+}
+
+func probeHTTP(ctx context.Context, hc *http.Client, u string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s returned %s", u, resp.Status)
+	}
+	return nil
+}
+
+// stopCanary stops the canary wave because the canary boot servers are
+// not healthy.
+func (s Server) stopCanary(ctx context.Context, leaderKey string, req neco.UpdateRequest, cause error) error {
+	if !req.Stop {
+		req.Stop = true
+		err := s.storage.PutRequest(ctx, req, leaderKey)
+		if err != nil {
+			return err
+		}
+	}
+
+	log.Warn("canary boot servers are not healthy", map[string]interface{}{
+		"version":   req.Version,
+		"canary":    req.Targets,
+		log.FnError: cause,
+	})
+	err := s.notifier.NotifyFailure(req, "rollout was stopped: "+cause.Error())
+	if err != nil {
+		log.Warn("failed to notify", map[string]interface{}{log.FnError: err})
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package updater
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCanaryProbeURL(t *testing.T) {
+	got := canaryProbeURL("http://{ip}:10080/health", 1)
+	if got != "http://10.69.0.195:10080/health" {
+		t.Error("unexpected URL", got)
+	}
+
+	got = canaryProbeURL("http://10.0.0.1/health", 1)
+	if got != "http://10.0.0.1/health" {
+		t.Error("unexpected URL", got)
+	}
+}
+
+func TestProbeHTTP(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/health" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer ts.Close()
+
+	err := probeHTTP(context.Background(), ts.Client(), ts.URL+"/health")
+	if err != nil {
+		t.Error(err)
+	}
+
+	err = probeHTTP(context.Background(), ts.Client(), ts.URL+"/unhealthy")
+	if err == nil {
+		t.Error("probe should fail")
+	}
+}
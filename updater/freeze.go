@@ -0,0 +1,68 @@
+package updater
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/cybozu-go/log"
+	"github.com/cybozu-go/neco"
+	"github.com/cybozu-go/neco/storage"
+)
+
+// Frozen returns the reason why updates must not be started at now.
+// It returns an empty string if updates can be started.
+//
+// If updates are frozen by a deny window, this also returns the end of
+// the window.  Otherwise the returned time is zero because the freeze
+// continues until "neco update unfreeze".
+func Frozen(ss *storage.Snapshot, now time.Time) (string, time.Time) {
+	if ss.Freeze != nil {
+		reason := ss.Freeze.Reason
+		if reason == "" {
+			reason = "no reason given"
+		}
+		return fmt.Sprintf("updates are frozen since %s: %s", ss.Freeze.FrozenAt.Format(time.RFC3339), reason), time.Time{}
+	}
+
+	for _, w := range ss.DenyWindows {
+		active, end := w.Active(now)
+		if active {
+			return fmt.Sprintf("updates are denied by window %s until %s", w.String(), end.Format(time.RFC3339)), end
+		}
+	}
+	return "", time.Time{}
+}
+
+// waitFreeze notifies that the new release is held, and waits for
+// the end of the freeze or any change of the freeze.
+//
+// held is the last notification about the held release.  The notification
+// is sent only when the target version or the reason of the freeze changes.
+func (s Server) waitFreeze(ctx context.Context, ss *storage.Snapshot, held *string) error {
+	reason, end := Frozen(ss, time.Now())
+	req := newUpdateRequest(ss, targetVersion(ss))
+	if ss.Request != nil && ss.Request.Wave == neco.WaveCanary {
+		// the rollout after the canary wave is held.
+		req = *ss.Request
+	}
+	msg := fmt.Sprintf("release %s is held. %s.", req.Version, reason)
+	if msg != *held {
+		log.Info("updates are frozen", map[string]interface{}{
+			"version": req.Version,
+			"reason":  reason,
+		})
+		err := s.notifier.NotifyInfo(req, msg)
+		if err != nil {
+			log.Warn("failed to notify", map[string]interface{}{log.FnError: err})
+		}
+		*held = msg
+	}
+
+	if !end.IsZero() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, end)
+		defer cancel()
+	}
+	return s.storage.WaitInfo(ctx, ss.Revision)
+}
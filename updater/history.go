@@ -0,0 +1,115 @@
+package updater
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cybozu-go/log"
+	"github.com/cybozu-go/neco"
+	"github.com/cybozu-go/neco/storage"
+)
+
+// historyResult returns the condition of the request in the snapshot
+// for the history record.
+func historyResult(ss *storage.Snapshot) (neco.UpdateCondition, string) {
+	req := ss.Request
+	if req.Stop {
+		return neco.CondAbort, abortMessage(ss)
+	}
+	if neco.UpdateCompleted(req.Version, req.Members(), ss.Statuses) {
+		return neco.CondComplete, ""
+	}
+	return neco.CondRunning, ""
+}
+
+// abortMessage returns the error messages reported by aborted workers.
+func abortMessage(ss *storage.Snapshot) string {
+	lrns := make([]int, 0, len(ss.Statuses))
+	for lrn, st := range ss.Statuses {
+		if st.Version != ss.Request.Version || st.Cond != neco.CondAbort {
+			continue
+		}
+		lrns = append(lrns, lrn)
+	}
+	if len(lrns) == 0 {
+		return "update was stopped"
+	}
+
+	sort.Ints(lrns)
+	msgs := make([]string, len(lrns))
+	for i, lrn := range lrns {
+		msgs[i] = fmt.Sprintf("boot server %d: %s", lrn, ss.Statuses[lrn].Message)
+	}
+	return strings.Join(msgs, "\n")
+}
+
+func (s Server) recordHistory(ctx context.Context, leaderKey string, ss *storage.Snapshot) error {
+	if ss.Request == nil || ss.Request.StartedAt.IsZero() {
+		return nil
+	}
+	cond, msg := historyResult(ss)
+	return s.putHistory(ctx, leaderKey, *ss.Request, cond, msg)
+}
+
+// putHistory creates or finishes the history record of the request.
+// Finished records are never updated.
+func (s Server) putHistory(ctx context.Context, leaderKey string, req neco.UpdateRequest, cond neco.UpdateCondition, msg string) error {
+	id := storage.HistoryID(req)
+	h, err := s.storage.GetHistory(ctx, id)
+	switch err {
+	case nil:
+		if h.EndedAt != nil || cond == neco.CondRunning {
+			return nil
+		}
+	case storage.ErrNotFound:
+		h = &neco.UpdateHistory{
+			ID:           id,
+			Version:      req.Version,
+			Servers:      req.Members(),
+			Wave:         req.Wave,
+			RollbackFrom: req.RollbackFrom,
+			ResumeStep:   req.ResumeStep,
+			StartedAt:    req.StartedAt,
+			Cond:         neco.CondRunning,
+		}
+	default:
+		return err
+	}
+
+	if cond != neco.CondRunning {
+		now := time.Now().UTC()
+		h.EndedAt = &now
+		h.Cond = cond
+		h.Message = msg
+	}
+	err = s.storage.PutHistory(ctx, *h, leaderKey)
+	if err != nil {
+		return err
+	}
+	if h.EndedAt == nil {
+		return nil
+	}
+
+	return s.pruneHistories(ctx, leaderKey)
+}
+
+func (s Server) pruneHistories(ctx context.Context, leaderKey string) error {
+	retention, err := s.storage.GetHistoryRetention(ctx)
+	if err != nil {
+		return err
+	}
+	n, err := s.storage.PruneHistories(ctx, time.Now().Add(-retention), leaderKey)
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		log.Info("pruned old update histories", map[string]interface{}{
+			"count":     n,
+			"retention": retention.String(),
+		})
+	}
+	return nil
+}
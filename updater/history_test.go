@@ -0,0 +1,79 @@
+package updater
+
+import (
+	"testing"
+
+	"github.com/cybozu-go/neco"
+	"github.com/cybozu-go/neco/storage"
+)
+
+func TestHistoryResult(t *testing.T) {
+	statuses := map[int]*neco.UpdateStatus{
+		0: {
+			Version: "1.0.0",
+			Step:    3,
+			Cond:    neco.CondComplete,
+		},
+		1: {
+			Version: "1.0.0",
+			Step:    2,
+			Cond:    neco.CondAbort,
+			Message: "failed",
+		},
+		2: {
+			Version: "0.9.0",
+			Step:    2,
+			Cond:    neco.CondAbort,
+			Message: "old failure",
+		},
+	}
+
+	tests := []struct {
+		name     string
+		req      *neco.UpdateRequest
+		statuses map[int]*neco.UpdateStatus
+		cond     neco.UpdateCondition
+		message  string
+	}{
+		{
+			name:     "running",
+			req:      &neco.UpdateRequest{Version: "1.0.0", Servers: []int{0, 1}},
+			statuses: statuses,
+			cond:     neco.CondRunning,
+		},
+		{
+			name:     "completed",
+			req:      &neco.UpdateRequest{Version: "1.0.0", Servers: []int{0}},
+			statuses: statuses,
+			cond:     neco.CondComplete,
+		},
+		{
+			name:     "aborted",
+			req:      &neco.UpdateRequest{Version: "1.0.0", Servers: []int{0, 1, 2}, Stop: true},
+			statuses: statuses,
+			cond:     neco.CondAbort,
+			message:  "boot server 1: failed",
+		},
+		{
+			name:    "timed-out",
+			req:     &neco.UpdateRequest{Version: "1.0.0", Servers: []int{0, 1}, Stop: true},
+			cond:    neco.CondAbort,
+			message: "update was stopped",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			ss := &storage.Snapshot{Request: tt.req, Statuses: tt.statuses}
+			cond, msg := historyResult(ss)
+			if cond != tt.cond {
+				t.Errorf("unexpected condition: %v, want %v", cond, tt.cond)
+			}
+			if msg != tt.message {
+				t.Errorf("unexpected message: %q, want %q", msg, tt.message)
+			}
+		})
+	}
+}
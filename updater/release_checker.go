@@ -16,7 +16,7 @@ import (
 
 var (
 	defaultCheckTimes    = "* * * * *"
-	defaultCheckTimeZone = "Asia/Tokyo"
+	defaultCheckTimeZone = storage.DefaultReleaseTimeZone
 )
 
 // ReleaseChecker checks newer GitHub releases by polling
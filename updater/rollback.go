@@ -0,0 +1,116 @@
+package updater
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/cybozu-go/log"
+	"github.com/cybozu-go/neco"
+	"github.com/cybozu-go/neco/storage"
+)
+
+// canRollback returns true if the failed update can be rolled back
+// to the last completed release.
+func canRollback(ss *storage.Snapshot) bool {
+	if !ss.AutoRollback {
+		return false
+	}
+	// never roll back a rollback.
+	if ss.Request.RollbackFrom != "" {
+		return false
+	}
+	if ss.LastCompleted == "" {
+		return false
+	}
+	return ss.LastCompleted != ss.Request.Version
+}
+
+// completedRelease returns the version of the request in the snapshot
+// if it has been completed by all the boot servers.  Otherwise, this
+// returns an empty string.
+func completedRelease(ss *storage.Snapshot) string {
+	req := ss.Request
+	if req == nil || req.Stop {
+		return ""
+	}
+
+	// boot servers other than the canary ones are still running the
+	// previous release.
+	if req.Wave == neco.WaveCanary {
+		return ""
+	}
+
+	if !neco.UpdateCompleted(req.Version, req.Members(), ss.Statuses) {
+		return ""
+	}
+	return req.Version
+}
+
+func (s Server) recordCompleted(ctx context.Context, leaderKey string, ss *storage.Snapshot) error {
+	version := completedRelease(ss)
+	if version == "" || version == ss.LastCompleted {
+		return nil
+	}
+
+	err := s.storage.PutLastCompletedRelease(ctx, version, leaderKey)
+	if err != nil {
+		return err
+	}
+	log.Info("recorded the last completed release", map[string]interface{}{
+		"version": version,
+	})
+	return nil
+}
+
+func (s Server) rollback(ctx context.Context, leaderKey string, ss *storage.Snapshot) error {
+	reasons := make(map[int]string)
+	for lrn, st := range ss.Statuses {
+		if st.Version != ss.Request.Version || st.Cond != neco.CondAbort {
+			continue
+		}
+		reasons[lrn] = st.Message
+	}
+
+	// the failed request is replaced without being stopped.
+	err := s.putHistory(ctx, leaderKey, *ss.Request, neco.CondAbort, abortMessage(ss))
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	req := neco.UpdateRequest{
+		Version:      ss.LastCompleted,
+		Servers:      ss.Servers,
+		StartedAt:    now,
+		RollbackFrom: ss.Request.Version,
+	}
+	// the canary boot servers have also installed the failed version
+	// if the rollout wave fails.
+	if ss.Request.Wave == neco.WaveCanary {
+		req.Targets = ss.Request.Targets
+	}
+	rec := neco.RollbackRecord{
+		FailedVersion: ss.Request.Version,
+		Version:       ss.LastCompleted,
+		Servers:       req.Members(),
+		Reasons:       reasons,
+		StartedAt:     now,
+	}
+	err = s.storage.PutRollbackRequest(ctx, req, rec, leaderKey)
+	if err != nil {
+		return err
+	}
+
+	log.Warn("rolling back the failed update", map[string]interface{}{
+		"failed_version": rec.FailedVersion,
+		"version":        rec.Version,
+		"servers":        rec.Servers,
+	})
+	msg := fmt.Sprintf("update to %s was aborted. rolling back to %s.", rec.FailedVersion, rec.Version)
+	err = s.notifier.NotifyInfo(req, msg)
+	if err != nil {
+		log.Warn("failed to notify", map[string]interface{}{log.FnError: err})
+	}
+	return nil
+}
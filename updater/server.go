@@ -93,12 +93,23 @@ func (s Server) runLoop(ctx context.Context, leaderKey string) error {
 	if err != nil {
 		return err
 	}
+	// held is the last notification about the release held by a freeze.
+	var held string
 	for {
 		ss, err := s.storage.NewSnapshot(ctx)
 		if err != nil {
 			return err
 		}
 
+		err = s.recordCompleted(ctx, leaderKey, ss)
+		if err != nil {
+			return err
+		}
+		err = s.recordHistory(ctx, leaderKey, ss)
+		if err != nil {
+			return err
+		}
+
 		action, err := NextAction(ss, timeout)
 		if err != nil {
 			return err
@@ -106,6 +117,9 @@ func (s Server) runLoop(ctx context.Context, leaderKey string) error {
 		log.Info("next action", map[string]interface{}{
 			"action": action.String(),
 		})
+		if action != ActionWaitFreeze {
+			held = ""
+		}
 
 		switch action {
 		case ActionWaitInfo:
@@ -115,9 +129,10 @@ func (s Server) runLoop(ctx context.Context, leaderKey string) error {
 			}
 		case ActionReconfigure:
 			req := neco.UpdateRequest{
-				Version:   ss.Request.Version,
-				Servers:   ss.Servers,
-				StartedAt: time.Now().UTC(),
+				Version:      ss.Request.Version,
+				Servers:      ss.Servers,
+				StartedAt:    time.Now().UTC(),
+				RollbackFrom: ss.Request.RollbackFrom,
 			}
 			err = s.storage.PutReconfigureRequest(ctx, req, leaderKey)
 			if err != nil {
@@ -128,16 +143,21 @@ func (s Server) runLoop(ctx context.Context, leaderKey string) error {
 				log.Warn("failed to notify", map[string]interface{}{log.FnError: err})
 			}
 		case ActionNewVersion:
-			req := neco.UpdateRequest{
-				Version:   ss.Latest,
-				Servers:   ss.Servers,
-				StartedAt: time.Now().UTC(),
-			}
+			req := newUpdateRequest(ss, targetVersion(ss))
+			req.StartedAt = time.Now().UTC()
 			err = s.storage.PutRequest(ctx, req, leaderKey)
 			if err != nil {
 				return err
 			}
-			err = s.notifier.NotifyInfo(req, "start updating the new release.")
+			release := "the new release"
+			if ss.Pinned != "" {
+				release = "the pinned release"
+			}
+			msg := fmt.Sprintf("start updating %s.", release)
+			if req.Wave == neco.WaveCanary {
+				msg = fmt.Sprintf("start updating %s on canary boot servers %v.", release, req.Targets)
+			}
+			err = s.notifier.NotifyInfo(req, msg)
 			if err != nil {
 				log.Warn("failed to notify", map[string]interface{}{log.FnError: err})
 			}
@@ -153,8 +173,41 @@ func (s Server) runLoop(ctx context.Context, leaderKey string) error {
 			if err != nil {
 				return err
 			}
+			if req.Wave == neco.WaveCanary {
+				if cerr := checkCanary(ss); cerr != nil {
+					err = s.stopCanary(ctx, leaderKey, req, cerr)
+					if err != nil {
+						return err
+					}
+				}
+			}
+		case ActionSoak:
+			err = s.waitSoak(ctx, leaderKey, ss)
+			if err != nil {
+				return err
+			}
+		case ActionRollout:
+			req := newRolloutRequest(ss)
+			err = s.storage.PutRequest(ctx, req, leaderKey)
+			if err != nil {
+				return err
+			}
+			err = s.notifier.NotifyInfo(req, fmt.Sprintf("canary boot servers are healthy. start rolling out the release to boot servers %v.", req.Members()))
+			if err != nil {
+				log.Warn("failed to notify", map[string]interface{}{log.FnError: err})
+			}
+		case ActionRollback:
+			err = s.rollback(ctx, leaderKey, ss)
+			if err != nil {
+				return err
+			}
+		case ActionWaitFreeze:
+			err = s.waitFreeze(ctx, ss, &held)
+			if err != nil {
+				return err
+			}
 		case ActionWaitClear:
-			err = s.storage.WaitRequestDeletion(ctx, ss.Revision)
+			err = s.storage.WaitRequestChange(ctx, ss.Revision)
 			if err != nil {
 				return err
 			}
@@ -190,6 +243,71 @@ func (s Server) waitComplete(ctx context.Context, leaderKey string, ss *storage.
 	return err
 }
 
+func (s Server) waitSoak(ctx context.Context, leaderKey string, ss *storage.Snapshot) error {
+	req := *ss.Request
+	if req.SoakStartedAt == nil {
+		now := time.Now().UTC()
+		req.SoakStartedAt = &now
+		err := s.storage.PutRequest(ctx, req, leaderKey)
+		if err != nil {
+			return err
+		}
+		log.Info("start soaking canary boot servers", map[string]interface{}{
+			"version":     req.Version,
+			"canary":      req.Targets,
+			"soak_period": ss.SoakPeriod.String(),
+		})
+		err = s.notifier.NotifyInfo(req, "canary boot servers were updated. soaking for "+ss.SoakPeriod.String()+" before rolling out.")
+		if err != nil {
+			log.Warn("failed to notify", map[string]interface{}{log.FnError: err})
+		}
+		return nil
+	}
+
+	// Probe the canary boot servers until the soak period ends.  Any status
+	// change of the canary boot servers needs to be evaluated by NextAction.
+	// The soak period is extended while the last probe is failing.
+	deadline := req.SoakStartedAt.Add(ss.SoakPeriod)
+	hc := ext.LocalHTTPClient()
+	failures := 0
+	for {
+		err := probeCanary(ctx, s.session.Client(), hc, req.Members(), ss.CanaryProbeURL)
+		switch {
+		case err == nil:
+			failures = 0
+			if !time.Now().Before(deadline) {
+				return nil
+			}
+		case ctx.Err() != nil:
+			return ctx.Err()
+		default:
+			failures++
+			log.Warn("failed to probe canary boot servers", map[string]interface{}{
+				"version":   req.Version,
+				"canary":    req.Targets,
+				"failures":  failures,
+				log.FnError: err,
+			})
+			if failures >= canaryProbeFailures {
+				return s.stopCanary(ctx, leaderKey, req, err)
+			}
+		}
+
+		wait := canaryProbeInterval
+		if d := time.Until(deadline); failures == 0 && d < wait {
+			wait = d
+		}
+		ctxWithTimeout, cancel := context.WithTimeout(ctx, wait)
+		err = storage.NewWorkerWatcher(func(ctx context.Context, lrn int, st *neco.UpdateStatus) bool {
+			return req.IsMember(lrn)
+		}).Watch(ctxWithTimeout, ss.Revision, s.storage)
+		cancel()
+		if err != storage.ErrTimedOut {
+			return err
+		}
+	}
+}
+
 type statusHandler struct {
 	req      *neco.UpdateRequest
 	statuses map[int]*neco.UpdateStatus
@@ -221,7 +339,14 @@ func (h statusHandler) handleStatus(ctx context.Context, lrn int, st *neco.Updat
 		})
 	}
 
-	completed := neco.UpdateCompleted(h.req.Version, h.req.Servers, h.statuses)
+	completed := neco.UpdateCompleted(h.req.Version, h.req.Members(), h.statuses)
+	if completed && h.req.Wave == neco.WaveCanary {
+		log.Info("canary worker finished updating", map[string]interface{}{
+			"version": h.req.Version,
+			"canary":  h.req.Targets,
+		})
+		return true
+	}
 	if completed {
 		log.Info("all worker finished updating", map[string]interface{}{
 			"version": h.req.Version,
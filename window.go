@@ -0,0 +1,141 @@
+package neco
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	windowDateFormat     = "2006-01-02"
+	windowDateTimeFormat = "2006-01-02T15:04"
+	windowYearlyFormat   = "01-02"
+)
+
+// DenyWindow is a period during which neco-updater does not start updates.
+//
+// A window is written as "START/END DESCRIPTION".  START and END are
+// one of the following formats in the time zone of the window.
+// DESCRIPTION is optional.
+//
+//   - "2006-01-02T15:04": END is exclusive.
+//   - "2006-01-02": the whole day.  END is inclusive.
+//   - "01-02": the whole day of every year.  END is inclusive.
+//     Both START and END must be in this format.  If END is before
+//     START, the window spans the new year.
+type DenyWindow struct {
+	Spec        string
+	Description string
+
+	yearly bool
+	start  time.Time
+	end    time.Time
+	loc    *time.Location
+}
+
+// ParseDenyWindows parses windows in loc.
+func ParseDenyWindows(windows []string, loc *time.Location) ([]DenyWindow, error) {
+	ws := make([]DenyWindow, 0, len(windows))
+	for _, s := range windows {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		w, err := parseDenyWindow(s, loc)
+		if err != nil {
+			return nil, err
+		}
+		ws = append(ws, w)
+	}
+	return ws, nil
+}
+
+func parseDenyWindow(s string, loc *time.Location) (DenyWindow, error) {
+	fields := strings.SplitN(s, " ", 2)
+	w := DenyWindow{Spec: fields[0], loc: loc}
+	if len(fields) == 2 {
+		w.Description = strings.TrimSpace(fields[1])
+	}
+
+	startEnd := strings.Split(w.Spec, "/")
+	if len(startEnd) != 2 {
+		return DenyWindow{}, fmt.Errorf("invalid deny window: %s", s)
+	}
+
+	start, startYearly, err := parseWindowTime(startEnd[0], false, loc)
+	if err != nil {
+		return DenyWindow{}, fmt.Errorf("invalid deny window: %s: %w", s, err)
+	}
+	end, endYearly, err := parseWindowTime(startEnd[1], true, loc)
+	if err != nil {
+		return DenyWindow{}, fmt.Errorf("invalid deny window: %s: %w", s, err)
+	}
+	if startYearly != endYearly {
+		return DenyWindow{}, fmt.Errorf("invalid deny window: %s: yearly and absolute dates are mixed", s)
+	}
+	if !startYearly && !start.Before(end) {
+		return DenyWindow{}, fmt.Errorf("invalid deny window: %s: end is not after start", s)
+	}
+
+	w.yearly = startYearly
+	w.start = start
+	w.end = end
+	return w, nil
+}
+
+// parseWindowTime parses s in loc.  If end is true, the returned time
+// of a date is the beginning of the next day.
+func parseWindowTime(s string, end bool, loc *time.Location) (time.Time, bool, error) {
+	if t, err := time.ParseInLocation(windowDateTimeFormat, s, loc); err == nil {
+		return t, false, nil
+	}
+
+	t, err := time.ParseInLocation(windowDateFormat, s, loc)
+	yearly := false
+	if err != nil {
+		t, err = time.ParseInLocation(windowYearlyFormat, s, loc)
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("invalid time: %s", s)
+		}
+		yearly = true
+	}
+	if end {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, yearly, nil
+}
+
+// Active returns true if now is in the window.
+// If true, this also returns the end of the window.
+func (w DenyWindow) Active(now time.Time) (bool, time.Time) {
+	if !w.yearly {
+		return !now.Before(w.start) && now.Before(w.end), w.end
+	}
+
+	year := now.In(w.loc).Year()
+	for _, y := range []int{year - 1, year} {
+		start := time.Date(y, w.start.Month(), w.start.Day(), 0, 0, 0, 0, w.loc)
+		end := time.Date(y, w.end.Month(), w.end.Day(), 0, 0, 0, 0, w.loc)
+		if !end.After(start) {
+			end = end.AddDate(1, 0, 0)
+		}
+		if !now.Before(start) && now.Before(end) {
+			return true, end
+		}
+	}
+	return false, time.Time{}
+}
+
+// String implements fmt.Stringer.
+func (w DenyWindow) String() string {
+	if w.Description == "" {
+		return w.Spec
+	}
+	return w.Spec + " " + w.Description
+}
+
+// UpdateFreeze represents a freeze of updates set by "neco update freeze".
+type UpdateFreeze struct {
+	Reason   string    `json:"reason"`
+	FrozenAt time.Time `json:"frozen_at"`
+}
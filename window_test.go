@@ -0,0 +1,96 @@
+package neco
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDenyWindows(t *testing.T) {
+	loc, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	valid := []string{
+		"2024-12-28/2025-01-04",
+		"2024-12-28T18:00/2025-01-04T09:00 year-end freeze",
+		"12-28/01-04 year-end freeze",
+		"2024-05-01/2024-05-01",
+		"",
+	}
+	ws, err := ParseDenyWindows(valid, loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ws) != 4 {
+		t.Fatal("unexpected windows", ws)
+	}
+	if ws[1].Spec != "2024-12-28T18:00/2025-01-04T09:00" || ws[1].Description != "year-end freeze" {
+		t.Error("unexpected window", ws[1])
+	}
+
+	invalid := []string{
+		"2024-12-28",
+		"2024-12-28/",
+		"2025-01-04/2024-12-28",
+		"2024-12-28T18:00/2024-12-28T18:00",
+		"12-28/2025-01-04",
+		"13-01/01-04",
+		"tomorrow/2025-01-04",
+	}
+	for _, s := range invalid {
+		_, err := ParseDenyWindows([]string{s}, loc)
+		if err == nil {
+			t.Error("should fail:", s)
+		}
+	}
+}
+
+func TestDenyWindowActive(t *testing.T) {
+	loc, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := func(s string) time.Time {
+		tm, err := time.ParseInLocation("2006-01-02T15:04", s, loc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tm
+	}
+
+	testCases := []struct {
+		window string
+		now    string
+		active bool
+		end    string
+	}{
+		{"2024-12-28/2025-01-04", "2024-12-27T23:59", false, ""},
+		{"2024-12-28/2025-01-04", "2024-12-28T00:00", true, "2025-01-05T00:00"},
+		{"2024-12-28/2025-01-04", "2025-01-04T23:59", true, "2025-01-05T00:00"},
+		{"2024-12-28/2025-01-04", "2025-01-05T00:00", false, ""},
+		{"2024-12-28T18:00/2025-01-04T09:00", "2025-01-04T08:59", true, "2025-01-04T09:00"},
+		{"2024-12-28T18:00/2025-01-04T09:00", "2025-01-04T09:00", false, ""},
+		{"12-28/01-04", "2030-12-30T12:00", true, "2031-01-05T00:00"},
+		{"12-28/01-04", "2031-01-02T12:00", true, "2031-01-05T00:00"},
+		{"12-28/01-04", "2031-01-05T00:00", false, ""},
+		{"12-28/01-04", "2031-06-01T00:00", false, ""},
+		{"05-01/05-05", "2031-05-03T00:00", true, "2031-05-06T00:00"},
+		{"05-01/05-05", "2031-04-30T23:59", false, ""},
+	}
+
+	for _, tc := range testCases {
+		ws, err := ParseDenyWindows([]string{tc.window}, loc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		active, end := ws[0].Active(at(tc.now))
+		if active != tc.active {
+			t.Errorf("%s at %s: expected active=%v", tc.window, tc.now, tc.active)
+			continue
+		}
+		if active && !end.Equal(at(tc.end)) {
+			t.Errorf("%s at %s: unexpected end %s", tc.window, tc.now, end)
+		}
+	}
+}
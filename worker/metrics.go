@@ -0,0 +1,166 @@
+package worker
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/cybozu-go/log"
+	"github.com/cybozu-go/neco"
+	"github.com/cybozu-go/neco/storage"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+type logger struct{}
+
+func (l logger) Println(v ...interface{}) {
+	log.Error("metrics error", map[string]interface{}{
+		"message": v,
+	})
+}
+
+const (
+	scrapeTimeout = time.Second * 8
+)
+
+// Metrics updated by Worker.
+// neco-worker restarts itself at the end of every update, so these
+// metrics only cover the steps run by the current process.
+var (
+	stepDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "neco_worker_step_duration_seconds",
+			Help:    "Duration of update steps.",
+			Buckets: []float64{1, 5, 10, 30, 60, 120, 300, 600, 1200, 1800, 3600},
+		},
+		[]string{"step"},
+	)
+	stepFailures = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "neco_worker_step_failures_total",
+			Help: "The number of failed update steps.",
+		},
+		[]string{"step"},
+	)
+)
+
+func observeStep(name string, d time.Duration, err error) {
+	stepDuration.WithLabelValues(name).Observe(d.Seconds())
+	if err != nil {
+		stepFailures.WithLabelValues(name).Inc()
+	}
+}
+
+type collector struct {
+	currentStep        *prometheus.Desc
+	updateCondition    *prometheus.Desc
+	lastSuccessVersion *prometheus.Desc
+	storage            storage.Storage
+	operator           Operator
+	mylrn              int
+
+	mu sync.Mutex
+	// lastSuccess is the version which this boot server has completed last.
+	// The status of this boot server is overwritten when a new update starts,
+	// so the version is kept in memory.
+	lastSuccess string
+}
+
+// NewCollector returns a prometheus.Collector for neco-worker.
+// This should be called before the worker starts a new update
+// to take over the last successful version from the status.
+func NewCollector(ctx context.Context, st storage.Storage, op Operator, mylrn int) (prometheus.Collector, error) {
+	c := &collector{
+		currentStep: prometheus.NewDesc(
+			"neco_worker_current_step",
+			"The current update step of this boot server.",
+			[]string{"version", "step"},
+			nil,
+		),
+		updateCondition: prometheus.NewDesc(
+			"neco_worker_update_condition",
+			"The update condition of this boot server.",
+			[]string{"version", "condition"},
+			nil,
+		),
+		lastSuccessVersion: prometheus.NewDesc(
+			"neco_worker_last_successful_version",
+			"The last neco version which this boot server has been updated to successfully.",
+			[]string{"version"},
+			nil,
+		),
+		storage:  st,
+		operator: op,
+		mylrn:    mylrn,
+	}
+
+	status, err := st.GetStatus(ctx, mylrn)
+	if err != nil && err != storage.ErrNotFound {
+		return nil, err
+	}
+	if status != nil && status.Cond == neco.CondComplete {
+		c.lastSuccess = status.Version
+	}
+	return c, nil
+}
+
+// GetMetricsHandler returns a http.Handler to serve metrics of neco-worker.
+func GetMetricsHandler(collector prometheus.Collector) http.Handler {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(collector)
+	handler := promhttp.HandlerFor(registry,
+		promhttp.HandlerOpts{
+			ErrorLog:      logger{},
+			ErrorHandling: promhttp.ContinueOnError,
+		})
+	return handler
+}
+
+func (c *collector) Describe(ch chan<- *prometheus.Desc) {
+	stepDuration.Describe(ch)
+	stepFailures.Describe(ch)
+	ch <- c.currentStep
+	ch <- c.updateCondition
+	ch <- c.lastSuccessVersion
+}
+
+func (c *collector) Collect(ch chan<- prometheus.Metric) {
+	stepDuration.Collect(ch)
+	stepFailures.Collect(ch)
+
+	ctx, cancel := context.WithTimeout(context.Background(), scrapeTimeout)
+	defer cancel()
+	c.updateStatus(ch, ctx)
+}
+
+func (c *collector) updateStatus(ch chan<- prometheus.Metric, ctx context.Context) {
+	st, err := c.storage.GetStatus(ctx, c.mylrn)
+	if err != nil && err != storage.ErrNotFound {
+		log.Error("failed to get worker status", map[string]interface{}{
+			log.FnError: err,
+		})
+		return
+	}
+
+	if st != nil {
+		ch <- prometheus.MustNewConstMetric(c.currentStep, prometheus.GaugeValue, float64(st.Step), st.Version, c.operator.Steps().Name(st.Step))
+		for _, cond := range []neco.UpdateCondition{neco.CondNotRunning, neco.CondRunning, neco.CondAbort, neco.CondComplete} {
+			var v float64
+			if st.Cond == cond {
+				v = 1
+			}
+			ch <- prometheus.MustNewConstMetric(c.updateCondition, prometheus.GaugeValue, v, st.Version, cond.String())
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if st != nil && st.Cond == neco.CondComplete {
+		c.lastSuccess = st.Version
+	}
+	if c.lastSuccess != "" {
+		ch <- prometheus.MustNewConstMetric(c.lastSuccessVersion, prometheus.GaugeValue, 1, c.lastSuccess)
+	}
+}
@@ -0,0 +1,131 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cybozu-go/neco"
+	"github.com/cybozu-go/neco/storage"
+	"github.com/cybozu-go/neco/storage/test"
+)
+
+func TestMetrics(t *testing.T) {
+	t.Parallel()
+
+	etcd := test.NewEtcdClient(t)
+	defer etcd.Close()
+	ctx := context.Background()
+	st := storage.NewStorage(etcd)
+
+	err := st.PutStatus(ctx, 0, neco.UpdateStatus{
+		Version: "1.0.0",
+		Step:    2,
+		Cond:    neco.CondRunning,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	observeStep("metrics-test-step", 3*time.Second, nil)
+	observeStep("metrics-test-step", 3*time.Second, errors.New("failed"))
+
+	// the cluster-wide release must not be reported as this server's.
+	_, err = etcd.Put(ctx, "hoge", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = st.PutLastCompletedRelease(ctx, "0.9.0", "hoge")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	collector, err := NewCollector(ctx, st, newMock(false, 0), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := GetMetricsHandler(collector)
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	metrics := rec.Body.String()
+
+	expects := []string{
+		`neco_worker_current_step{step="final-barrier",version="1.0.0"} 2`,
+		`neco_worker_update_condition{condition="running",version="1.0.0"} 1`,
+		`neco_worker_update_condition{condition="aborted",version="1.0.0"} 0`,
+		`neco_worker_step_duration_seconds_count{step="metrics-test-step"} 2`,
+		`neco_worker_step_failures_total{step="metrics-test-step"} 1`,
+	}
+	for _, expect := range expects {
+		if !strings.Contains(metrics, expect) {
+			t.Errorf("expected %s, but got %s", expect, metrics)
+		}
+	}
+	if strings.Contains(metrics, "neco_worker_last_successful_version") {
+		t.Errorf("unexpected last successful version: %s", metrics)
+	}
+
+	err = st.PutStatus(ctx, 0, neco.UpdateStatus{
+		Version: "1.0.0",
+		Step:    2,
+		Cond:    neco.CondComplete,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	metrics = rec.Body.String()
+	expect := `neco_worker_last_successful_version{version="1.0.0"} 1`
+	if !strings.Contains(metrics, expect) {
+		t.Errorf("expected %s, but got %s", expect, metrics)
+	}
+
+	// the version is kept after the status is overwritten by a new update.
+	err = st.PutStatus(ctx, 0, neco.UpdateStatus{
+		Version: "1.1.0",
+		Step:    1,
+		Cond:    neco.CondRunning,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	metrics = rec.Body.String()
+	if !strings.Contains(metrics, expect) {
+		t.Errorf("expected %s, but got %s", expect, metrics)
+	}
+
+	// the version is taken over by a new process from the status.
+	err = st.PutStatus(ctx, 0, neco.UpdateStatus{
+		Version: "1.1.0",
+		Step:    2,
+		Cond:    neco.CondComplete,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	collector, err = NewCollector(ctx, st, newMock(false, 0), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = st.PutStatus(ctx, 0, neco.UpdateStatus{
+		Version: "1.2.0",
+		Step:    1,
+		Cond:    neco.CondRunning,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec = httptest.NewRecorder()
+	GetMetricsHandler(collector).ServeHTTP(rec, req)
+	metrics = rec.Body.String()
+	expect = `neco_worker_last_successful_version{version="1.1.0"} 1`
+	if !strings.Contains(metrics, expect) {
+		t.Errorf("expected %s, but got %s", expect, metrics)
+	}
+}
@@ -2,7 +2,6 @@ package worker
 
 import (
 	"context"
-	"fmt"
 	"net/http"
 	"os"
 
@@ -19,11 +18,8 @@ type Operator interface {
 	// UpdateNeco updates neco package.
 	UpdateNeco(ctx context.Context, req *neco.UpdateRequest) error
 
-	// FinalStep is the step number of the final operation.
-	FinalStep() int
-
-	// RunStep executes operations for given step.
-	RunStep(ctx context.Context, req *neco.UpdateRequest, step int) error
+	// Steps returns the registry of update steps.
+	Steps() *StepRegistry
 
 	// RestoreServices starts installed services at startup.
 	StartServices(ctx context.Context) error
@@ -41,6 +37,7 @@ type operator struct {
 	localClient      *http.Client
 	fetcher          neco.ImageFetcher
 	containerRuntime neco.ContainerRuntime
+	steps            *StepRegistry
 }
 
 // NewOperator creates an Operator
@@ -75,7 +72,7 @@ func NewOperator(ctx context.Context, ec *clientv3.Client, mylrn int) (Operator,
 		return nil, err
 	}
 
-	return &operator{
+	o := &operator{
 		mylrn:            mylrn,
 		ec:               ec,
 		storage:          st,
@@ -84,7 +81,12 @@ func NewOperator(ctx context.Context, ec *clientv3.Client, mylrn int) (Operator,
 		localClient:      localClient,
 		fetcher:          fetcher,
 		containerRuntime: rt,
-	}, nil
+	}
+	o.steps, err = NewStepRegistry(o.stepList()...)
+	if err != nil {
+		return nil, err
+	}
+	return o, nil
 }
 
 func (o *operator) UpdateNeco(ctx context.Context, req *neco.UpdateRequest) error {
@@ -105,9 +107,7 @@ func (o *operator) UpdateNeco(ctx context.Context, req *neco.UpdateRequest) erro
 	if env == neco.TestEnv {
 		return installLocalPackage(ctx, deb, map[string]string{"HOME": "/home/cybozu"})
 	}
-	if env == neco.DevEnv {
-		deb.Release = "test-" + req.Version
-	}
+	deb.Release = neco.ReleaseTag(env, req.Version)
 	token, err := o.storage.GetGitHubToken(ctx)
 	if err != nil {
 		return err
@@ -115,55 +115,40 @@ func (o *operator) UpdateNeco(ctx context.Context, req *neco.UpdateRequest) erro
 	return InstallDebianPackage(ctx, o.proxyClient, token, o.ghClient, deb, true, map[string]string{"HOME": "/home/cybozu"})
 }
 
-func (o *operator) FinalStep() int {
-	return 18
+// stepList returns the update steps in the order of execution.
+// Steps without barrier must be local to the boot server.
+func (o *operator) stepList() []Step {
+	return []Step{
+		{Name: "fetch-images", Barrier: true, Run: o.FetchImages},
+		{Name: "update-etcd", Requires: []string{"fetch-images"}, Barrier: true, Run: o.UpdateEtcd},
+		{Name: "stop-vault", Barrier: true, Run: o.StopVault},
+		{Name: "update-vault", Requires: []string{"fetch-images", "update-etcd", "stop-vault"}, Barrier: true, Run: o.UpdateVault},
+		{Name: "update-setup-hw", Requires: []string{"fetch-images"}, Run: o.UpdateSetupHW},
+		{Name: "update-serf", Requires: []string{"fetch-images"}, Barrier: true, Run: o.UpdateSerf},
+		{Name: "update-setup-serf-tags", Requires: []string{"update-serf"}, Run: o.UpdateSetupSerfTags},
+		{Name: "update-etcdpasswd", Requires: []string{"update-etcd"}, Barrier: true, Run: o.UpdateEtcdpasswd},
+		{Name: "update-sabakan", Requires: []string{"fetch-images", "update-etcd", "update-vault"}, Barrier: true, Run: o.UpdateSabakan},
+		{Name: "update-sabakan-state-setter", Requires: []string{"update-sabakan"}, Barrier: true, Run: o.UpdateSabakanStateSetter},
+		{Name: "stop-cke", Barrier: true, Run: o.StopCKE},
+		{Name: "update-cke", Requires: []string{"fetch-images", "update-etcd", "update-vault", "stop-cke"}, Barrier: true, Run: o.UpdateCKE},
+		{Name: "update-cke-contents", Requires: []string{"update-cke"}, Barrier: true, Run: o.UpdateCKEContents},
+		{Name: "update-sabakan-contents", Requires: []string{"update-sabakan"}, Barrier: true, Run: o.UpdateSabakanContents},
+		{Name: "update-dhcp-json", Requires: []string{"update-sabakan"}, Barrier: true, Run: o.UpdateDHCPJSON},
+		{Name: "update-promtail", Requires: []string{"fetch-images"}, Run: o.UpdatePromtail},
+		{Name: "update-user-resources", Requires: []string{"update-cke"}, Barrier: true, Run: o.UpdateUserResources},
+
+		// THIS MUST BE THE FINAL STEP to synchronize before restarting etcd.
+		// NewStepRegistry rejects any step after this.
+		{Name: FinalStepName, Barrier: true, Run: o.finalBarrier},
+	}
 }
 
-func (o *operator) RunStep(ctx context.Context, req *neco.UpdateRequest, step int) error {
-
-	switch step {
-	case 1:
-		return o.FetchImages(ctx, req)
-	case 2:
-		return o.UpdateEtcd(ctx, req)
-	case 3:
-		return o.StopVault(ctx, req)
-	case 4:
-		return o.UpdateVault(ctx, req)
-	case 5:
-		return o.UpdateSetupHW(ctx, req)
-	case 6:
-		return o.UpdateSerf(ctx, req)
-	case 7:
-		return o.UpdateSetupSerfTags(ctx, req)
-	case 8:
-		return o.UpdateEtcdpasswd(ctx, req)
-	case 9:
-		return o.UpdateSabakan(ctx, req)
-	case 10:
-		return o.UpdateSabakanStateSetter(ctx, req)
-	case 11:
-		return o.StopCKE(ctx, req)
-	case 12:
-		return o.UpdateCKE(ctx, req)
-	case 13:
-		return o.UpdateCKEContents(ctx, req)
-	case 14:
-		return o.UpdateSabakanContents(ctx, req)
-	case 15:
-		return o.UpdateDHCPJSON(ctx, req)
-	case 16:
-		return o.UpdatePromtail(ctx, req)
-	case 17:
-		return o.UpdateUserResources(ctx, req)
-	case 18:
-		// THIS MUST BE THE FINAL STEP!!!!!
-		// to synchronize before restarting etcd.
-		return nil
-		// DO NOT ADD ANY STEP AFTER THIS LINE!!!
-	}
+func (o *operator) finalBarrier(ctx context.Context, req *neco.UpdateRequest) error {
+	return nil
+}
 
-	return fmt.Errorf("invalid step: %d", step)
+func (o *operator) Steps() *StepRegistry {
+	return o.steps
 }
 
 func (o *operator) restoreService(ctx context.Context, svc string) error {
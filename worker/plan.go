@@ -0,0 +1,172 @@
+package worker
+
+import (
+	"context"
+	"sort"
+
+	"github.com/cybozu-go/neco"
+	"github.com/cybozu-go/neco/storage"
+)
+
+// Kinds of artifacts in PlanItem.
+const (
+	PlanKindContainer = "container"
+	PlanKindDeb       = "deb"
+	PlanKindOSImage   = "osimage"
+	PlanKindCKE       = "cke"
+	PlanKindContents  = "contents"
+	PlanKindAsset     = "sabakan-asset"
+)
+
+// containerSteps maps container images installed on each boot server
+// to the update steps which install them and restart the services.
+var containerSteps = map[string]string{
+	"etcd":     "update-etcd",
+	"vault":    "update-vault",
+	"setup-hw": "update-setup-hw",
+	"serf":     "update-serf",
+	"sabakan":  "update-sabakan",
+	"promtail": "update-promtail",
+}
+
+// debSteps maps debian packages installed on each boot server
+// to the update steps which install them.
+var debSteps = map[string]string{
+	"etcdpasswd": "update-etcdpasswd",
+}
+
+// OSImageStep is the update step which uploads the OS image to sabakan.
+const OSImageStep = "update-sabakan-contents"
+
+// CKEStep is the update step which installs CKE.
+const CKEStep = "update-cke"
+
+// contentsSteps is the list of contents uploaded to sabakan and CKE
+// once in the cluster, and the update steps which upload them.
+// The contents are uploaded again for every new neco release.
+var contentsSteps = []struct {
+	name string
+	step string
+	get  func(storage.Storage, context.Context) (*neco.ContentsUpdateStatus, error)
+}{
+	{"sabakan", "update-sabakan-contents", storage.Storage.GetSabakanContentsStatus},
+	{"dhcp-json", "update-dhcp-json", storage.Storage.GetDHCPJSONContentsStatus},
+	{"cke-template", "update-cke", storage.Storage.GetCKETemplateContentsStatus},
+	{"cke", "update-cke-contents", storage.Storage.GetCKEContentsStatus},
+	{"user-resources", "update-user-resources", storage.Storage.GetUserResourcesContentsStatus},
+}
+
+// PlanItem represents an artifact to be updated on boot servers.
+type PlanItem struct {
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Servers   []int  `json:"servers,omitempty"`
+	Installed string `json:"installed"`
+	Target    string `json:"target"`
+	Step      string `json:"step"`
+}
+
+// Plan compares the artifacts installed on boot servers with target,
+// and returns the items to be updated.
+//
+// Installed versions are read from install/<lrn>/containers and
+// install/<lrn>/debs records.  Boot servers having the same installed
+// version are grouped into an item.  Installed is empty if an artifact
+// has not been installed yet.  This does not write anything to etcd.
+func Plan(ctx context.Context, st storage.Storage, lrns []int, target *neco.ArtifactSet) ([]PlanItem, error) {
+	var items []PlanItem
+
+	for _, img := range target.Images {
+		step, ok := containerSteps[img.Name]
+		if !ok {
+			continue
+		}
+		installed := make(map[int]string)
+		for _, lrn := range lrns {
+			tag, err := st.GetContainerTag(ctx, lrn, img.Name)
+			if err != nil && err != storage.ErrNotFound {
+				return nil, err
+			}
+			installed[lrn] = tag
+		}
+		items = append(items, planItems(PlanKindContainer, img.Name, img.Tag, step, lrns, installed)...)
+	}
+
+	for _, deb := range target.Debs {
+		step, ok := debSteps[deb.Name]
+		if !ok {
+			continue
+		}
+		installed := make(map[int]string)
+		for _, lrn := range lrns {
+			ver, err := st.GetDebVersion(ctx, lrn, deb.Name)
+			if err != nil && err != storage.ErrNotFound {
+				return nil, err
+			}
+			installed[lrn] = ver
+		}
+		items = append(items, planItems(PlanKindDeb, deb.Name, deb.Release, step, lrns, installed)...)
+	}
+
+	return items, nil
+}
+
+// PlanContents returns the items of the contents to be uploaded again
+// for the neco release version.
+//
+// Installed is the neco release which uploaded the contents last.
+// It is empty if the contents have never been uploaded successfully.
+func PlanContents(ctx context.Context, st storage.Storage, version string) ([]PlanItem, error) {
+	var items []PlanItem
+	for _, c := range contentsSteps {
+		status, err := c.get(st, ctx)
+		if err != nil && err != storage.ErrNotFound {
+			return nil, err
+		}
+		var installed string
+		if status != nil && status.Success {
+			installed = status.Version
+		}
+		if installed == version {
+			continue
+		}
+		items = append(items, PlanItem{
+			Kind:      PlanKindContents,
+			Name:      c.name,
+			Installed: installed,
+			Target:    version,
+			Step:      c.step,
+		})
+	}
+	return items, nil
+}
+
+func planItems(kind, name, target, step string, lrns []int, installed map[int]string) []PlanItem {
+	servers := make(map[string][]int)
+	for _, lrn := range lrns {
+		v := installed[lrn]
+		if v == target {
+			continue
+		}
+		servers[v] = append(servers[v], lrn)
+	}
+
+	versions := make([]string, 0, len(servers))
+	for v := range servers {
+		versions = append(versions, v)
+	}
+	sort.Strings(versions)
+
+	items := make([]PlanItem, len(versions))
+	for i, v := range versions {
+		items[i] = PlanItem{
+			Kind:      kind,
+			Name:      name,
+			Servers:   servers[v],
+			Installed: v,
+			Target:    target,
+			Step:      step,
+		}
+	}
+	return items
+}
@@ -0,0 +1,152 @@
+package worker
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cybozu-go/neco"
+	"github.com/cybozu-go/neco/storage"
+	"github.com/cybozu-go/neco/storage/test"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestPlanItems(t *testing.T) {
+	t.Parallel()
+
+	installed := map[int]string{
+		0: "1.0.0",
+		1: "1.1.0",
+		2: "",
+		3: "1.0.0",
+	}
+	items := planItems(PlanKindContainer, "etcd", "1.1.0", "update-etcd", []int{0, 1, 2, 3}, installed)
+	expected := []PlanItem{
+		{Kind: PlanKindContainer, Name: "etcd", Servers: []int{2}, Installed: "", Target: "1.1.0", Step: "update-etcd"},
+		{Kind: PlanKindContainer, Name: "etcd", Servers: []int{0, 3}, Installed: "1.0.0", Target: "1.1.0", Step: "update-etcd"},
+	}
+	if !cmp.Equal(items, expected) {
+		t.Error("unexpected items", cmp.Diff(items, expected))
+	}
+
+	items = planItems(PlanKindContainer, "etcd", "1.1.0", "update-etcd", []int{1}, installed)
+	if len(items) != 0 {
+		t.Error("no items are expected", items)
+	}
+}
+
+func TestPlan(t *testing.T) {
+	t.Parallel()
+
+	etcd := test.NewEtcdClient(t)
+	defer etcd.Close()
+	ctx := context.Background()
+	st := storage.NewStorage(etcd)
+
+	for _, lrn := range []int{0, 1} {
+		err := st.RecordContainerTag(ctx, lrn, "etcd")
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = st.RecordDebVersion(ctx, lrn, "etcdpasswd")
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	etcdImg, err := neco.CurrentArtifacts.FindContainerImage("etcd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	passwd, err := neco.CurrentArtifacts.FindDebianPackage("etcdpasswd")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	target := &neco.ArtifactSet{
+		Images: []neco.ContainerImage{
+			{Name: "etcd", Tag: etcdImg.Tag},
+			{Name: "serf", Tag: "0.10.1.8"},
+			{Name: "coil", Tag: "2.9.0"},
+		},
+		Debs: []neco.DebianPackage{
+			{Name: "etcdpasswd", Release: "v1.5.0"},
+		},
+	}
+	items, err := Plan(ctx, st, []int{0, 1}, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []PlanItem{
+		{Kind: PlanKindContainer, Name: "serf", Servers: []int{0, 1}, Target: "0.10.1.8", Step: "update-serf"},
+		{Kind: PlanKindDeb, Name: "etcdpasswd", Servers: []int{0, 1}, Installed: passwd.Release, Target: "v1.5.0", Step: "update-etcdpasswd"},
+	}
+	if !cmp.Equal(items, expected) {
+		t.Error("unexpected items", cmp.Diff(items, expected))
+	}
+}
+
+func TestPlanContents(t *testing.T) {
+	t.Parallel()
+
+	etcd := test.NewEtcdClient(t)
+	defer etcd.Close()
+	ctx := context.Background()
+	st := storage.NewStorage(etcd)
+
+	_, err := etcd.Put(ctx, "leader", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = st.PutSabakanContentsStatus(ctx, &neco.ContentsUpdateStatus{Version: "1.1.0", Success: true}, "leader")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = st.PutDHCPJSONContentsStatus(ctx, &neco.ContentsUpdateStatus{Version: "1.0.0", Success: true}, "leader")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = st.PutCKEContentsStatus(ctx, &neco.ContentsUpdateStatus{Version: "1.1.0", Success: false}, "leader")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	items, err := PlanContents(ctx, st, "1.1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []PlanItem{
+		{Kind: PlanKindContents, Name: "dhcp-json", Installed: "1.0.0", Target: "1.1.0", Step: "update-dhcp-json"},
+		{Kind: PlanKindContents, Name: "cke-template", Target: "1.1.0", Step: "update-cke"},
+		{Kind: PlanKindContents, Name: "cke", Target: "1.1.0", Step: "update-cke-contents"},
+		{Kind: PlanKindContents, Name: "user-resources", Target: "1.1.0", Step: "update-user-resources"},
+	}
+	if !cmp.Equal(items, expected) {
+		t.Error("unexpected items", cmp.Diff(items, expected))
+	}
+}
+
+func TestPlanSteps(t *testing.T) {
+	t.Parallel()
+
+	r, err := NewStepRegistry((&operator{}).stepList()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	steps := []string{OSImageStep, CKEStep}
+	for _, c := range contentsSteps {
+		steps = append(steps, c.step)
+	}
+	for _, step := range containerSteps {
+		steps = append(steps, step)
+	}
+	for _, step := range debSteps {
+		steps = append(steps, step)
+	}
+	for _, step := range steps {
+		if _, ok := r.Number(step); !ok {
+			t.Error("no such step:", step)
+		}
+	}
+}
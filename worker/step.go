@@ -0,0 +1,132 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/cybozu-go/neco"
+)
+
+// FinalStepName is the name of the final step of every StepRegistry.
+//
+// The final step synchronizes all the boot servers before restarting etcd.
+const FinalStepName = "final-barrier"
+
+// Step represents an update step.
+type Step struct {
+	// Name is the unique name of the step.
+	Name string
+
+	// Requires is the list of names of the steps which must be run
+	// before this step.
+	Requires []string
+
+	// Barrier is true if all the boot servers need to reach this step
+	// before running it.
+	Barrier bool
+
+	// Run executes the operations of the step.
+	Run func(ctx context.Context, req *neco.UpdateRequest) error
+}
+
+// StepRegistry is the ordered list of update steps.
+// Steps are numbered from 1 in the order of registration.
+type StepRegistry struct {
+	steps []Step
+	index map[string]int
+}
+
+// NewStepRegistry validates steps and returns a StepRegistry.
+//
+// The first step must have barrier so that all the boot servers start
+// the update together.  The last step must be named FinalStepName and
+// have barrier to synchronize all the boot servers before restarting etcd.
+func NewStepRegistry(steps ...Step) (*StepRegistry, error) {
+	if len(steps) == 0 {
+		return nil, errors.New("no steps")
+	}
+	if !steps[0].Barrier {
+		return nil, fmt.Errorf("the first step %s must have barrier", steps[0].Name)
+	}
+	final := steps[len(steps)-1]
+	if final.Name != FinalStepName || !final.Barrier {
+		return nil, fmt.Errorf("the last step must be %s with barrier", FinalStepName)
+	}
+
+	r := &StepRegistry{
+		index: make(map[string]int),
+	}
+	for _, st := range steps {
+		if st.Name == "" {
+			return nil, fmt.Errorf("step #%d has no name", len(r.steps)+1)
+		}
+		if _, ok := r.index[st.Name]; ok {
+			return nil, fmt.Errorf("duplicate step name: %s", st.Name)
+		}
+		if st.Run == nil {
+			return nil, fmt.Errorf("step %s has no operation", st.Name)
+		}
+		for _, dep := range st.Requires {
+			if _, ok := r.index[dep]; !ok {
+				return nil, fmt.Errorf("step %s requires %s which is not registered before it", st.Name, dep)
+			}
+		}
+		r.steps = append(r.steps, st)
+		r.index[st.Name] = len(r.steps)
+	}
+
+	return r, nil
+}
+
+// FinalStep returns the step number of the final step.
+func (r *StepRegistry) FinalStep() int {
+	return len(r.steps)
+}
+
+// Get returns the step of the given number.
+func (r *StepRegistry) Get(step int) (Step, error) {
+	if step < 1 || step > len(r.steps) {
+		return Step{}, fmt.Errorf("invalid step: %d", step)
+	}
+	return r.steps[step-1], nil
+}
+
+// Name returns the name of the step.
+// For an invalid step number, this returns "step-N".
+func (r *StepRegistry) Name(step int) string {
+	st, err := r.Get(step)
+	if err != nil {
+		return fmt.Sprintf("step-%d", step)
+	}
+	return st.Name
+}
+
+// Number returns the step number of the named step.
+func (r *StepRegistry) Number(name string) (int, bool) {
+	n, ok := r.index[name]
+	return n, ok
+}
+
+// Run executes the operations of the step.
+func (r *StepRegistry) Run(ctx context.Context, req *neco.UpdateRequest, step int) error {
+	st, err := r.Get(step)
+	if err != nil {
+		return err
+	}
+	return st.Run(ctx, req)
+}
+
+// PrevBarrier returns the number of the last barrier step before the step.
+// If there is no such step, this returns 1.
+//
+// Boot servers may be running different steps between PrevBarrier(step)
+// and step because steps without barrier are run without synchronization.
+func (r *StepRegistry) PrevBarrier(step int) int {
+	for i := min(step-1, len(r.steps)); i > 1; i-- {
+		if r.steps[i-1].Barrier {
+			return i
+		}
+	}
+	return 1
+}
@@ -0,0 +1,160 @@
+package worker
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cybozu-go/neco"
+)
+
+func nopStep(ctx context.Context, req *neco.UpdateRequest) error {
+	return nil
+}
+
+func TestNewStepRegistry(t *testing.T) {
+	t.Parallel()
+
+	final := Step{Name: FinalStepName, Barrier: true, Run: nopStep}
+
+	testCases := []struct {
+		name  string
+		steps []Step
+		valid bool
+	}{
+		{
+			name: "valid",
+			steps: []Step{
+				{Name: "a", Barrier: true, Run: nopStep},
+				{Name: "b", Requires: []string{"a"}, Run: nopStep},
+				final,
+			},
+			valid: true,
+		},
+		{
+			name: "empty",
+		},
+		{
+			name: "first-without-barrier",
+			steps: []Step{
+				{Name: "a", Run: nopStep},
+				final,
+			},
+		},
+		{
+			name: "no-final",
+			steps: []Step{
+				{Name: "a", Barrier: true, Run: nopStep},
+			},
+		},
+		{
+			name: "final-without-barrier",
+			steps: []Step{
+				{Name: "a", Barrier: true, Run: nopStep},
+				{Name: FinalStepName, Run: nopStep},
+			},
+		},
+		{
+			name: "final-not-last",
+			steps: []Step{
+				{Name: "a", Barrier: true, Run: nopStep},
+				final,
+				{Name: "b", Barrier: true, Run: nopStep},
+			},
+		},
+		{
+			name: "no-name",
+			steps: []Step{
+				{Name: "a", Barrier: true, Run: nopStep},
+				{Run: nopStep},
+				final,
+			},
+		},
+		{
+			name: "duplicate",
+			steps: []Step{
+				{Name: "a", Barrier: true, Run: nopStep},
+				{Name: "a", Run: nopStep},
+				final,
+			},
+		},
+		{
+			name: "no-run",
+			steps: []Step{
+				{Name: "a", Barrier: true},
+				final,
+			},
+		},
+		{
+			name: "unknown-dependency",
+			steps: []Step{
+				{Name: "a", Barrier: true, Run: nopStep},
+				{Name: "b", Requires: []string{"c"}, Run: nopStep},
+				{Name: "c", Run: nopStep},
+				final,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewStepRegistry(tc.steps...)
+			if tc.valid && err != nil {
+				t.Error("unexpected error:", err)
+			}
+			if !tc.valid && err == nil {
+				t.Error("error is expected")
+			}
+		})
+	}
+}
+
+func TestStepRegistry(t *testing.T) {
+	t.Parallel()
+
+	r, err := NewStepRegistry(
+		Step{Name: "a", Barrier: true, Run: nopStep},
+		Step{Name: "b", Run: nopStep},
+		Step{Name: "c", Barrier: true, Run: nopStep},
+		Step{Name: "d", Run: nopStep},
+		Step{Name: "e", Run: nopStep},
+		Step{Name: FinalStepName, Barrier: true, Run: nopStep},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r.FinalStep() != 6 {
+		t.Error("unexpected final step:", r.FinalStep())
+	}
+	if r.Name(3) != "c" {
+		t.Error("unexpected name:", r.Name(3))
+	}
+	if r.Name(7) != "step-7" {
+		t.Error("unexpected name:", r.Name(7))
+	}
+	if n, ok := r.Number(FinalStepName); !ok || n != 6 {
+		t.Error("unexpected number:", n, ok)
+	}
+	if _, ok := r.Number("x"); ok {
+		t.Error("x should not be found")
+	}
+	if err := r.Run(context.Background(), nil, 0); err == nil {
+		t.Error("running step 0 should fail")
+	}
+
+	prevBarriers := map[int]int{
+		1: 1,
+		2: 1,
+		3: 1,
+		4: 3,
+		5: 3,
+		6: 3,
+		7: 6,
+	}
+	for step, expected := range prevBarriers {
+		if actual := r.PrevBarrier(step); actual != expected {
+			t.Errorf("PrevBarrier(%d) = %d, expected %d", step, actual, expected)
+		}
+	}
+}
@@ -5,14 +5,16 @@ import (
 )
 
 // UpdateAborted returns true if the current update process was aborted.
+// startStep is the step from which workers start the update process;
+// it is 1 unless the process has been resumed.
 //
 // The condition is checked as follows:
 // 1. If the status is not for the current version, it is ignored.
 // 2. If the status has Cond==CondComplete, it is ignored.
 // 3. If the status has Cond==CondAbort, this returns true.
-// 4. If the status has Step==1 for other workers, it is ignored.
+// 4. If the status has Step==startStep for other workers, it is ignored.
 // 5. Otherwise, the process was aborted.
-func UpdateAborted(version string, mylrn int, statuses map[int]*neco.UpdateStatus) bool {
+func UpdateAborted(version string, startStep int, mylrn int, statuses map[int]*neco.UpdateStatus) bool {
 	for lrn, u := range statuses {
 		if u.Version != version {
 			continue
@@ -23,7 +25,7 @@ func UpdateAborted(version string, mylrn int, statuses map[int]*neco.UpdateStatu
 		case neco.CondAbort:
 			return true
 		}
-		if u.Step == 1 && lrn != mylrn {
+		if u.Step == startStep && lrn != mylrn {
 			continue
 		}
 		return true
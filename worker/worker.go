@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"sort"
+	"time"
 
 	"github.com/cybozu-go/log"
 	"github.com/cybozu-go/neco"
@@ -21,6 +22,8 @@ type Worker struct {
 	storage  storage.Storage
 	operator Operator
 
+	steps *StepRegistry
+
 	// internal states
 	req     *neco.UpdateRequest
 	step    int
@@ -36,6 +39,7 @@ func NewWorker(ec *clientv3.Client, op Operator, version string, mylrn int) *Wor
 		ec:       ec,
 		storage:  storage.NewStorage(ec),
 		operator: op,
+		steps:    op.Steps(),
 		bch:      make(chan struct{}, 1),
 	}
 }
@@ -96,7 +100,7 @@ func (w *Worker) Run(ctx context.Context) error {
 		if err2 != nil {
 			return err2
 		}
-		if UpdateAborted(req.Version, w.mylrn, stMap) {
+		if UpdateAborted(req.Version, req.StartStep(), w.mylrn, stMap) {
 			if myst, ok := stMap[w.mylrn]; ok {
 				status := *myst
 				status.Cond = neco.CondAbort
@@ -110,7 +114,7 @@ func (w *Worker) Run(ctx context.Context) error {
 			rev = modRev
 			continue
 		}
-		if neco.UpdateCompleted(req.Version, req.Servers, stMap) {
+		if neco.UpdateCompleted(req.Version, req.Members(), stMap) {
 			log.Info("previous update was completed successfully", nil)
 			req, modRev, err = w.storage.WaitRequest(ctx, rev)
 			rev = modRev
@@ -119,7 +123,8 @@ func (w *Worker) Run(ctx context.Context) error {
 
 		w.req = req
 		log.Info("update starts", map[string]interface{}{
-			"version": req.Version,
+			"version":    req.Version,
+			"start_step": req.StartStep(),
 		})
 		err = w.update(ctx, modRev)
 		if err != nil {
@@ -134,18 +139,31 @@ func (w *Worker) Run(ctx context.Context) error {
 	}
 }
 
+// status returns UpdateStatus of the current step.
+func (w *Worker) status(cond neco.UpdateCondition, msg string) neco.UpdateStatus {
+	return neco.UpdateStatus{
+		Version:  w.req.Version,
+		Step:     w.step,
+		StepName: w.steps.Name(w.step),
+		Cond:     cond,
+		Message:  msg,
+	}
+}
+
+// update runs the update process from the start step of the request.
+// All the workers synchronize with a barrier before running the start step
+// even if the step does not require barrier.
 func (w *Worker) update(ctx context.Context, modRev int64) error {
-	status := neco.UpdateStatus{
-		Version: w.req.Version,
-		Step:    1,
-		Cond:    neco.CondRunning,
+	w.step = w.req.StartStep()
+	if _, err := w.steps.Get(w.step); err != nil {
+		w.registerAbort(ctx, err)
+		return err
 	}
-	err := w.storage.PutStatus(ctx, w.mylrn, status)
+	w.barrier = NewBarrier(w.req.Members())
+	err := w.storage.PutStatus(ctx, w.mylrn, w.status(neco.CondRunning, ""))
 	if err != nil {
 		return err
 	}
-	w.step = 1
-	w.barrier = NewBarrier(w.req.Servers)
 
 	watcher := storage.NewStatusWatcher(w.handleCurrent, w.handleWorkerStatus, w.registerAbort)
 	return watcher.Watch(ctx, w.storage, modRev)
@@ -158,6 +176,15 @@ func (w *Worker) handleCurrent(ctx context.Context, req *neco.UpdateRequest) (bo
 		})
 		return true, nil
 	}
+	if req.RollbackFrom == w.req.Version {
+		// neco-updater has given up this update while this worker was
+		// running it.  The rollback is started by the next request loop.
+		log.Warn("request was rolled back", map[string]interface{}{
+			"version":       req.Version,
+			"rollback_from": req.RollbackFrom,
+		})
+		return true, nil
+	}
 
 	log.Error("unexpected request", map[string]interface{}{
 		"version":    req.Version,
@@ -173,7 +200,7 @@ func (w *Worker) handleWorkerStatus(ctx context.Context, lrn int, st *neco.Updat
 		log.Warn("ignoring unexpected boot server", map[string]interface{}{
 			"lrn":     lrn,
 			"version": w.req.Version,
-			"servers": w.req.Servers,
+			"members": w.req.Members(),
 		})
 		return false, nil
 	}
@@ -183,12 +210,16 @@ func (w *Worker) handleWorkerStatus(ctx context.Context, lrn int, st *neco.Updat
 	if st.Cond == neco.CondAbort {
 		return false, fmt.Errorf("other boot server failed to update: %d", lrn)
 	}
-	if st.Step != w.step {
+	// other boot servers may be running steps without barrier.
+	if st.Step > w.step || st.Step < w.steps.PrevBarrier(w.step) {
 		return false, fmt.Errorf("unexpected step in worker status: %d", st.Step)
 	}
 	if st.Cond == neco.CondComplete {
 		return false, fmt.Errorf("other boot server reports completion: %d", lrn)
 	}
+	if st.Step != w.step {
+		return false, nil
+	}
 
 	if w.barrier.Check(lrn) {
 		select {
@@ -202,40 +233,58 @@ func (w *Worker) handleWorkerStatus(ctx context.Context, lrn int, st *neco.Updat
 }
 
 func (w *Worker) registerAbort(ctx context.Context, err error) error {
-	status := neco.UpdateStatus{
-		Version: w.req.Version,
-		Step:    w.step,
-		Cond:    neco.CondAbort,
-		Message: err.Error(),
-	}
+	status := w.status(neco.CondAbort, err.Error())
 	err = w.storage.PutStatus(ctx, w.mylrn, status)
 	if err != nil {
 		log.Warn("failed to update status", map[string]interface{}{
 			log.FnError:      err,
 			"step":           w.step,
+			"step_name":      status.StepName,
 			"original_error": err,
 		})
 	}
 	return err
 }
 
+// runStep runs the current step and the following steps without barrier.
 func (w *Worker) runStep(ctx context.Context) (bool, error) {
-	err := w.operator.RunStep(ctx, w.req, w.step)
+	for {
+		done, err := w.runOneStep(ctx)
+		if err != nil || done {
+			return done, err
+		}
+
+		st, err := w.steps.Get(w.step)
+		if err != nil {
+			return false, err
+		}
+		if st.Barrier {
+			return false, nil
+		}
+		log.Info("run step without barrier", map[string]interface{}{
+			"version":   w.req.Version,
+			"step":      w.step,
+			"step_name": st.Name,
+		})
+	}
+}
+
+func (w *Worker) runOneStep(ctx context.Context) (bool, error) {
+	name := w.steps.Name(w.step)
+	startedAt := time.Now().UTC()
+	err := w.steps.Run(ctx, w.req, w.step)
+	observeStep(name, time.Since(startedAt), err)
+	w.recordStep(ctx, startedAt, err)
 
 	if err != nil {
 		log.Error("update failed", map[string]interface{}{
 			"version":   w.req.Version,
 			"step":      w.step,
+			"step_name": name,
 			log.FnError: err,
 		})
-		st := neco.UpdateStatus{
-			Version: w.req.Version,
-			Step:    w.step,
-			Cond:    neco.CondAbort,
-			Message: err.Error(),
-		}
 
-		err2 := w.storage.PutStatus(ctx, w.mylrn, st)
+		err2 := w.storage.PutStatus(ctx, w.mylrn, w.status(neco.CondAbort, err.Error()))
 		if err2 != nil {
 			log.Warn("failed to put status", map[string]interface{}{
 				log.FnError: err2.Error(),
@@ -245,38 +294,56 @@ func (w *Worker) runStep(ctx context.Context) (bool, error) {
 		return false, err
 	}
 
-	if w.step != w.operator.FinalStep() {
+	if w.step != w.steps.FinalStep() {
 		w.step++
-		w.barrier = NewBarrier(w.req.Servers)
-		st := neco.UpdateStatus{
-			Version: w.req.Version,
-			Step:    w.step,
-			Cond:    neco.CondRunning,
-		}
-		err = w.storage.PutStatus(ctx, w.mylrn, st)
+		w.barrier = NewBarrier(w.req.Members())
+		err = w.storage.PutStatus(ctx, w.mylrn, w.status(neco.CondRunning, ""))
 		if err != nil {
 			return false, err
 		}
 		return false, nil
 	}
 
-	st := neco.UpdateStatus{
-		Version: w.req.Version,
-		Step:    w.step,
-		Cond:    neco.CondComplete,
-	}
-	err = w.storage.PutStatus(ctx, w.mylrn, st)
+	err = w.storage.PutStatus(ctx, w.mylrn, w.status(neco.CondComplete, ""))
 	if err != nil {
 		return false, err
 	}
 	log.Info("update status as completed", map[string]interface{}{
-		"version": w.req.Version,
-		"step":    w.step,
+		"version":   w.req.Version,
+		"step":      w.step,
+		"step_name": name,
 	})
 
 	// Restart etcd always.
 	// NOTE: ignore error due to os.Exit() is called in this function.
-	w.operator.RestartEtcd(sort.SearchInts(w.req.Servers, w.mylrn), w.req)
+	w.operator.RestartEtcd(sort.SearchInts(w.req.Members(), w.mylrn), w.req)
 
 	return true, nil
 }
+
+func (w *Worker) recordStep(ctx context.Context, startedAt time.Time, err error) {
+	if w.req.StartedAt.IsZero() {
+		return
+	}
+
+	st := neco.HistoryStep{
+		Step:      w.step,
+		Name:      w.steps.Name(w.step),
+		StartedAt: startedAt,
+		EndedAt:   time.Now().UTC(),
+		Cond:      neco.CondComplete,
+	}
+	if err != nil {
+		st.Cond = neco.CondAbort
+		st.Message = err.Error()
+	}
+
+	err = w.storage.RecordHistoryStep(ctx, storage.HistoryID(*w.req), w.mylrn, st)
+	if err != nil {
+		log.Warn("failed to record step history", map[string]interface{}{
+			log.FnError: err,
+			"step":      w.step,
+			"step_name": st.Name,
+		})
+	}
+}
@@ -60,8 +60,27 @@ func (op *mockOp) UpdateNeco(ctx context.Context, req *neco.UpdateRequest) error
 	return nil
 }
 
-func (op *mockOp) FinalStep() int {
-	return 2
+func (op *mockOp) Steps() *StepRegistry {
+	r, err := NewStepRegistry(
+		Step{
+			Name:    "step-1",
+			Barrier: true,
+			Run: func(ctx context.Context, req *neco.UpdateRequest) error {
+				return op.RunStep(ctx, req, 1)
+			},
+		},
+		Step{
+			Name:    FinalStepName,
+			Barrier: true,
+			Run: func(ctx context.Context, req *neco.UpdateRequest) error {
+				return op.RunStep(ctx, req, 2)
+			},
+		},
+	)
+	if err != nil {
+		panic(err)
+	}
+	return r
 }
 
 func (op *mockOp) RunStep(ctx context.Context, req *neco.UpdateRequest, step int) error {
@@ -101,6 +120,20 @@ func inputStatus(lrn int, status *neco.UpdateStatus, wait bool) testInput {
 	}
 }
 
+func inputRollback(req *neco.UpdateRequest, wait bool) testInput {
+	return func(ctx context.Context, st storage.Storage, bch <-chan struct{}) error {
+		if wait {
+			<-bch
+		}
+		rec := neco.RollbackRecord{
+			FailedVersion: req.RollbackFrom,
+			Version:       req.Version,
+			Servers:       req.Members(),
+		}
+		return st.PutRollbackRequest(ctx, *req, rec, "hoge")
+	}
+}
+
 func inputClear() testInput {
 	return func(ctx context.Context, st storage.Storage, bch <-chan struct{}) error {
 		return st.ClearStatusAndContents(ctx)
@@ -142,6 +175,21 @@ var (
 		Servers: []int{0, 1},
 		Stop:    true,
 	}
+	testReqRollback = &neco.UpdateRequest{
+		Version:      "0.9.0",
+		Servers:      []int{0, 1},
+		RollbackFrom: "1.0.0",
+	}
+	testReqResume = &neco.UpdateRequest{
+		Version:    "1.0.0",
+		Servers:    []int{0, 1},
+		ResumeStep: 2,
+	}
+	testReqResumeInvalid = &neco.UpdateRequest{
+		Version:    "1.0.0",
+		Servers:    []int{0, 1},
+		ResumeStep: 3,
+	}
 )
 
 func TestWorker(t *testing.T) {
@@ -254,6 +302,16 @@ func TestWorker(t *testing.T) {
 			Error:  true,
 			Cond:   neco.CondAbort,
 		},
+		{
+			Name: "rollback-request",
+			Input: []testInput{
+				inputRequest(testReq, false),
+				inputStatus(1, testStatus(1, neco.CondRunning), false),
+				inputRollback(testReqRollback, true),
+			},
+			Op:     newMock(false, 0),
+			Expect: expect(true, 1, testReqRollback),
+		},
 		{
 			Name: "unexpected-version",
 			Input: []testInput{
@@ -315,6 +373,27 @@ func TestWorker(t *testing.T) {
 			Error:  true,
 			Cond:   neco.CondAbort,
 		},
+		{
+			Name: "resume",
+			Input: []testInput{
+				inputRequest(testReqResume, false),
+				inputStatus(1, testStatus(2, neco.CondRunning), false),
+				inputStatus(1, testStatus(2, neco.CondComplete), true),
+			},
+			Op:     newMock(false, 0),
+			Expect: expect(false, 2, testReqResume),
+			Cond:   neco.CondComplete,
+		},
+		{
+			Name: "resume-invalid-step",
+			Input: []testInput{
+				inputRequest(testReqResumeInvalid, false),
+			},
+			Op:     newMock(false, 0),
+			Expect: expect(false, 0, nil),
+			Error:  true,
+			Cond:   neco.CondAbort,
+		},
 	}
 
 	for _, c := range testCases {